@@ -5,8 +5,19 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	microutils "github.com/eterline/micro-utils"
 	ipDataAdapters "github.com/eterline/micro-utils/internal/adapters/ipdata"
@@ -15,6 +26,7 @@ import (
 
 	"github.com/eterline/micro-utils/internal/config/cfgutil"
 	"github.com/eterline/micro-utils/internal/models"
+	dohProvider "github.com/eterline/micro-utils/pkg/DoH"
 )
 
 var (
@@ -24,51 +36,783 @@ var (
 			IsJson:          false,
 			Pretty:          false,
 			ResolverService: "local",
+			ResolverMode:    string(ipDataAdapters.ResolveFallback),
+			DoHMethod:       "get",
+			Timeout:         0,
+			LookupTimeout:   10 * time.Second,
+			Resumer:         "ipapi",
+			ResumerMode:     string(ipDataAdapters.CompositeSequential),
+			ResumerDB:       []string{},
+			Types:           []string{},
+			Compare:         []string{},
+			Columns:         []string{},
+			ECS:             []string{},
+			Top:             10,
+			Cache:           "",
+			CachePath:       "",
+			CacheTTL:        30 * time.Minute,
+			CacheMode:       string(ipDataService.CacheDefault),
+			DnsCache:        "",
+			DnsCachePath:    "",
+			DnsCacheSize:    ipDataAdapters.DnsCacheSize,
 		},
 		Name: "seeip",
 	}
 )
 
+/*
+exitCodes - process exit codes of names lookup errors
+
+	0 - every name is resolved, 1 - fatal error.
+	If some names are not resolved exit code of the most severe error (models.ErrorCode Severity) is returned,
+	codes aren't ordered by severity: unknown error is above nxdomain.
+*/
+var exitCodes = map[models.ErrorCode]int{
+	models.CodeUnknown:     2,
+	models.CodeNODATA:      3,
+	models.CodeNXDOMAIN:    4,
+	models.CodeREFUSED:     5,
+	models.CodeSERVFAIL:    6,
+	models.CodeMalformed:   7,
+	models.CodeRateLimited: 8,
+	models.CodeTimeout:     9,
+	models.CodeNetwork:     10,
+	models.CodeNotCached:   13,
+	models.CodeCanceled:    130,
+}
+
+const (
+	// exitFatal - invalid flags, failed init of resolvers, caches and outputs
+	exitFatal = 1
+	// exitDisagree - --compare resolvers disagree on some name
+	exitDisagree = 11
+	// exitEmailFail - email subcommand check of some domain failed
+	exitEmailFail = 12
+)
+
 func main() {
+	os.Exit(run())
+}
+
+// fatal - print fatal error and get its exit code. Return it from run to close caches by deferred calls
+func fatal(err error) int {
+	microutils.PrintErr(err)
+	return exitFatal
+}
+
+// run - seeip run, returns exit code. Deferred closing of caches is done before exit
+func run() int {
 
 	cfg, err := initArgs.ParseArgs()
 	if err != nil {
-		microutils.PrintFatalErr(err)
+		return fatal(err)
+	}
+
+	cfg.Resolvers, err = configSeeip.LoadResolvers(cfg.ResolversFile)
+	if err != nil {
+		return fatal(err)
 	}
 
-	rslv, err := selectResolver(cfg.ResolverService)
+	out, err := selectOutput(cfg)
 	if err != nil {
-		microutils.PrintFatalErr(err)
+		return fatal(err)
+	}
+
+	// domains of email subcommand are checked with --addr names, stdin isn't read then
+	if cfg.Email != nil {
+		cfg.Address = append(cfg.Address, cfg.Email.Domains...)
+	}
+
+	input, err := openInput(cfg)
+	if err != nil {
+		return fatal(err)
+	}
+	defer input.Close()
+
+	if len(cfg.Compare) > 0 {
+		if input != nil {
+			names, err := collectNames(input)
+			if err != nil {
+				return fatal(err)
+			}
+			cfg.Address = append(cfg.Address, names...)
+		}
+		return compareResolvers(cfg)
+	}
+
+	if cfg.Email != nil {
+		if input != nil {
+			names, err := collectNames(input)
+			if err != nil {
+				return fatal(err)
+			}
+			cfg.Address = append(cfg.Address, names...)
+		}
+		return checkEmail(cfg)
 	}
 
-	resumer := ipDataAdapters.NewExternalApi()
+	rslv, err := selectResolvers(cfg)
+	if err != nil {
+		return fatal(err)
+	}
+
+	if closer, ok := rslv.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	cacheMode, err := ipDataService.ParseCacheMode(cfg.CacheMode)
+	if err != nil {
+		return fatal(err)
+	}
+
+	// storage lives until exit: interrupt must not close it before saving of resumed objects
+	storage, closeStorage, err := selectStorage(context.Background(), cfg.Cache, cfg.CachePath, cfg.CacheTTL)
+	if err != nil {
+		return fatal(err)
+	}
+	defer closeStorage.Close()
+
+	dnsCache, closeDnsCache, err := selectDnsCache(context.Background(), rslv, cfg)
+	if err != nil {
+		return fatal(err)
+	}
+	defer closeDnsCache.Close()
+
+	ctx, stop := runContext(cfg.Timeout)
+	defer stop()
+
+	resumer, closeResumer, err := selectResumers(cfg)
+	if err != nil {
+		return fatal(err)
+	}
+	defer closeResumer.Close()
+
+	scrRslv := rslv
+	if dnsCache != nil {
+		scrRslv = dnsCache
+	}
+
+	scr := ipDataService.NewNetworkScrapeService(cfg.Workers, scrRslv, resumer, storage)
+	scr.SetCacheMode(cacheMode)
+	scr.SetLookupTimeout(cfg.LookupTimeout)
+	scr.SetReverseLookup(cfg.PTR)
+	scr.SetChainTrace(cfg.Chain)
+
+	if cfg.Trace {
+		tracer, ok := rslv.(models.Tracer)
+		if !ok {
+			tracer = ipDataAdapters.NewTraceResolver()
+		}
+		scr.SetTracer(tracer)
+	}
+
+	rtypes := splitList(cfg.Types, strings.ToUpper)
+	for _, rtype := range rtypes {
+		if err := ipDataAdapters.CheckRecordType(rtype); err != nil {
+			return fatal(err)
+		}
+	}
+	scr.SetRecordTypes(rtypes)
+
+	if cfg.Owner {
+		scr.SetOwnerLookup(ipDataAdapters.NewRDAPOwnerLookup())
+	}
+
+	if cfg.Dnssec {
+		validator, err := selectDnssecValidator(rslv, cfg)
+		if err != nil {
+			return fatal(err)
+		}
+		scr.SetDnssecValidator(validator)
+	}
+
+	if cfg.AXFR || cfg.AXFRDir != "" {
+		probe := ipDataAdapters.NewAXFRProbe(rslv)
+		probe.SetTimeout(cfg.LookupTimeout)
+		probe.SetDumpDir(cfg.AXFRDir)
+		scr.SetZoneTransfer(probe)
+	}
+
+	if len(cfg.ECS) > 0 {
+		subnetRv, subnets, err := selectClientSubnets(rslv, cfg)
+		if err != nil {
+			return fatal(err)
+		}
+		scr.SetClientSubnets(subnetRv, subnets)
+	}
+
+	if cfg.Wordlist != "" {
+		if input != nil {
+			names, err := collectNames(input)
+			if err != nil {
+				return fatal(err)
+			}
+			cfg.Address, input = append(cfg.Address, names...), nil
+		}
+
+		cfg.Address, err = enumerateSubdomains(ctx, scrRslv, cfg)
+		if err != nil {
+			return fatal(err)
+		}
+		if len(cfg.Address) == 0 {
+			if err := out.printAll(map[string]ipDataAdapters.ResumeInfo{}); err != nil {
+				return fatal(err)
+			}
+			return out.exitCode()
+		}
+	}
+
+	if input != nil {
+		streamScrape(ctx, scr, cfg.Address, input, out)
+	} else {
+		if err := scrapeAll(ctx, scr, cfg.Address, out); err != nil {
+			return fatal(err)
+		}
+	}
+
+	if cfg.Verbose && dnsCache != nil {
+		fmt.Fprintf(os.Stderr, "dns cache: %s\n", dnsCache.Stats())
+	}
+
+	return out.exitCode()
+}
+
+/*
+enumerateSubdomains - subdomains of --addr domains found by --wordlist words
+
+	Partial results are returned if ctx is done. Enumeration statistics are printed to stderr with --verbose.
+*/
+func enumerateSubdomains(ctx context.Context, rslv models.Resolver, cfg configSeeip.Configuration) ([]string, error) {
+	f, err := os.Open(cfg.Wordlist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wordlist: %w", err)
+	}
+	defer f.Close()
+
+	words, err := collectNames(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wordlist: %w", err)
+	}
+
+	var (
+		found = []string{}
+		es    = ipDataService.NewSubdomainEnumService(cfg.Workers, rslv)
+	)
+	es.SetLookupTimeout(cfg.LookupTimeout)
+
+	for _, base := range cfg.Address {
+		enum, err := es.Enumerate(ctx, base, words)
+		if err != nil && enum.Base == "" {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			continue
+		}
+		found = append(found, enum.Found...)
+
+		if cfg.Verbose {
+			fmt.Fprintf(os.Stderr, "enum %s: %d of %d candidates found, %d failed", enum.Base, len(enum.Found), enum.Candidates, enum.Failed)
+			if enum.Wildcard {
+				fmt.Fprintf(os.Stderr, ", wildcard DNS %v dropped %d", enum.WildcardIPs, enum.Dropped)
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
 
-	scr := ipDataService.NewNetworkScrapeService(cfg.Workers, rslv, resumer, nil)
+	return found, nil
+}
 
-	resolvs, err := scr.ResolveDNS(context.Background(), cfg.Address)
+// scrapeAll - resolve and resume all --addr names at once and print them
+func scrapeAll(ctx context.Context, scr *ipDataService.NetworkScrapeService, addrs []string, out *resultOutput) error {
+	resolvs, err := scr.ResolveDNS(ctx, addrs)
 	if err != nil {
-		microutils.PrintFatalErr(err)
+		return err
 	}
 
 	d := []models.ResumeAboutIP{}
 
-	for _, abouts := range resolvs {
-		resume, err := scr.FetchAboutIP(abouts.IPs)
+	if ips := uniqueIPs(resolvs); len(ips) > 0 {
+		d, err = scr.FetchAboutIP(ctx, ips)
 		if err != nil {
 			microutils.PrintErr(err)
 		}
-		d = append(d, resume...)
 	}
 
-	resulted := ipDataAdapters.SortResolvedAndResume(resolvs, d)
+	return out.printAll(ipDataAdapters.SortResolvedAndResume(resolvs, d))
+}
+
+// streamScrape - resolve and resume --addr names and input lines, print every name as soon as it's finished
+func streamScrape(ctx context.Context, scr *ipDataService.NetworkScrapeService, addrs []string, input io.Reader, out *resultOutput) {
+	var (
+		names = make(chan string)
+		errCh = make(chan error, 1)
+	)
+
+	go func() {
+		defer close(names)
+		errCh <- readNames(ctx, addrs, input, names)
+	}()
+
+	// stdout is output stream, errors go to stderr
+	scr.StreamScrape(ctx, names, func(res ipDataService.ScrapeResult) {
+		if err := out.print(res.Name, ipDataAdapters.ResumeInfoOf(res.Resolve, res.Resumes)); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		}
+	})
+
+	if err := out.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+	}
+
+	// after interrupt reader may be blocked on input read: don't wait for it
+	select {
+	case err := <-errCh:
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		}
+	case <-ctx.Done():
+	}
+}
+
+/*
+resultOutput - lookup results printing by output flags
+
+	stats  - results are only counted, report is printed on flush
+	rows   - flat table, csv, tsv or template rows
+	others - yaml or json object of all names, NDJSON line per name when streamed
+*/
+type resultOutput struct {
+	format string
+	pretty bool
+	rows   ipDataAdapters.ResumeRowWriter
+	stats  *ipDataAdapters.ResumeStats
+	worst  models.ErrorCode
+}
+
+// track - keep resolve error code of name if it's the most severe one
+func (o *resultOutput) track(info ipDataAdapters.ResumeInfo) {
+	code := info.ErrorIPsCode
+	if code == "" {
+		return
+	}
+
+	if _, ok := exitCodes[code]; !ok {
+		code = models.CodeUnknown
+	}
+	if o.worst == "" || code.Severity() > o.worst.Severity() {
+		o.worst = code
+	}
+}
+
+// exitCode - exit code of the most severe resolve error, 0 if every name is resolved
+func (o *resultOutput) exitCode() int {
+	if o.worst == "" {
+		return 0
+	}
+	return exitCodes[o.worst]
+}
+
+// print - print or count single streamed name result
+func (o *resultOutput) print(name string, info ipDataAdapters.ResumeInfo) error {
+	o.track(info)
+
+	switch {
+	case o.stats != nil:
+		o.stats.Add(name, info)
+		return nil
+	case o.rows != nil:
+		return o.rows.Write(ipDataAdapters.ResumeRows(name, info)...)
+	}
+
+	return microutils.PrintJSON(false, ipDataAdapters.NamedResumeInfo{
+		Name:       name,
+		ResumeInfo: info,
+	})
+}
+
+// printAll - print results of all names, rows are sorted by name
+func (o *resultOutput) printAll(resulted map[string]ipDataAdapters.ResumeInfo) error {
+	if o.stats == nil && o.rows == nil {
+		for _, info := range resulted {
+			o.track(info)
+		}
+
+		if o.format == "json" {
+			return microutils.PrintJSON(o.pretty, resulted)
+		}
+		return microutils.PrintYaml(resulted)
+	}
+
+	names := make([]string, 0, len(resulted))
+	for name := range resulted {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if err := o.print(name, resulted[name]); err != nil {
+			return err
+		}
+	}
+
+	return o.flush()
+}
+
+// flush - write out buffered rows or statistics report
+func (o *resultOutput) flush() error {
+	switch {
+	case o.stats != nil:
+		if o.format == "json" {
+			return microutils.PrintJSON(o.pretty, o.stats.Report())
+		}
+		return microutils.PrintYaml(o.stats.Report())
+	case o.rows != nil:
+		return o.rows.Flush()
+	}
+	return nil
+}
+
+// outputFormat - --output format, template if only --template is set, json if only --json is set
+func outputFormat(cfg configSeeip.Configuration) string {
+	switch {
+	case cfg.Output != "":
+		return strings.ToLower(cfg.Output)
+	case cfg.Template != "":
+		return "template"
+	case cfg.IsJson:
+		return "json"
+	}
+	return "yaml"
+}
+
+// selectOutput - results output of format flags. Statistics report is printed as yaml or json
+func selectOutput(cfg configSeeip.Configuration) (*resultOutput, error) {
+	out := &resultOutput{
+		format: outputFormat(cfg),
+		pretty: cfg.Pretty,
+	}
+
+	if cfg.Stats {
+		out.stats = ipDataAdapters.NewResumeStats(cfg.Top)
+		return out, nil
+	}
+
+	switch out.format {
+	case "yaml", "json":
+	default:
+		rows, err := ipDataAdapters.NewResumeRowWriter(os.Stdout, out.format, splitList(cfg.Columns, strings.ToLower), cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+		out.rows = rows
+	}
+
+	return out, nil
+}
+
+/*
+openInput - names input of --in file, stdin for "-" or pipe without --addr. Nil if there is no input
+
+	Closing of nil input is safe.
+*/
+func openInput(cfg configSeeip.Configuration) (*os.File, error) {
+	switch {
+	case cfg.Input == "-":
+		return os.Stdin, nil
+
+	case cfg.Input != "":
+		f, err := os.Open(cfg.Input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input: %w", err)
+		}
+		return f, nil
+
+	case len(cfg.Address) == 0 && microutils.IsInputFromPipe():
+		return os.Stdin, nil
+	}
+
+	return nil, nil
+}
+
+/*
+readNames - send addrs, then every name of input lines to names
+
+	Text after '#' and blank lines are skipped. Stops when ctx is done.
+*/
+func readNames(ctx context.Context, addrs []string, input io.Reader, names chan<- string) error {
+	send := func(name string) bool {
+		select {
+		case names <- name:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for _, name := range addrs {
+		if !send(name) {
+			return nil
+		}
+	}
+
+	sc := bufio.NewScanner(input)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !send(line) {
+			return nil
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return nil
+}
+
+// collectNames - all names of input lines
+func collectNames(input io.Reader) ([]string, error) {
+	var (
+		names = make(chan string)
+		errCh = make(chan error, 1)
+		list  = []string{}
+	)
+
+	go func() {
+		defer close(names)
+		errCh <- readNames(context.Background(), nil, input, names)
+	}()
+
+	for name := range names {
+		list = append(list, name)
+	}
+	return list, <-errCh
+}
+
+/*
+runContext - ctx canceled by interrupt or after whole run timeout (unlimited if 0)
+
+	Signals are caught until ctx is done: second interrupt kills process while partial results are printed.
+*/
+func runContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	context.AfterFunc(ctx, stop)
+
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// compareResolvers - resolve names with every resolver of --compare list, returns exitDisagree if any name disagrees
+func compareResolvers(cfg configSeeip.Configuration) int {
+	var (
+		names     = splitList(cfg.Compare, nil)
+		resolvers = make([]models.NamedResolver, 0, len(names))
+	)
+
+	for _, name := range names {
+		rslv, err := selectResolver(name, cfg)
+		if err != nil {
+			return fatal(fmt.Errorf("%s: %w", name, err))
+		}
+		resolvers = append(resolvers, models.NamedResolver{Name: name, Resolver: rslv})
+	}
+
+	cs, err := ipDataService.NewResolverCompareService(cfg.Workers, resolvers...)
+	if err != nil {
+		return fatal(err)
+	}
+	cs.SetLookupTimeout(cfg.LookupTimeout)
+
+	ctx, stop := runContext(cfg.Timeout)
+	defer stop()
+
+	compared, err := cs.Compare(ctx, cfg.Address)
+	if err != nil {
+		return fatal(err)
+	}
+
 	if cfg.IsJson {
-		microutils.PrintJSON(cfg.Pretty, resulted)
+		microutils.PrintJSON(cfg.Pretty, compared)
 	} else {
-		microutils.PrintYaml(resulted)
+		microutils.PrintYaml(compared)
+	}
+
+	for _, cmp := range compared {
+		if !cmp.Agree {
+			return exitDisagree
+		}
 	}
+
+	return 0
 }
 
-func selectResolver(name string) (models.Resolver, error) {
+/*
+checkEmail - email subcommand: email security posture of names with resolver, exitEmailFail if any check fails
+
+	Warnings don't change exit code.
+*/
+func checkEmail(cfg configSeeip.Configuration) int {
+	rslv, err := selectResolvers(cfg)
+	if err != nil {
+		return fatal(err)
+	}
+
+	if closer, ok := rslv.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	checker := ipDataAdapters.NewMailSecCheck(rslv)
+	checker.SetLookupTimeout(cfg.LookupTimeout)
+	checker.SetDKIMSelectors(splitList(cfg.Email.DKIMSelectors, nil))
+
+	ctx, stop := runContext(cfg.Timeout)
+	defer stop()
+
+	checked, err := ipDataService.NewEmailCheckService(cfg.Workers, checker).Check(ctx, cfg.Address)
+	if err != nil {
+		return fatal(err)
+	}
+
+	if cfg.IsJson {
+		microutils.PrintJSON(cfg.Pretty, checked)
+	} else {
+		microutils.PrintYaml(checked)
+	}
+
+	for _, posture := range checked {
+		if posture.Status == models.FindingFail {
+			return exitEmailFail
+		}
+	}
+
+	return 0
+}
+
+// selectDnssecValidator - DNSSEC validator over resolver transport with --trust-anchor if it's set
+func selectDnssecValidator(rslv models.Resolver, cfg configSeeip.Configuration) (models.DnssecValidator, error) {
+	validator, err := ipDataAdapters.NewDnssecValidator(rslv)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.TrustAnchor != "" {
+		if err := validator.LoadTrustAnchors(cfg.TrustAnchor); err != nil {
+			return nil, err
+		}
+	}
+
+	return validator, nil
+}
+
+// selectClientSubnets - --ecs subnets with resolver of them. Client subnet answers differ by subnet, so they are not cached
+func selectClientSubnets(rslv models.Resolver, cfg configSeeip.Configuration) (models.SubnetResolver, []netip.Prefix, error) {
+	subnetRv, ok := ipDataAdapters.UnwrapResolver(rslv).(models.SubnetResolver)
+	if !ok {
+		return nil, nil, fmt.Errorf("--ecs requires single DNS server, DoT or DoH resolver: %s", cfg.ResolverService)
+	}
+
+	var subnets []netip.Prefix
+	for _, s := range splitList(cfg.ECS, nil) {
+		subnet, err := ipDataAdapters.ParseClientSubnet(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !slices.Contains(subnets, subnet) {
+			subnets = append(subnets, subnet)
+		}
+	}
+
+	return subnetRv, subnets, nil
+}
+
+// splitList - split comma separated values of list, normalize them (if normalize is not nil) and drop empty and repeated
+func splitList(list []string, normalize func(string) string) []string {
+	var (
+		seen   = map[string]struct{}{}
+		values = []string{}
+	)
+
+	for _, item := range list {
+		for _, v := range strings.Split(item, ",") {
+			v = strings.TrimSpace(v)
+			if normalize != nil {
+				v = normalize(v)
+			}
+			if _, ok := seen[v]; ok || v == "" {
+				continue
+			}
+			seen[v] = struct{}{}
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// uniqueIPs - collect IPs of all resolves without repeats
+func uniqueIPs(resolvs map[string]models.AboutResolve) []net.IP {
+	var (
+		seen = map[string]struct{}{}
+		ips  = []net.IP{}
+	)
+
+	for _, abouts := range resolvs {
+		for _, ip := range abouts.AllIPs() {
+			key := ip.String()
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
+
+// selectResolvers - single resolver or composite one over comma separated list
+func selectResolvers(cfg configSeeip.Configuration) (models.Resolver, error) {
+	// keep case: DoT pins are base64, DoH URL paths are case sensitive
+	list := splitList([]string{cfg.ResolverService}, nil)
+	if len(list) == 1 {
+		return selectResolver(list[0], cfg)
+	}
+
+	upstreams := make([]models.NamedResolver, 0, len(list))
+	for _, name := range list {
+		rslv, err := selectResolver(name, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		upstreams = append(upstreams, models.NamedResolver{Name: name, Resolver: rslv})
+	}
+
+	composite, err := ipDataAdapters.NewCompositeResolver(ipDataAdapters.ResolveStrategy(cfg.ResolverMode), upstreams...)
+	if err != nil {
+		return nil, err
+	}
+	composite.SetLookupTimeout(cfg.LookupTimeout)
+
+	return composite, nil
+}
+
+func selectResolver(name string, cfg configSeeip.Configuration) (models.Resolver, error) {
+	if rc, ok := cfg.Resolvers[name]; ok {
+		return namedResolver(rc)
+	}
+
 	switch {
 
 	case name == "cloudflare":
@@ -80,6 +824,15 @@ func selectResolver(name string) (models.Resolver, error) {
 	case name == "local":
 		return ipDataAdapters.NewLocalResolver(), nil
 
+	case name == "trace":
+		return ipDataAdapters.NewTraceResolver(), nil
+
+	case ipDataAdapters.IsTLSResolver(name):
+		return ipDataAdapters.ParseTLSResolver(name)
+
+	case strings.HasPrefix(name, "https://"):
+		return ipDataAdapters.NewDoHResolver(name, cfg.DoHMethod)
+
 	case microutils.IsAddressString(name):
 		return ipDataAdapters.NewRemoteResolver(name)
 
@@ -87,3 +840,205 @@ func selectResolver(name string) (models.Resolver, error) {
 		return nil, errors.New("unknown DNS resolver name")
 	}
 }
+
+// namedResolver - resolver of resolvers file definition
+func namedResolver(rc configSeeip.ResolverConfig) (models.Resolver, error) {
+	var (
+		rslv models.Resolver
+		edns = ipDataAdapters.EDNSOptions{}
+	)
+
+	if rc.EDNS != nil {
+		edns = ipDataAdapters.EDNSOptions{UDPSize: rc.EDNS.UDPSize, DNSSEC: rc.EDNS.DNSSEC}
+	}
+
+	switch rc.Type {
+
+	case configSeeip.ResolverDoH:
+		doh, err := ipDataAdapters.NewDoHResolverWith(dohProvider.ProviderConfig{
+			URL:       rc.URL,
+			JSON:      rc.JSON,
+			Method:    rc.Method,
+			Timeout:   rc.Timeout,
+			Bootstrap: rc.Bootstrap,
+			Proxy:     rc.Proxy,
+			EDNSSize:  edns.UDPSize,
+			DNSSEC:    edns.DNSSEC,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rslv = doh
+
+	case configSeeip.ResolverDoT:
+		dot, err := ipDataAdapters.NewTLSResolver(rc.Server, rc.ServerName, rc.Pins...)
+		if err != nil {
+			return nil, err
+		}
+		if rc.EDNS != nil {
+			dot.SetEDNS(edns)
+		}
+		rslv = dot
+
+	case configSeeip.ResolverPlain:
+		plain, err := ipDataAdapters.NewRemoteResolver(rc.Server)
+		if err != nil {
+			return nil, err
+		}
+		if rc.EDNS != nil {
+			plain.SetEDNS(edns)
+		}
+		rslv = plain
+
+	default:
+		return nil, fmt.Errorf("unknown resolver type: %s", rc.Type)
+	}
+
+	if rc.Timeout > 0 || rc.Retries > 0 {
+		return ipDataAdapters.NewRetryResolver(rslv, rc.Timeout, rc.Retries), nil
+	}
+
+	return rslv, nil
+}
+
+// selectResumers - single resumer or composite one over comma separated list
+func selectResumers(cfg configSeeip.Configuration) (models.ResumerIP, io.Closer, error) {
+	var (
+		names     = strings.Split(cfg.Resumer, ",")
+		providers = make([]ipDataAdapters.NamedResumer, 0, len(names))
+		closers   = closerList{}
+	)
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+
+		rs, closer, err := selectResumer(name, cfg)
+		if err != nil {
+			closers.Close()
+			return nil, nil, err
+		}
+
+		closers = append(closers, closer)
+		providers = append(providers, ipDataAdapters.NamedResumer{Name: name, Resumer: rs})
+	}
+
+	// single provider is used as is
+	if len(providers) == 1 {
+		return providers[0].Resumer, closers, nil
+	}
+
+	rs, err := ipDataAdapters.NewCompositeResumer(ipDataAdapters.CompositeMode(cfg.ResumerMode), providers...)
+	if err != nil {
+		closers.Close()
+		return nil, nil, err
+	}
+	rs.SetWorkers(cfg.Workers)
+
+	return rs, closers, nil
+}
+
+func selectResumer(name string, cfg configSeeip.Configuration) (models.ResumerIP, io.Closer, error) {
+	switch name {
+
+	case "ipapi":
+		return ipDataAdapters.NewExternalApi(cfg.LookupTimeout), nopCloser{}, nil
+
+	case "ipinfo":
+		return ipDataAdapters.NewIpinfoApi(cfg.IpinfoToken, cfg.LookupTimeout), nopCloser{}, nil
+
+	case "ipwho":
+		return ipDataAdapters.NewIpwhoApi(cfg.LookupTimeout), nopCloser{}, nil
+
+	case "rdap":
+		return ipDataAdapters.NewRDAPApi(cfg.LookupTimeout), nopCloser{}, nil
+
+	case "mmdb":
+		rs, err := ipDataAdapters.NewIpInfoMMDB(cfg.ResumerDB...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rs, rs, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown IP info resumer: %s", name)
+	}
+}
+
+type closerList []io.Closer
+
+func (cl closerList) Close() error {
+	var errs []error
+	for _, c := range cl {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+/*
+selectDnsCache - caching decorator of resolver or nil if DNS cache is disabled
+
+	Closer closes persistent layer only, wrapped resolver is closed by caller.
+*/
+func selectDnsCache(ctx context.Context, rslv models.Resolver, cfg configSeeip.Configuration) (*ipDataAdapters.CacheResolve, io.Closer, error) {
+	switch cfg.DnsCache {
+
+	case "":
+		return nil, nopCloser{}, nil
+
+	case "memory":
+		return ipDataAdapters.NewCacheResolver(rslv, cfg.DnsCacheSize), nopCloser{}, nil
+
+	case "starskey":
+		path := cfg.DnsCachePath
+		if path == "" {
+			path = "seeip-dns-cache"
+		}
+		st, err := ipDataAdapters.NewDnsCacheStarskey(ctx, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		cache := ipDataAdapters.NewCacheResolver(rslv, cfg.DnsCacheSize)
+		cache.SetStore(st)
+		return cache, st, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown DNS cache backend: %s", cfg.DnsCache)
+	}
+}
+
+func selectStorage(ctx context.Context, name, path string, ttl time.Duration) (ipDataService.IPstorage, io.Closer, error) {
+	switch name {
+
+	case "":
+		return nil, nopCloser{}, nil
+
+	case "sqlite":
+		if path == "" {
+			path = "seeip-cache.db"
+		}
+		st, err := ipDataAdapters.NewIpInfoSqlite(ctx, path, ttl)
+		if err != nil {
+			return nil, nil, err
+		}
+		return st, st, nil
+
+	case "starskey":
+		if path == "" {
+			path = "seeip-cache"
+		}
+		st, err := ipDataAdapters.NewIpInfoStarskey(ctx, path, ttl)
+		if err != nil {
+			return nil, nil, err
+		}
+		return st, st, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown cache backend: %s", name)
+	}
+}
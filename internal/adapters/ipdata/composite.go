@@ -0,0 +1,324 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// CompositeMode - composite resumer providers asking strategy
+type CompositeMode string
+
+const (
+	// CompositeSequential - ask providers one by one in priority order until core fields are filled
+	CompositeSequential CompositeMode = "sequential"
+	// CompositeParallel - ask all providers at once, merge in priority order
+	CompositeParallel CompositeMode = "parallel"
+)
+
+// coreFields - sequential mode stops asking providers when all of them are filled
+var coreFields = []string{"countryCode", "city", "as", "org"}
+
+// compositeWorkers - concurrent single IP requests to provider without batch support if it's not set
+const compositeWorkers = 8
+
+// NamedResumer - IP resumer with provider name for fields sources
+type NamedResumer struct {
+	Name    string
+	Resumer models.ResumerIP
+}
+
+/*
+IpInfoComposite - IP resumer over several providers with fields merging
+
+	Fields are taken from the first provider (in priority order) with non-zero value.
+	Flags (mobile, proxy, hosting) are set if any provider sets them.
+	Every merged field records provider name in Sources, different values of other providers - in Conflicts.
+	IP lists are forwarded as batches to providers implementing models.BatchResumerIP,
+	other providers are asked IP by IP concurrently.
+*/
+type IpInfoComposite struct {
+	mode      CompositeMode
+	providers []NamedResumer
+	workers   int
+}
+
+func NewCompositeResumer(mode CompositeMode, providers ...NamedResumer) (*IpInfoComposite, error) {
+	if len(providers) < 1 {
+		return nil, errors.New("no IP info providers for composite resumer")
+	}
+
+	switch mode {
+	case CompositeSequential, CompositeParallel:
+	default:
+		return nil, fmt.Errorf("unknown composite resumer mode: %s", mode)
+	}
+
+	return &IpInfoComposite{
+		mode:      mode,
+		providers: providers,
+		workers:   compositeWorkers,
+	}, nil
+}
+
+// SetWorkers - max concurrent single IP requests per provider without batch support. Default if n <= 0
+func (c *IpInfoComposite) SetWorkers(n int) {
+	if n <= 0 {
+		n = compositeWorkers
+	}
+	c.workers = n
+}
+
+func (c *IpInfoComposite) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	var (
+		objs = make([]models.AboutIPobject, len(c.providers))
+		errs = make([]error, len(c.providers))
+	)
+
+	if c.mode == CompositeParallel {
+		wg := sync.WaitGroup{}
+		for i, p := range c.providers {
+			wg.Go(func() {
+				objs[i], errs[i] = p.Resumer.ResumeIP(ctx, ip)
+			})
+		}
+		wg.Wait()
+		return c.merge(objs, errs)
+	}
+
+	for i, p := range c.providers {
+		objs[i], errs[i] = p.Resumer.ResumeIP(ctx, ip)
+		if ctx.Err() != nil {
+			break
+		}
+
+		merged, err := c.merge(objs[:i+1], errs[:i+1])
+		if err == nil && coreFilled(merged) {
+			break
+		}
+	}
+
+	return c.merge(objs, errs)
+}
+
+/*
+ResumeIPs - resume IP list with every provider by mode
+
+	Sequential mode asks the next provider only about IPs with core fields still unfilled.
+	Results have the same order as ips.
+*/
+func (c *IpInfoComposite) ResumeIPs(ctx context.Context, ips []net.IP) ([]models.AboutIPobject, []error) {
+	var (
+		// provider answers of every IP: [ip][provider]
+		objs = make([][]models.AboutIPobject, len(ips))
+		errs = make([][]error, len(ips))
+		all  = make([]int, len(ips))
+	)
+
+	for n := range ips {
+		objs[n] = make([]models.AboutIPobject, len(c.providers))
+		errs[n] = make([]error, len(c.providers))
+		all[n] = n
+	}
+
+	if c.mode == CompositeParallel {
+		wg := sync.WaitGroup{}
+		for i := range c.providers {
+			wg.Go(func() {
+				c.resumeWith(ctx, i, ips, all, objs, errs)
+			})
+		}
+		wg.Wait()
+	} else {
+		pending := all
+		for i := range c.providers {
+			if len(pending) == 0 || ctx.Err() != nil {
+				break
+			}
+
+			c.resumeWith(ctx, i, ips, pending, objs, errs)
+
+			next := pending[:0]
+			for _, n := range pending {
+				merged, err := c.merge(objs[n][:i+1], errs[n][:i+1])
+				if err != nil || !coreFilled(merged) {
+					next = append(next, n)
+				}
+			}
+			pending = next
+		}
+	}
+
+	var (
+		res    = make([]models.AboutIPobject, len(ips))
+		resErr = make([]error, len(ips))
+	)
+
+	for n := range ips {
+		res[n], resErr[n] = c.merge(objs[n], errs[n])
+	}
+
+	return res, resErr
+}
+
+// resumeWith - answers of provider i about ips by indexes idx: one batch call or concurrent single IP requests
+func (c *IpInfoComposite) resumeWith(ctx context.Context, i int, ips []net.IP, idx []int, objs [][]models.AboutIPobject, errs [][]error) {
+	rs := c.providers[i].Resumer
+
+	if batch, ok := rs.(models.BatchResumerIP); ok {
+		sub := make([]net.IP, len(idx))
+		for k, n := range idx {
+			sub[k] = ips[n]
+		}
+
+		bObjs, bErrs := batch.ResumeIPs(ctx, sub)
+		for k, n := range idx {
+			objs[n][i], errs[n][i] = bObjs[k], bErrs[k]
+		}
+		return
+	}
+
+	var (
+		wg  = sync.WaitGroup{}
+		sem = make(chan struct{}, c.workers)
+	)
+
+	for _, n := range idx {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := ctx.Err(); err != nil {
+				errs[n][i] = err
+				return
+			}
+			objs[n][i], errs[n][i] = rs.ResumeIP(ctx, ips[n])
+		})
+	}
+
+	wg.Wait()
+}
+
+func (c *IpInfoComposite) merge(objs []models.AboutIPobject, errs []error) (models.AboutIPobject, error) {
+	var (
+		merged    = models.AboutIPobject{}
+		mergedVal = reflect.ValueOf(&merged).Elem()
+		failed    []error
+		success   bool
+	)
+
+	for i, obj := range objs {
+		name := c.providers[i].Name
+
+		if errs[i] != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", name, errs[i]))
+			continue
+		}
+
+		if obj.Status != "success" {
+			continue
+		}
+
+		if !success {
+			merged.RequestTime = obj.RequestTime
+		}
+		success = true
+
+		objVal := reflect.ValueOf(obj)
+		for _, f := range mergeableFields() {
+			src := objVal.Field(f.index)
+			if src.IsZero() {
+				continue
+			}
+
+			dst := mergedVal.Field(f.index)
+
+			if dst.IsZero() {
+				dst.Set(src)
+				setSource(&merged, f.name, name)
+				continue
+			}
+
+			if !dst.Equal(src) {
+				addConflict(&merged, f.name, fmt.Sprintf("%s: %v", name, src.Interface()))
+			}
+		}
+	}
+
+	if !success {
+		if len(failed) == 0 {
+			return models.AboutIPobject{}, errors.New("no IP info providers succeeded")
+		}
+		return models.AboutIPobject{}, errors.Join(failed...)
+	}
+
+	merged.Status = "success"
+	return merged, nil
+}
+
+func coreFilled(obj models.AboutIPobject) bool {
+	for _, name := range coreFields {
+		if obj.Sources[name] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func setSource(obj *models.AboutIPobject, field, provider string) {
+	if obj.Sources == nil {
+		obj.Sources = map[string]string{}
+	}
+	obj.Sources[field] = provider
+}
+
+func addConflict(obj *models.AboutIPobject, field, value string) {
+	if obj.Conflicts == nil {
+		obj.Conflicts = map[string][]string{}
+	}
+	obj.Conflicts[field] = append(obj.Conflicts[field], value)
+}
+
+type mergeField struct {
+	index int
+	name  string
+}
+
+var (
+	mergeFieldsOnce sync.Once
+	mergeFieldsList []mergeField
+)
+
+// mergeableFields - AboutIPobject data fields with JSON names
+func mergeableFields() []mergeField {
+	mergeFieldsOnce.Do(func() {
+		t := reflect.TypeFor[models.AboutIPobject]()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+			switch f.Type.Kind() {
+			case reflect.String, reflect.Bool, reflect.Int, reflect.Float64:
+			default:
+				continue
+			}
+
+			if name == "" || name == "-" || name == "status" {
+				continue
+			}
+
+			mergeFieldsList = append(mergeFieldsList, mergeField{index: i, name: name})
+		}
+	})
+	return mergeFieldsList
+}
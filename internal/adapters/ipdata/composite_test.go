@@ -0,0 +1,113 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// fakeResumer - answers with fixed objects by IP, records asked IPs
+type fakeResumer struct {
+	mu      sync.Mutex
+	answers map[string]models.AboutIPobject
+	asked   []string
+	batches int
+}
+
+func (f *fakeResumer) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.asked = append(f.asked, ip.String())
+
+	obj, ok := f.answers[ip.String()]
+	if !ok {
+		return models.AboutIPobject{}, errors.New("unknown ip")
+	}
+	return obj, nil
+}
+
+// fakeBatchResumer - fakeResumer with batch support
+type fakeBatchResumer struct {
+	fakeResumer
+}
+
+func (f *fakeBatchResumer) ResumeIPs(ctx context.Context, ips []net.IP) ([]models.AboutIPobject, []error) {
+	f.mu.Lock()
+	f.batches++
+	f.mu.Unlock()
+
+	var (
+		objs = make([]models.AboutIPobject, len(ips))
+		errs = make([]error, len(ips))
+	)
+	for i, ip := range ips {
+		objs[i], errs[i] = f.ResumeIP(ctx, ip)
+	}
+	return objs, errs
+}
+
+func TestCompositeResumeIPs(t *testing.T) {
+	full := models.AboutIPobject{Status: "success", CountryCode: "US", City: "Ashburn", As: "AS64500", Org: "Example"}
+
+	for _, mode := range []CompositeMode{CompositeSequential, CompositeParallel} {
+		t.Run(string(mode), func(t *testing.T) {
+			batch := &fakeBatchResumer{fakeResumer{answers: map[string]models.AboutIPobject{
+				"192.0.2.1": full,
+				"192.0.2.2": {Status: "success", CountryCode: "DE"},
+			}}}
+			single := &fakeResumer{answers: map[string]models.AboutIPobject{
+				"192.0.2.2": {Status: "success", CountryCode: "FR", City: "Paris"},
+			}}
+
+			c, err := NewCompositeResumer(mode,
+				NamedResumer{Name: "batch", Resumer: batch},
+				NamedResumer{Name: "single", Resumer: single},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var _ models.BatchResumerIP = c
+
+			ips := []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2"), net.ParseIP("192.0.2.3")}
+			objs, errs := c.ResumeIPs(context.Background(), ips)
+
+			if batch.batches != 1 || len(batch.asked) != 3 {
+				t.Errorf("batch provider: %d batch calls for %v, want one call for every IP", batch.batches, batch.asked)
+			}
+
+			wantSingle := 3
+			if mode == CompositeSequential {
+				// 192.0.2.1 is complete after the first provider
+				wantSingle = 2
+			}
+			if len(single.asked) != wantSingle {
+				t.Errorf("single provider asked %v, want %d IPs", single.asked, wantSingle)
+			}
+
+			if errs[0] != nil || objs[0].City != "Ashburn" {
+				t.Errorf("192.0.2.1: %+v, %v", objs[0], errs[0])
+			}
+
+			if errs[1] != nil || objs[1].CountryCode != "DE" || objs[1].City != "Paris" || objs[1].Sources["city"] != "single" {
+				t.Errorf("192.0.2.2 isn't merged: %+v, %v", objs[1], errs[1])
+			}
+			if got := objs[1].Conflicts["countryCode"]; len(got) != 1 || got[0] != "single: FR" {
+				t.Errorf("192.0.2.2 conflicts = %v", got)
+			}
+
+			if errs[2] == nil {
+				t.Error("192.0.2.3: error expected when every provider failed")
+			}
+		})
+	}
+}
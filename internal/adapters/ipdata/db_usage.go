@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -23,16 +24,29 @@ const (
 	maxCacheAge = 30 * time.Minute
 )
 
+// cacheTTL - returns ttl or maxCacheAge as default when ttl isn't set
+func cacheTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return maxCacheAge
+	}
+	return ttl
+}
+
 // IpInfoSqlite - use as SQLite cache for IP requests
-// work in process on that. Maybe won't use
 type IpInfoSqlite struct {
 	db       *sql.DB
 	savePrep *sql.Stmt
 	getPrep  *sql.Stmt
+	ttl      time.Duration
 }
 
-func NewIpInfoSqlite(ctx context.Context, db string) (*IpInfoSqlite, error) {
-	self := &IpInfoSqlite{}
+/*
+NewIpInfoSqlite - open SQLite IP info cache by db file path
+
+	Cached objects older than ttl are ignored. If ttl <= 0, maxCacheAge is used.
+*/
+func NewIpInfoSqlite(ctx context.Context, db string, ttl time.Duration) (*IpInfoSqlite, error) {
+	self := &IpInfoSqlite{ttl: cacheTTL(ttl)}
 
 	sqlite, err := sql.Open("sqlite3", db)
 	if err != nil {
@@ -79,25 +93,48 @@ func (d *IpInfoSqlite) checkMigrate(ctx context.Context) error {
 		mobile         BOOLEAN,
 		proxy          BOOLEAN,
 		hosting        BOOLEAN,
-		request_time   TIMESTAMP
+		request_time   TIMESTAMP,
+		sources        TEXT,
+		conflicts      TEXT
     );`)
 
 	if err != nil {
 		return fmt.Errorf("failed migrate ip_data: %w", err)
 	}
 
+	// tables of previous versions
+	for _, column := range []string{"sources", "conflicts"} {
+		if err := d.addColumn(ctx, column, "TEXT"); err != nil {
+			return fmt.Errorf("failed migrate ip_data: %w", err)
+		}
+	}
+
 	return nil
 }
 
+func (d *IpInfoSqlite) addColumn(ctx context.Context, column, typ string) error {
+	var count int
+
+	err := d.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pragma_table_info('ip_data') WHERE name = ?;`, column,
+	).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = d.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE ip_data ADD COLUMN %s %s;`, column, typ))
+	return err
+}
+
 func (d *IpInfoSqlite) prepareExec() error {
 	savePrep, err := d.db.Prepare(`
 	INSERT INTO ip_data (
 		ip, status, continent, continent_code, country, country_code,
 		region, region_name, city, district, zip,
 		lat, lon, timezone, offset, currency, isp, org, as_field, asname,
-		reverse, mobile, proxy, hosting, request_time
+		reverse, mobile, proxy, hosting, request_time, sources, conflicts
 	)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(ip) DO UPDATE SET
 		status         = excluded.status,
 		continent      = excluded.continent,
@@ -122,7 +159,9 @@ func (d *IpInfoSqlite) prepareExec() error {
 		mobile         = excluded.mobile,
 		proxy          = excluded.proxy,
 		hosting        = excluded.hosting,
-		request_time   = excluded.request_time;
+		request_time   = excluded.request_time,
+		sources        = excluded.sources,
+		conflicts      = excluded.conflicts;
 	`)
 
 	if err != nil {
@@ -134,10 +173,9 @@ func (d *IpInfoSqlite) prepareExec() error {
         SELECT status, continent, continent_code, country, country_code,
                region, region_name, city, district, zip,
                lat, lon, timezone, offset, currency, isp, org, as_field, asname,
-               reverse, mobile, proxy, hosting, request_time
+               reverse, mobile, proxy, hosting, request_time, sources, conflicts
         FROM ip_data
-        WHERE ip = ?
-        AND (? - request_time) <= ?;
+        WHERE ip = ?;
     `)
 
 	if err != nil {
@@ -149,27 +187,42 @@ func (d *IpInfoSqlite) prepareExec() error {
 }
 
 func (d *IpInfoSqlite) Save(ctx context.Context, ip net.IP, obj models.AboutIPobject) error {
-	_, err := d.savePrep.ExecContext(ctx,
-		ip, &obj.Status, &obj.Continent, &obj.ContinentCode, &obj.Country, &obj.CountryCode,
+	if ip == nil {
+		return errors.New("ip is nil")
+	}
+
+	sources, err := json.Marshal(obj.Sources)
+	if err != nil {
+		return err
+	}
+
+	conflicts, err := json.Marshal(obj.Conflicts)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.savePrep.ExecContext(ctx,
+		ip.String(), &obj.Status, &obj.Continent, &obj.ContinentCode, &obj.Country, &obj.CountryCode,
 		&obj.Region, &obj.RegionName, &obj.City, &obj.District, &obj.Zip, &obj.Lat, &obj.Lon,
 		&obj.Timezone, &obj.Offset, &obj.Currency, &obj.Isp, &obj.Org, &obj.As, &obj.Asname,
 		&obj.Reverse, &obj.Mobile, &obj.Proxy, &obj.Hosting, &obj.RequestTime,
+		string(sources), string(conflicts),
 	)
 	return err
 }
 
 func (d *IpInfoSqlite) Get(ctx context.Context, ip net.IP) (*models.AboutIPobject, error) {
-	now := time.Now().Unix()
-	row := d.getPrep.QueryRowContext(ctx, ip, now, maxCacheAge.Seconds())
+	row := d.getPrep.QueryRowContext(ctx, ip.String())
 
-	var ipNew net.IP = ip
+	var sources, conflicts sql.NullString
 
 	obj := &models.AboutIPobject{}
 	err := row.Scan(
 		&obj.Status, &obj.Continent, &obj.ContinentCode, &obj.Country, &obj.CountryCode,
 		&obj.Region, &obj.RegionName, &obj.City, &obj.District, &obj.Zip, &obj.Lat, &obj.Lon,
 		&obj.Timezone, &obj.Offset, &obj.Currency, &obj.Isp, &obj.Org, &obj.As, &obj.Asname,
-		&obj.Reverse, &obj.Mobile, &obj.Proxy, &obj.Hosting, &obj.RequestTime, &ipNew,
+		&obj.Reverse, &obj.Mobile, &obj.Proxy, &obj.Hosting, &obj.RequestTime,
+		&sources, &conflicts,
 	)
 
 	if err == sql.ErrNoRows {
@@ -179,16 +232,82 @@ func (d *IpInfoSqlite) Get(ctx context.Context, ip net.IP) (*models.AboutIPobjec
 		return nil, err
 	}
 
+	if time.Since(obj.RequestTime) > d.ttl {
+		return nil, nil
+	}
+
+	if sources.Valid {
+		if err := json.Unmarshal([]byte(sources.String), &obj.Sources); err != nil {
+			return nil, err
+		}
+	}
+
+	if conflicts.Valid {
+		if err := json.Unmarshal([]byte(conflicts.String), &obj.Conflicts); err != nil {
+			return nil, err
+		}
+	}
+
 	return obj, nil
 }
 
+// Close - closes prepared statements and database
+func (d *IpInfoSqlite) Close() error {
+	d.savePrep.Close()
+	d.getPrep.Close()
+	return d.db.Close()
+}
+
+// starskeyRecord - stored value. RequestTime is hidden from AboutIPobject JSON
+type starskeyRecord struct {
+	Object      models.AboutIPobject `json:"object"`
+	RequestTime time.Time            `json:"request_time"`
+}
+
+// IpInfoStarskey - use as Starskey (LSM-tree) cache for IP requests
 type IpInfoStarskey struct {
-	db *starskey.Starskey
+	db        *starskey.Starskey
+	logCh     chan string
+	ttl       time.Duration
+	closeOnce sync.Once
+	closeErr  error
 }
 
-func NewIpInfoStarskey(ctx context.Context, db string) (*IpInfoStarskey, error) {
+/*
+NewIpInfoStarskey - open Starskey IP info cache by db directory path
 
-	stubLogCh := make(chan string)
+	Cached objects older than ttl are ignored. If ttl <= 0, maxCacheAge is used.
+	Database closes after ctx done or Close call.
+*/
+func NewIpInfoStarskey(ctx context.Context, db string, ttl time.Duration) (*IpInfoStarskey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to init cache: %w", err)
+	}
+
+	skey, logCh, err := openStarskey(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cache: %w", err)
+	}
+
+	self := &IpInfoStarskey{
+		db:    skey,
+		logCh: logCh,
+		ttl:   cacheTTL(ttl),
+	}
+
+	go func() {
+		<-ctx.Done()
+		self.Close()
+	}()
+
+	return self, nil
+}
+
+// openStarskey - open Starskey database directory with drained log channel. Channel must be closed after database
+func openStarskey(db string) (*starskey.Starskey, chan string, error) {
+
+	// buffered: starskey drops to log.Println when channel send would block
+	stubLogCh := make(chan string, 256)
 
 	go func() {
 		for range stubLogCh {
@@ -210,23 +329,21 @@ func NewIpInfoStarskey(ctx context.Context, db string) (*IpInfoStarskey, error)
 		},
 	})
 
-	if ctx.Err() != nil {
-		return nil, fmt.Errorf("failed to init cache: %w", err)
-	}
-
 	if err != nil {
-		return nil, fmt.Errorf("failed to init cache: %w", err)
-	}
-
-	go func() {
-		<-ctx.Done()
-		skey.Close()
 		close(stubLogCh)
-	}()
+		return nil, nil, err
+	}
 
-	self := &IpInfoStarskey{db: skey}
+	return skey, stubLogCh, nil
+}
 
-	return self, nil
+// Close - flushes and closes database. Safe for repeated calls
+func (d *IpInfoStarskey) Close() error {
+	d.closeOnce.Do(func() {
+		d.closeErr = d.db.Close()
+		close(d.logCh)
+	})
+	return d.closeErr
 }
 
 func (d *IpInfoStarskey) Get(ctx context.Context, ip net.IP) (*models.AboutIPobject, error) {
@@ -234,22 +351,33 @@ func (d *IpInfoStarskey) Get(ctx context.Context, ip net.IP) (*models.AboutIPobj
 		return nil, err
 	}
 
-	payload, err := d.db.Get(ip)
+	if ip == nil {
+		return nil, errors.New("ip is nil")
+	}
+
+	payload, err := d.db.Get([]byte(ip.String()))
 	if err != nil {
 		return nil, err
 	}
 
-	obj := &models.AboutIPobject{}
-	if err := json.Unmarshal(payload, obj); err != nil {
+	if payload == nil {
+		return nil, nil
+	}
+
+	rec := starskeyRecord{}
+	if err := json.Unmarshal(payload, &rec); err != nil {
 		return nil, err
 	}
 
-	expireTime := obj.RequestTime.Add(maxCacheAge)
+	expireTime := rec.RequestTime.Add(d.ttl)
 	if time.Now().After(expireTime) {
 		return nil, nil
 	}
 
-	return obj, err
+	obj := rec.Object
+	obj.RequestTime = rec.RequestTime
+
+	return &obj, nil
 }
 
 func (d *IpInfoStarskey) Save(ctx context.Context, ip net.IP, obj models.AboutIPobject) error {
@@ -261,10 +389,13 @@ func (d *IpInfoStarskey) Save(ctx context.Context, ip net.IP, obj models.AboutIP
 		return errors.New("ip is nil")
 	}
 
-	payload, err := json.Marshal(obj)
+	payload, err := json.Marshal(starskeyRecord{
+		Object:      obj,
+		RequestTime: obj.RequestTime,
+	})
 	if err != nil {
 		return err
 	}
 
-	return d.db.Put(ip, payload)
+	return d.db.Put([]byte(ip.String()), payload)
 }
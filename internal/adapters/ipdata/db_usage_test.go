@@ -0,0 +1,27 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIpInfoStarskeyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	db := filepath.Join(t.TempDir(), "cache")
+	if store, err := NewIpInfoStarskey(ctx, db, 0); !errors.Is(err, context.Canceled) || store != nil {
+		t.Fatalf("got %v, %v, want canceled", store, err)
+	}
+	// database is not opened
+	if _, err := os.Stat(db); !os.IsNotExist(err) {
+		t.Fatalf("database directory is created: %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+)
+
+// axfrTimeout - dial and single message read timeout of zone transfer if it's not set
+const axfrTimeout = 10 * time.Second
+
+/*
+AXFRProbe - zone transfer (AXFR, RFC 5936) attempts against every address of zone nameservers
+
+	Nameserver addresses are resolved with resolver, transfers go straight to servers over TCP port 53 (SetPort).
+	Zone of first allowed transfer can be dumped to directory in RFC 1035 master file format.
+*/
+type AXFRProbe struct {
+	rv      models.Resolver
+	timeout time.Duration
+	port    string
+	dumpDir string
+}
+
+func NewAXFRProbe(rv models.Resolver) *AXFRProbe {
+	return &AXFRProbe{
+		rv:      rv,
+		timeout: axfrTimeout,
+		port:    "53",
+	}
+}
+
+// SetTimeout - dial, nameserver address lookup and single message read timeout. Whole transfer is not limited. Default if d <= 0
+func (p *AXFRProbe) SetTimeout(d time.Duration) {
+	if d <= 0 {
+		d = axfrTimeout
+	}
+	p.timeout = d
+}
+
+// SetPort - TCP port of nameservers transfers go to, 53 by default
+func (p *AXFRProbe) SetPort(port string) {
+	p.port = port
+}
+
+// SetDumpDir - write zones of allowed transfers to dir as "<zone>.zone". Disabled if empty
+func (p *AXFRProbe) SetDumpDir(dir string) {
+	p.dumpDir = dir
+}
+
+// TransferZone - try AXFR of zone from every address of nameservers at once
+func (p *AXFRProbe) TransferZone(ctx context.Context, zone string, nameservers []string) (models.ZoneTransfer, error) {
+	if len(nameservers) == 0 {
+		return models.ZoneTransfer{}, fmt.Errorf("no nameservers of %s to transfer zone from", zone)
+	}
+
+	zone = dns.Fqdn(strings.ToLower(zone))
+
+	var (
+		res    = models.ZoneTransfer{}
+		mu     = sync.Mutex{}
+		wg     = &sync.WaitGroup{}
+		dumped bool
+	)
+
+	// dump - zone of first allowed transfer only
+	dump := func(records []dns.RR, srv models.AXFRServer) {
+		if p.dumpDir == "" {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if dumped {
+			return
+		}
+		dumped = true
+
+		file, err := p.dump(zone, srv, records)
+		if err != nil {
+			res.Err = err.Error()
+			return
+		}
+		res.File = file
+	}
+
+	for _, ns := range nameservers {
+		wg.Go(func() {
+			servers := p.transferNS(ctx, zone, ns, dump)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, srv := range servers {
+				res.Servers = append(res.Servers, srv)
+				res.Open = res.Open || srv.Allowed
+			}
+		})
+	}
+
+	wg.Wait()
+
+	slices.SortFunc(res.Servers, func(a, b models.AXFRServer) int {
+		return strings.Compare(a.NS+" "+a.Address, b.NS+" "+b.Address)
+	})
+
+	return res, nil
+}
+
+// transferNS - AXFR attempts against every address of nameserver, allowed is called with records of every successful transfer
+func (p *AXFRProbe) transferNS(
+	ctx context.Context, zone, ns string, allowed func([]dns.RR, models.AXFRServer),
+) []models.AXFRServer {
+	ns = strings.ToLower(strings.TrimSuffix(ns, "."))
+
+	lookupCtx, cancel := withTimeout(ctx, p.timeout)
+	ips, err := p.rv.ResolveIP(lookupCtx, ns)
+	cancel()
+
+	if err != nil {
+		return []models.AXFRServer{{NS: ns, Err: err.Error(), ErrCode: models.ErrorCodeOf(err)}}
+	}
+
+	var (
+		servers = make([]models.AXFRServer, len(ips))
+		wg      = &sync.WaitGroup{}
+	)
+
+	for i, ip := range ips {
+		wg.Go(func() {
+			start := time.Now()
+
+			srv := models.AXFRServer{
+				NS:      ns,
+				Address: net.JoinHostPort(ip.String(), p.port),
+			}
+
+			records, err := p.transfer(ctx, zone, srv.Address)
+			srv.DurationMs = time.Since(start).Milliseconds()
+
+			if err != nil {
+				srv.Err, srv.ErrCode = err.Error(), models.ErrorCodeOf(err)
+			} else {
+				// closing SOA repeats the first one
+				srv.Allowed, srv.Records = true, len(records)-1
+				srv.Serial = records[0].(*dns.SOA).Serial
+				allowed(records, srv)
+			}
+
+			servers[i] = srv
+		})
+	}
+
+	wg.Wait()
+	return servers
+}
+
+/*
+transfer - AXFR of zone from server address
+
+	Transfer is complete when answer stream starts and ends with SOA of zone (RFC 5936 2.2).
+	Error rcode of any message (REFUSED, NOTAUTH) is RcodeError.
+*/
+func (p *AXFRProbe) transfer(ctx context.Context, zone, addr string) ([]dns.RR, error) {
+	d := net.Dialer{Timeout: p.timeout}
+
+	raw, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &dns.Conn{Conn: raw}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { raw.Close() })
+	defer stop()
+
+	msg := new(dns.Msg)
+	msg.SetAxfr(zone)
+
+	conn.SetWriteDeadline(time.Now().Add(p.timeout))
+	if err := conn.WriteMsg(msg); err != nil {
+		return nil, ctxOr(ctx, err)
+	}
+
+	var records []dns.RR
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(p.timeout))
+
+		in, err := conn.ReadMsg()
+		if err != nil {
+			return nil, ctxOr(ctx, exchangeError(err))
+		}
+
+		if in.Id != msg.Id {
+			return nil, &models.LookupError{Code: models.CodeMalformed, Err: errors.New("AXFR answer id mismatch")}
+		}
+
+		if in.Rcode != dns.RcodeSuccess {
+			return nil, &RcodeError{Name: zone, Type: "AXFR", Rcode: in.Rcode}
+		}
+
+		if len(records) == 0 {
+			if len(in.Answer) == 0 {
+				return nil, noRecordsErr(zone, "AXFR")
+			}
+
+			if soa, ok := in.Answer[0].(*dns.SOA); !ok || !strings.EqualFold(soa.Hdr.Name, zone) {
+				return nil, &models.LookupError{Code: models.CodeMalformed, Err: errors.New("AXFR answer doesn't start with SOA of zone")}
+			}
+		}
+
+		records = append(records, in.Answer...)
+
+		if len(records) > 1 {
+			if _, ok := records[len(records)-1].(*dns.SOA); ok {
+				return records, nil
+			}
+		}
+	}
+}
+
+// ctxOr - ctx error if ctx is done: closed connection error hides interrupt
+func ctxOr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+/*
+dump - write transferred zone as master file, closing SOA is dropped
+
+	Zone name is file name, names with path separators are rejected: DNS labels may have any byte, "/" and "\" too.
+*/
+func (p *AXFRProbe) dump(zone string, srv models.AXFRServer, records []dns.RR) (string, error) {
+	name := strings.TrimSuffix(zone, ".")
+	if strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("zone %s can't be dump file name: it has path separator", zone)
+	}
+
+	if err := os.MkdirAll(p.dumpDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create zone dump directory: %w", err)
+	}
+
+	path := filepath.Join(p.dumpDir, name+".zone")
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create zone dump: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "; %s transferred from %s (%s) at %s\n", zone, srv.NS, srv.Address, time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "$ORIGIN %s\n", zone)
+
+	for _, rr := range records[:len(records)-1] {
+		fmt.Fprintln(w, rr.String())
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write zone dump: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write zone dump: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+)
+
+// startTCPDNS - TCP DNS servers on loopback IPs sharing one port, returns the port
+func startTCPDNS(t *testing.T, handlers map[string]dns.HandlerFunc) string {
+	t.Helper()
+
+	for attempt := 0; attempt < 10; attempt++ {
+		var (
+			listeners []net.Listener
+			port      = "0"
+			err       error
+		)
+
+		for ip := range handlers {
+			var l net.Listener
+			if l, err = net.Listen("tcp", net.JoinHostPort(ip, port)); err != nil {
+				break
+			}
+			listeners = append(listeners, l)
+			port = strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+		}
+
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			continue
+		}
+
+		for _, l := range listeners {
+			ip := l.Addr().(*net.TCPAddr).IP.String()
+			srv := &dns.Server{Listener: l, Handler: handlers[ip]}
+
+			started := make(chan struct{})
+			srv.NotifyStartedFunc = func() { close(started) }
+
+			go srv.ActivateAndServe()
+			<-started
+			t.Cleanup(func() { srv.Shutdown() })
+		}
+
+		return port
+	}
+
+	t.Fatal("no free port shared by loopback addresses")
+	return ""
+}
+
+// axfrZone - records of example.test. zone, starts and ends with SOA
+var axfrZone = []string{
+	"example.test. 3600 IN SOA ns1.dns.test. hostmaster.example.test. 2025010101 7200 3600 1209600 300",
+	"example.test. 3600 IN NS ns1.dns.test.",
+	"example.test. 3600 IN NS ns2.dns.test.",
+	"www.example.test. 300 IN A 192.0.2.10",
+	"mail.example.test. 300 IN A 192.0.2.25",
+	"example.test. 300 IN MX 10 mail.example.test.",
+	"example.test. 300 IN TXT \"v=spf1 -all\"",
+	"example.test. 3600 IN SOA ns1.dns.test. hostmaster.example.test. 2025010101 7200 3600 1209600 300",
+}
+
+// axfrServer - AXFR handler answering zone in messages of perMsg records, every other query is refused
+func axfrServer(t *testing.T, perMsg int) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		if r.Question[0].Qtype != dns.TypeAXFR || r.Question[0].Name != "example.test." {
+			resp := new(dns.Msg)
+			resp.SetRcode(r, dns.RcodeRefused)
+			w.WriteMsg(resp)
+			return
+		}
+
+		for i := 0; i < len(axfrZone); i += perMsg {
+			resp := new(dns.Msg)
+			resp.SetReply(r)
+			resp.Authoritative = true
+
+			for _, s := range axfrZone[i:min(i+perMsg, len(axfrZone))] {
+				resp.Answer = append(resp.Answer, mustRR(t, s))
+			}
+			if err := w.WriteMsg(resp); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}
+}
+
+func refuseServer(w dns.ResponseWriter, r *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetRcode(r, dns.RcodeRefused)
+	w.WriteMsg(resp)
+}
+
+func TestTransferZone(t *testing.T) {
+	dnsPort := startDNS(t, map[string]dns.HandlerFunc{
+		"127.0.0.1": authority(t, "dns.test.",
+			"ns1.dns.test. 300 IN A 127.0.0.2",
+			"ns2.dns.test. 300 IN A 127.0.0.3",
+			"ns3.dns.test. 300 IN A 127.0.0.4",
+		),
+	})
+	axfrPort := startTCPDNS(t, map[string]dns.HandlerFunc{
+		"127.0.0.2": axfrServer(t, len(axfrZone)),
+		"127.0.0.3": refuseServer,
+		"127.0.0.4": axfrServer(t, 3),
+	})
+
+	rs, err := NewRemoteResolver("127.0.0.1:" + dnsPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	p := NewAXFRProbe(rs)
+	p.SetPort(axfrPort)
+	p.SetTimeout(2 * time.Second)
+	p.SetDumpDir(dir)
+
+	res, err := p.TransferZone(context.Background(), "Example.test", []string{"ns1.dns.test.", "ns2.dns.test", "ns3.dns.test."})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.Open || len(res.Servers) != 3 {
+		t.Fatalf("got open %v with %d servers, want open with 3: %+v", res.Open, len(res.Servers), res)
+	}
+
+	for i, want := range []struct {
+		ns      string
+		allowed bool
+		code    models.ErrorCode
+	}{
+		{ns: "ns1.dns.test", allowed: true},
+		{ns: "ns2.dns.test", code: models.CodeREFUSED},
+		{ns: "ns3.dns.test", allowed: true},
+	} {
+		srv := res.Servers[i]
+		if srv.NS != want.ns || srv.Allowed != want.allowed || srv.ErrCode != want.code {
+			t.Errorf("server %d: got %+v, want %s allowed %v code %q", i, srv, want.ns, want.allowed, want.code)
+		}
+		if !srv.Allowed {
+			continue
+		}
+		// single message and multi-message transfers give the same zone
+		if srv.Records != len(axfrZone)-1 || srv.Serial != 2025010101 {
+			t.Errorf("server %s: got %d records of serial %d, want %d of 2025010101", srv.NS, srv.Records, srv.Serial, len(axfrZone)-1)
+		}
+	}
+
+	if want := filepath.Join(dir, "example.test.zone"); res.File != want || res.Err != "" {
+		t.Fatalf("got dump %q (%s), want %q", res.File, res.Err, want)
+	}
+
+	data, err := os.ReadFile(res.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		zp    = dns.NewZoneParser(strings.NewReader(string(data)), "", res.File)
+		count int
+	)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if want := mustRR(t, axfrZone[count]); !dns.IsDuplicate(rr, want) {
+			t.Errorf("dump record %d: got %s, want %s", count, rr, want)
+		}
+		count++
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != len(axfrZone)-1 {
+		t.Fatalf("dump has %d records, want %d without closing SOA", count, len(axfrZone)-1)
+	}
+}
+
+func TestTransferZoneRefused(t *testing.T) {
+	dnsPort := startDNS(t, map[string]dns.HandlerFunc{
+		"127.0.0.1": authority(t, "dns.test.", "ns1.dns.test. 300 IN A 127.0.0.2"),
+	})
+	axfrPort := startTCPDNS(t, map[string]dns.HandlerFunc{"127.0.0.2": refuseServer})
+
+	rs, _ := NewRemoteResolver("127.0.0.1:" + dnsPort)
+	dir := t.TempDir()
+
+	p := NewAXFRProbe(rs)
+	p.SetPort(axfrPort)
+	p.SetDumpDir(dir)
+
+	res, err := p.TransferZone(context.Background(), "example.test", []string{"ns1.dns.test", "missing.dns.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Open || res.File != "" || len(res.Servers) != 2 {
+		t.Fatalf("got %+v, want closed zone without dump", res)
+	}
+	if srv := res.Servers[0]; srv.NS != "missing.dns.test" || srv.ErrCode != models.CodeNXDOMAIN {
+		t.Errorf("got %+v, want nxdomain nameserver lookup", srv)
+	}
+	if srv := res.Servers[1]; srv.Address != net.JoinHostPort("127.0.0.2", axfrPort) || srv.ErrCode != models.CodeREFUSED {
+		t.Errorf("got %+v, want refused transfer", srv)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("closed zone is dumped: %v", entries)
+	}
+}
+
+func TestAXFRDumpName(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		p       = NewAXFRProbe(nil)
+		records []dns.RR
+	)
+	p.SetDumpDir(filepath.Join(dir, "zones"))
+
+	for _, s := range axfrZone {
+		records = append(records, mustRR(t, s))
+	}
+
+	// raw input names and escaped labels of presentation format
+	for _, zone := range []string{"../../evil.test.", `\.\.\/evil.test.`, `a\\b.test.`} {
+		if file, err := p.dump(zone, models.AXFRServer{}, records); err == nil {
+			t.Errorf("zone %s is dumped to %s", zone, file)
+		}
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("files are created: %v", entries)
+	}
+
+	file, err := p.dump("example.test.", models.AXFRServer{NS: "ns1.dns.test", Address: "127.0.0.2:53"}, records)
+	if err != nil || file != filepath.Join(dir, "zones", "example.test.zone") {
+		t.Fatalf("got %q, %v", file, err)
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+	"github.com/starskey-io/starskey"
+)
+
+const (
+	// DnsCacheSize - default count of in-memory DNS cache answers
+	DnsCacheSize = 4096
+	// dnsCacheUnknownTTL - lifetime of answers without TTL (system resolver)
+	dnsCacheUnknownTTL = time.Minute
+	// dnsCacheNegativeTTL - lifetime of negative answers without SOA in authority section
+	dnsCacheNegativeTTL = 30 * time.Second
+	// dnsCacheMaxTTL - answer lifetime cap, broken servers may send huge TTLs
+	dnsCacheMaxTTL = 24 * time.Hour
+)
+
+// dnsCacheEntry - cached answer: records or negative answer
+type dnsCacheEntry struct {
+	Records  []models.DnsRecord `json:"records,omitempty"`
+	Negative *NegativeAnswer    `json:"negative,omitempty"`
+	Expires  time.Time          `json:"expires"`
+}
+
+// result - answer with TTLs counted down to remaining lifetime
+func (e dnsCacheEntry) result(now time.Time) ([]models.DnsRecord, error) {
+	left := uint32(e.Expires.Sub(now) / time.Second)
+
+	if e.Negative != nil {
+		negative := *e.Negative
+		negative.TTL = left
+		return nil, &negative
+	}
+
+	records := make([]models.DnsRecord, len(e.Records))
+	for i, rec := range e.Records {
+		rec.TTL = min(rec.TTL, left)
+		records[i] = rec
+	}
+	return records, nil
+}
+
+// newDnsCacheEntry - cache entry of resolve result. Reports false if result must not be cached (resolver failure)
+func newDnsCacheEntry(records []models.DnsRecord, err error, now time.Time) (dnsCacheEntry, bool) {
+	var negative *NegativeAnswer
+
+	switch {
+	case errors.As(err, &negative):
+		ttl := dnsCacheNegativeTTL
+		if negative.TTL > 0 {
+			ttl = time.Duration(negative.TTL) * time.Second
+		}
+		return dnsCacheEntry{Negative: negative, Expires: now.Add(min(ttl, dnsCacheMaxTTL))}, true
+
+	case err != nil, len(records) == 0:
+		return dnsCacheEntry{}, false
+	}
+
+	var minTTL uint32
+	for _, rec := range records {
+		if rec.TTL > 0 && (minTTL == 0 || rec.TTL < minTTL) {
+			minTTL = rec.TTL
+		}
+	}
+
+	ttl := dnsCacheUnknownTTL
+	if minTTL > 0 {
+		ttl = time.Duration(minTTL) * time.Second
+	}
+
+	return dnsCacheEntry{Records: records, Expires: now.Add(min(ttl, dnsCacheMaxTTL))}, true
+}
+
+// DnsCacheStats - DNS cache usage counters
+type DnsCacheStats struct {
+	Hits   uint64 `json:"hits" yaml:"hits"`
+	Misses uint64 `json:"misses" yaml:"misses"`
+}
+
+// HitRatio - part of queries answered from cache: 0..1
+func (s DnsCacheStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+func (s DnsCacheStats) String() string {
+	return fmt.Sprintf("hits %d, misses %d, hit ratio %.1f%%", s.Hits, s.Misses, s.HitRatio()*100)
+}
+
+/*
+CacheResolve - resolver decorator with answers cache by record TTL
+
+	Positive answers live for the lowest record TTL, negative answers (NXDOMAIN, NODATA)
+	for SOA minimum of authority section (RFC 2308). Resolver failures are not cached.
+	Answers are kept in LRU memory cache with optional Starskey persistent layer.
+*/
+type CacheResolve struct {
+	rv    models.Resolver
+	store *DnsCacheStarskey
+
+	mu    sync.Mutex
+	size  int
+	lru   *list.List
+	items map[string]*list.Element
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type dnsCacheItem struct {
+	key   string
+	entry dnsCacheEntry
+}
+
+// NewCacheResolver - cache answers of rv, at most size answers are kept in memory (DnsCacheSize if <= 0)
+func NewCacheResolver(rv models.Resolver, size int) *CacheResolve {
+	if size <= 0 {
+		size = DnsCacheSize
+	}
+
+	return &CacheResolve{
+		rv:    rv,
+		size:  size,
+		lru:   list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// SetStore - set persistent cache layer behind memory cache. Store is closed by caller
+func (c *CacheResolve) SetStore(store *DnsCacheStarskey) {
+	c.store = store
+}
+
+// Stats - cache hits and misses since start
+func (c *CacheResolve) Stats() DnsCacheStats {
+	return DnsCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// Close - close wrapped resolver if it holds connections
+func (c *CacheResolve) Close() error {
+	if closer, ok := c.rv.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func dnsCacheKey(s, rtype string) string {
+	return strings.ToLower(dns.Fqdn(s)) + "/" + strings.ToUpper(rtype)
+}
+
+// get - unexpired entry from memory, then from persistent layer
+func (c *CacheResolve) get(key string, now time.Time) (dnsCacheEntry, bool) {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		item := el.Value.(*dnsCacheItem)
+		if now.Before(item.entry.Expires) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			return item.entry, true
+		}
+		c.lru.Remove(el)
+		delete(c.items, key)
+	}
+	c.mu.Unlock()
+
+	if c.store == nil {
+		return dnsCacheEntry{}, false
+	}
+
+	entry, ok := c.store.Get(key)
+	if !ok || !now.Before(entry.Expires) {
+		return dnsCacheEntry{}, false
+	}
+
+	c.put(key, entry)
+	return entry, true
+}
+
+// put - save entry in memory, least recently used entry is evicted on overflow
+func (c *CacheResolve) put(key string, entry dnsCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*dnsCacheItem).entry = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&dnsCacheItem{key: key, entry: entry})
+
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*dnsCacheItem).key)
+	}
+}
+
+func (c *CacheResolve) ResolveRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	key := dnsCacheKey(s, rtype)
+
+	if entry, ok := c.get(key, time.Now()); ok {
+		c.hits.Add(1)
+		return entry.result(time.Now())
+	}
+	c.misses.Add(1)
+
+	records, err := c.rv.ResolveRecords(ctx, s, rtype)
+
+	if entry, ok := newDnsCacheEntry(records, err, time.Now()); ok {
+		c.put(key, entry)
+		if c.store != nil {
+			c.store.Save(key, entry)
+		}
+	}
+
+	return records, err
+}
+
+func (c *CacheResolve) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
+	return resolveIPRecords(ctx, s, c.ResolveRecords)
+}
+
+func (c *CacheResolve) ResolveNS(ctx context.Context, s string) ([]string, error) {
+	return resolveNameRecords(ctx, s, "NS", c.ResolveRecords)
+}
+
+func (c *CacheResolve) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return resolvePTRRecords(ctx, ip, c.ResolveRecords)
+}
+
+// =======================================
+
+// DnsCacheStarskey - Starskey (LSM-tree) persistent layer of DNS answers cache
+type DnsCacheStarskey struct {
+	db        *starskey.Starskey
+	logCh     chan string
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+/*
+NewDnsCacheStarskey - open Starskey DNS cache by db directory path
+
+	Database is closed when ctx is done.
+*/
+func NewDnsCacheStarskey(ctx context.Context, db string) (*DnsCacheStarskey, error) {
+	skey, logCh, err := openStarskey(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init DNS cache: %w", err)
+	}
+
+	self := &DnsCacheStarskey{
+		db:    skey,
+		logCh: logCh,
+	}
+
+	go func() {
+		<-ctx.Done()
+		self.Close()
+	}()
+
+	return self, nil
+}
+
+// Close - flushes and closes database. Safe for repeated calls
+func (d *DnsCacheStarskey) Close() error {
+	d.closeOnce.Do(func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.closed = true
+		d.closeErr = d.db.Close()
+		close(d.logCh)
+	})
+	return d.closeErr
+}
+
+// Get - stored entry by key. Broken or missing entries are misses
+func (d *DnsCacheStarskey) Get(key string) (dnsCacheEntry, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return dnsCacheEntry{}, false
+	}
+
+	payload, err := d.db.Get([]byte(key))
+	if err != nil || payload == nil {
+		return dnsCacheEntry{}, false
+	}
+
+	entry := dnsCacheEntry{}
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		return dnsCacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+func (d *DnsCacheStarskey) Save(key string, entry dnsCacheEntry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return errors.New("DNS cache is closed")
+	}
+	return d.db.Put([]byte(key), payload)
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+)
+
+// countingResolver - fixed answers by cache key with count of resolve calls
+type countingResolver struct {
+	answers map[string][]models.DnsRecord
+	errs    map[string]error
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (r *countingResolver) ResolveRecords(ctx context.Context, name, rtype string) ([]models.DnsRecord, error) {
+	key := dnsCacheKey(name, rtype)
+
+	r.mu.Lock()
+	if r.calls == nil {
+		r.calls = map[string]int{}
+	}
+	r.calls[key]++
+	r.mu.Unlock()
+
+	if err, ok := r.errs[key]; ok {
+		return nil, err
+	}
+	if records, ok := r.answers[key]; ok {
+		return records, nil
+	}
+	return nil, nxdomainErr(name, rtype, 0)
+}
+
+func (r *countingResolver) count(name, rtype string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[dnsCacheKey(name, rtype)]
+}
+
+func (r *countingResolver) ResolveIP(ctx context.Context, name string) ([]net.IP, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *countingResolver) ResolveNS(ctx context.Context, name string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *countingResolver) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func aRecord(name string, ttl uint32) []models.DnsRecord {
+	return []models.DnsRecord{{Name: name, Type: "A", TTL: ttl, Data: "192.0.2.1"}}
+}
+
+func TestCacheResolvePositive(t *testing.T) {
+	rv := &countingResolver{
+		answers: map[string][]models.DnsRecord{
+			dnsCacheKey("example.test", "A"): {
+				{Name: "example.test.", Type: "A", TTL: 300, Data: "192.0.2.1"},
+				{Name: "example.test.", Type: "A", TTL: 120, Data: "192.0.2.2"},
+			},
+			dnsCacheKey("nottl.test", "A"): aRecord("nottl.test.", 0),
+		},
+		errs: map[string]error{
+			dnsCacheKey("broken.test", "A"): errors.New("network is unreachable"),
+		},
+	}
+	c := NewCacheResolver(rv, 0)
+	ctx := context.Background()
+
+	for range 3 {
+		// name case and trailing dot make the same key
+		for _, name := range []string{"example.test", "Example.TEST."} {
+			records, err := c.ResolveRecords(ctx, name, "a")
+			if err != nil || len(records) != 2 {
+				t.Fatalf("got %v, %v", records, err)
+			}
+		}
+	}
+	if n := rv.count("example.test", "A"); n != 1 {
+		t.Fatalf("resolved %d times, want 1", n)
+	}
+	if stats := c.Stats(); stats.Hits != 5 || stats.Misses != 1 {
+		t.Fatalf("got %s, want 5 hits and 1 miss", stats)
+	}
+
+	// lowest record TTL is entry lifetime
+	key := dnsCacheKey("example.test", "A")
+	now := time.Now()
+	if _, ok := c.get(key, now.Add(119*time.Second)); !ok {
+		t.Fatal("entry expired before lowest TTL")
+	}
+	if _, ok := c.get(key, now.Add(121*time.Second)); ok {
+		t.Fatal("entry lives after lowest TTL")
+	}
+	if _, err := c.ResolveRecords(ctx, "example.test", "A"); err != nil || rv.count("example.test", "A") != 2 {
+		t.Fatalf("expired entry is not resolved again: %v", err)
+	}
+
+	// records without TTL live for dnsCacheUnknownTTL
+	c.ResolveRecords(ctx, "nottl.test", "A")
+	key = dnsCacheKey("nottl.test", "A")
+	if _, ok := c.get(key, time.Now().Add(dnsCacheUnknownTTL-time.Second)); !ok {
+		t.Fatal("entry without TTL expired early")
+	}
+	if _, ok := c.get(key, time.Now().Add(dnsCacheUnknownTTL+time.Second)); ok {
+		t.Fatal("entry without TTL lives too long")
+	}
+
+	// resolver failures are not cached
+	for range 2 {
+		if _, err := c.ResolveRecords(ctx, "broken.test", "A"); err == nil {
+			t.Fatal("failure is lost")
+		}
+	}
+	if n := rv.count("broken.test", "A"); n != 2 {
+		t.Fatalf("failure resolved %d times, want 2", n)
+	}
+}
+
+func TestCacheResolveTTLCountdown(t *testing.T) {
+	rv := &countingResolver{
+		answers: map[string][]models.DnsRecord{dnsCacheKey("example.test", "A"): aRecord("example.test.", 300)},
+	}
+	c := NewCacheResolver(rv, 0)
+	c.ResolveRecords(context.Background(), "example.test", "A")
+
+	entry, ok := c.get(dnsCacheKey("example.test", "A"), time.Now())
+	if !ok {
+		t.Fatal("no entry")
+	}
+
+	records, _ := entry.result(entry.Expires.Add(-100 * time.Second))
+	if records[0].TTL != 100 {
+		t.Fatalf("got TTL %d, want remaining 100", records[0].TTL)
+	}
+	// cached records keep original TTL
+	if entry.Records[0].TTL != 300 {
+		t.Fatalf("cached TTL is changed to %d", entry.Records[0].TTL)
+	}
+}
+
+func TestCacheResolveNegative(t *testing.T) {
+	var queries atomic.Int32
+
+	// SOA of authority: TTL 300, minimum 60
+	zone := authority(t, "example.test.", "www.example.test. 300 IN A 192.0.2.1")
+	port := startDNS(t, map[string]dns.HandlerFunc{
+		"127.0.0.1": func(w dns.ResponseWriter, r *dns.Msg) {
+			queries.Add(1)
+			zone(w, r)
+		},
+	})
+
+	rs, err := NewRemoteResolver("127.0.0.1:" + port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCacheResolver(rs, 0)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name, rtype string
+		code        models.ErrorCode
+	}{
+		{name: "missing.example.test", rtype: "A", code: models.CodeNXDOMAIN},
+		{name: "www.example.test", rtype: "MX", code: models.CodeNODATA},
+	} {
+		queries.Store(0)
+
+		for range 3 {
+			_, err := c.ResolveRecords(ctx, tt.name, tt.rtype)
+
+			var negative *NegativeAnswer
+			if !errors.As(err, &negative) || models.ErrorCodeOf(err) != tt.code {
+				t.Fatalf("%s %s: got %v, want %s", tt.name, tt.rtype, err, tt.code)
+			}
+			if negative.TTL == 0 || negative.TTL > 60 {
+				t.Fatalf("%s %s: got TTL %d, want SOA minimum 60", tt.name, tt.rtype, negative.TTL)
+			}
+		}
+		if n := queries.Load(); n != 1 {
+			t.Fatalf("%s %s: %d queries, want 1", tt.name, tt.rtype, n)
+		}
+
+		key := dnsCacheKey(tt.name, tt.rtype)
+		now := time.Now()
+		if _, ok := c.get(key, now.Add(59*time.Second)); !ok {
+			t.Fatalf("%s %s expired before SOA minimum", tt.name, tt.rtype)
+		}
+		if _, ok := c.get(key, now.Add(61*time.Second)); ok {
+			t.Fatalf("%s %s lives after SOA minimum", tt.name, tt.rtype)
+		}
+	}
+
+	// negative answer without SOA lives for dnsCacheNegativeTTL
+	entry, ok := newDnsCacheEntry(nil, noRecordsErr("example.test", "A"), time.Unix(0, 0))
+	if !ok || entry.Expires != time.Unix(0, 0).Add(dnsCacheNegativeTTL) {
+		t.Fatalf("got %+v, want expiry after %s", entry, dnsCacheNegativeTTL)
+	}
+}
+
+func TestCacheResolveLRU(t *testing.T) {
+	rv := &countingResolver{answers: map[string][]models.DnsRecord{}}
+	for _, name := range []string{"a.test", "b.test", "c.test"} {
+		rv.answers[dnsCacheKey(name, "A")] = aRecord(name+".", 300)
+	}
+
+	c := NewCacheResolver(rv, 2)
+	ctx := context.Background()
+
+	// a is used after b, so b is least recently used when c comes in
+	for _, name := range []string{"a.test", "b.test", "a.test", "c.test", "a.test", "b.test"} {
+		if _, err := c.ResolveRecords(ctx, name, "A"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string]int{"a.test": 1, "b.test": 2, "c.test": 1} {
+		if n := rv.count(name, "A"); n != want {
+			t.Errorf("%s resolved %d times, want %d", name, n, want)
+		}
+	}
+	if n := c.lru.Len(); n != 2 || len(c.items) != 2 {
+		t.Fatalf("cache holds %d entries (%d keys), want 2", n, len(c.items))
+	}
+	// b evicted c on its return
+	if _, ok := c.items[dnsCacheKey("c.test", "A")]; ok {
+		t.Fatal("c is kept, want evicted")
+	}
+}
+
+func TestCacheResolveStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := NewDnsCacheStarskey(ctx, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	rv := &countingResolver{
+		answers: map[string][]models.DnsRecord{dnsCacheKey("example.test", "A"): aRecord("example.test.", 300)},
+	}
+
+	first := NewCacheResolver(rv, 0)
+	first.SetStore(store)
+	first.ResolveRecords(ctx, "example.test", "A")
+	first.ResolveRecords(ctx, "missing.test", "A")
+
+	// new memory cache is filled from persistent layer
+	second := NewCacheResolver(rv, 0)
+	second.SetStore(store)
+
+	records, err := second.ResolveRecords(ctx, "example.test", "A")
+	if err != nil || len(records) != 1 || records[0].Data != "192.0.2.1" {
+		t.Fatalf("got %v, %v", records, err)
+	}
+	if _, err := second.ResolveRecords(ctx, "missing.test", "A"); models.ErrorCodeOf(err) != models.CodeNXDOMAIN {
+		t.Fatalf("got %v, want stored NXDOMAIN", err)
+	}
+	if rv.count("example.test", "A") != 1 || rv.count("missing.test", "A") != 1 {
+		t.Fatal("stored answers are resolved again")
+	}
+	if stats := second.Stats(); stats.Hits != 2 || stats.Misses != 0 {
+		t.Fatalf("got %s, want 2 hits", stats)
+	}
+	if _, ok := second.items[dnsCacheKey("example.test", "A")]; !ok {
+		t.Fatal("stored entry is not moved to memory")
+	}
+
+	// expired stored entries are misses
+	key := dnsCacheKey("old.test", "A")
+	if err := store.Save(key, dnsCacheEntry{Records: aRecord("old.test.", 300), Expires: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := second.get(key, time.Now()); ok {
+		t.Fatal("expired stored entry is a hit")
+	}
+
+	// store is closed with ctx, memory cache keeps working
+	cancel()
+	for i := 0; i < 100; i++ {
+		if _, ok := store.Get(key); !ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := store.Get(key); ok {
+		t.Fatal("closed store answers")
+	}
+	if err := store.Save(key, dnsCacheEntry{}); err == nil {
+		t.Fatal("closed store saves")
+	}
+	if _, err := second.ResolveRecords(context.Background(), "example.test", "A"); err != nil {
+		t.Fatal(err)
+	}
+}
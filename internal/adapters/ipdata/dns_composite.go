@@ -0,0 +1,289 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// ResolveStrategy - composite resolver upstreams asking strategy
+type ResolveStrategy string
+
+const (
+	// ResolveFallback - ask upstreams one by one in priority order until answer
+	ResolveFallback ResolveStrategy = "fallback"
+	// ResolveRace - ask all upstreams at once, first answer wins, negative one too
+	ResolveRace ResolveStrategy = "race"
+	// ResolveRoundRobin - start every query from next upstream, fall back to others on failure
+	ResolveRoundRobin ResolveStrategy = "roundrobin"
+)
+
+const (
+	// upstreamMaxFails - consecutive failures that take upstream out of rotation
+	upstreamMaxFails = 3
+	// upstreamCooldown - time of failing upstream out of rotation
+	upstreamCooldown = 30 * time.Second
+)
+
+// UpstreamHealth - composite resolver upstream state
+type UpstreamHealth struct {
+	Name      string    `json:"name" yaml:"name"`
+	Fails     int       `json:"fails" yaml:"fails"`
+	LastError string    `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	DownUntil time.Time `json:"down_until,omitzero" yaml:"down_until,omitempty"`
+}
+
+/*
+CompositeResolve - resolver over several upstream resolvers
+
+	Upstream with upstreamMaxFails failures in a row (timeouts, network errors, SERVFAIL, REFUSED,
+	malformed or rate limited responses) is taken out of rotation for upstreamCooldown.
+	Negative answers (NXDOMAIN, no records) are not failures.
+	If all upstreams are out of rotation, they are asked anyway.
+*/
+type CompositeResolve struct {
+	strategy  ResolveStrategy
+	upstreams []models.NamedResolver
+	attempt   time.Duration
+
+	mu     sync.Mutex
+	health []UpstreamHealth
+	next   atomic.Uint64
+}
+
+func NewCompositeResolver(strategy ResolveStrategy, upstreams ...models.NamedResolver) (*CompositeResolve, error) {
+	if len(upstreams) < 1 {
+		return nil, errors.New("no upstream resolvers for composite resolver")
+	}
+
+	switch strategy {
+	case ResolveFallback, ResolveRace, ResolveRoundRobin:
+	default:
+		return nil, fmt.Errorf("unknown composite resolver strategy: %s", strategy)
+	}
+
+	health := make([]UpstreamHealth, len(upstreams))
+	for i, up := range upstreams {
+		health[i].Name = up.Name
+	}
+
+	return &CompositeResolve{
+		strategy:  strategy,
+		upstreams: upstreams,
+		health:    health,
+	}, nil
+}
+
+/*
+SetLookupTimeout - single upstream query timeout of fallback and round-robin is lookup timeout shared by all upstreams
+
+	Slow upstream must not eat whole lookup: every one gets d / upstreams count. Disabled if d <= 0
+*/
+func (c *CompositeResolve) SetLookupTimeout(d time.Duration) {
+	if d <= 0 {
+		c.attempt = 0
+		return
+	}
+	c.attempt = d / time.Duration(len(c.upstreams))
+}
+
+// Health - current state of every upstream
+func (c *CompositeResolve) Health() []UpstreamHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	health := make([]UpstreamHealth, len(c.health))
+	copy(health, c.health)
+	return health
+}
+
+// order - upstream indexes to ask: healthy ones by strategy, then ones out of rotation
+func (c *CompositeResolve) order() []int {
+	var (
+		n     = len(c.upstreams)
+		start = 0
+		now   = time.Now()
+		up    = make([]int, 0, n)
+		down  = []int{}
+	)
+
+	if c.strategy == ResolveRoundRobin {
+		start = int((c.next.Add(1) - 1) % uint64(n))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range n {
+		i := (start + k) % n
+		if now.Before(c.health[i].DownUntil) {
+			down = append(down, i)
+			continue
+		}
+		up = append(up, i)
+	}
+
+	if len(up) == 0 {
+		return down
+	}
+
+	if c.strategy == ResolveRace {
+		return up
+	}
+
+	return append(up, down...)
+}
+
+// report - update upstream health by query result. Caller cancellation is not upstream failure
+func (c *CompositeResolve) report(ctx context.Context, i int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h := &c.health[i]
+
+	if err == nil {
+		h.Fails = 0
+		h.DownUntil = time.Time{}
+		return
+	}
+
+	if ctx.Err() != nil || !upstreamFailed(err) {
+		return
+	}
+
+	h.Fails++
+	h.LastError = err.Error()
+
+	// after cooldown single failure takes upstream out again, because Fails is reset only by success
+	if h.Fails >= upstreamMaxFails {
+		h.DownUntil = time.Now().Add(upstreamCooldown)
+	}
+}
+
+// upstreamFailed - error is resolver failure (network, timeout, broken server), not negative DNS answer or cancel
+func upstreamFailed(err error) bool {
+	switch models.ErrorCodeOf(err) {
+	case models.CodeTimeout, models.CodeNetwork, models.CodeSERVFAIL, models.CodeREFUSED,
+		models.CodeMalformed, models.CodeRateLimited:
+		return true
+	}
+	return false
+}
+
+// compositeQuery - ask upstreams with resolver strategy
+func compositeQuery[T any](ctx context.Context, c *CompositeResolve, q func(context.Context, models.Resolver) (T, error)) (T, error) {
+	if c.strategy == ResolveRace {
+		return raceQuery(ctx, c, q)
+	}
+
+	var (
+		zero T
+		errs []error
+	)
+
+	for _, i := range c.order() {
+		attemptCtx, cancel := withTimeout(ctx, c.attempt)
+		res, err := q(attemptCtx, c.upstreams[i].Resolver)
+		cancel()
+
+		c.report(ctx, i, err)
+
+		// negative answer (NXDOMAIN etc.) is answer too, other upstreams shouldn't know better
+		if err == nil || !upstreamFailed(err) {
+			return res, err
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", c.upstreams[i].Name, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return zero, errors.Join(errs...)
+}
+
+func raceQuery[T any](ctx context.Context, c *CompositeResolve, q func(context.Context, models.Resolver) (T, error)) (T, error) {
+	type result struct {
+		i   int
+		res T
+		err error
+	}
+
+	var (
+		zero  T
+		errs  []error
+		order = c.order()
+		ch    = make(chan result, len(order))
+	)
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, i := range order {
+		go func() {
+			res, err := q(raceCtx, c.upstreams[i].Resolver)
+			ch <- result{i: i, res: res, err: err}
+		}()
+	}
+
+	for range order {
+		r := <-ch
+		c.report(ctx, r.i, r.err)
+
+		// negative answer wins like in compositeQuery: it's the fastest upstream answer
+		if r.err == nil || !upstreamFailed(r.err) {
+			return r.res, r.err
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", c.upstreams[r.i].Name, r.err))
+	}
+
+	return zero, errors.Join(errs...)
+}
+
+// Close - close upstreams that hold connections
+func (c *CompositeResolve) Close() error {
+	var errs []error
+	for _, up := range c.upstreams {
+		if closer, ok := up.Resolver.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *CompositeResolve) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
+	return compositeQuery(ctx, c, func(ctx context.Context, rv models.Resolver) ([]net.IP, error) {
+		return rv.ResolveIP(ctx, s)
+	})
+}
+
+func (c *CompositeResolve) ResolveNS(ctx context.Context, s string) ([]string, error) {
+	return compositeQuery(ctx, c, func(ctx context.Context, rv models.Resolver) ([]string, error) {
+		return rv.ResolveNS(ctx, s)
+	})
+}
+
+func (c *CompositeResolve) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return compositeQuery(ctx, c, func(ctx context.Context, rv models.Resolver) ([]string, error) {
+		return rv.ResolvePTR(ctx, ip)
+	})
+}
+
+func (c *CompositeResolve) ResolveRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	return compositeQuery(ctx, c, func(ctx context.Context, rv models.Resolver) ([]models.DnsRecord, error) {
+		return rv.ResolveRecords(ctx, s, rtype)
+	})
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	doh "github.com/eterline/micro-utils/pkg/DoH"
+	dns "github.com/miekg/dns"
+)
+
+// stubResolver - answers ResolveIP with fixed IP or error, counts queries
+type stubResolver struct {
+	err   error
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (s *stubResolver) ResolveIP(ctx context.Context, name string) ([]net.IP, error) {
+	s.calls.Add(1)
+
+	if s.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.delay):
+		}
+	}
+
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []net.IP{net.ParseIP("192.0.2.1")}, nil
+}
+
+func (s *stubResolver) ResolveNS(ctx context.Context, name string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubResolver) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubResolver) ResolveRecords(ctx context.Context, name, rtype string) ([]models.DnsRecord, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestUpstreamFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		failed bool
+	}{
+		{"go resolver not found", &net.DNSError{Err: "no such host", Name: "x.test", IsNotFound: true}, false},
+		{"go resolver timeout", &net.DNSError{Err: "i/o timeout", Name: "x.test", IsTimeout: true}, true},
+		{"nxdomain", nxdomainErr("x.test", "A", 0), false},
+		{"nodata", noRecordsErr("x.test", "A"), false},
+		{"servfail", &RcodeError{Name: "x.test", Type: "A", Rcode: dns.RcodeServerFailure}, true},
+		{"refused", &RcodeError{Name: "x.test", Type: "A", Rcode: dns.RcodeRefused}, true},
+		{"doh servfail", doh.SERVFAIL, true},
+		{"doh 503", &doh.HTTPStatusError{Code: 503, Status: "503 Service Unavailable"}, true},
+		{"doh 429", &doh.HTTPStatusError{Code: 429, Status: "429 Too Many Requests"}, true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"connection refused", &net.OpError{Op: "dial", Net: "udp", Err: errors.New("connection refused")}, true},
+	}
+
+	for _, tt := range tests {
+		if got := upstreamFailed(tt.err); got != tt.failed {
+			t.Errorf("%s: upstreamFailed = %v, want %v", tt.name, got, tt.failed)
+		}
+	}
+}
+
+func TestCompositeFallback(t *testing.T) {
+	var (
+		servfail = &stubResolver{err: &RcodeError{Name: "x.test", Type: "A", Rcode: dns.RcodeServerFailure}}
+		slow     = &stubResolver{delay: time.Second}
+		negative = &stubResolver{err: &net.DNSError{Err: "no such host", Name: "x.test", IsNotFound: true}}
+		good     = &stubResolver{}
+	)
+
+	c, err := NewCompositeResolver(ResolveFallback,
+		models.NamedResolver{Name: "servfail", Resolver: servfail},
+		models.NamedResolver{Name: "slow", Resolver: slow},
+		models.NamedResolver{Name: "negative", Resolver: negative},
+		models.NamedResolver{Name: "good", Resolver: good},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// slow upstream gets its share of lookup timeout only
+	c.SetLookupTimeout(400 * time.Millisecond)
+
+	start := time.Now()
+	_, err = c.ResolveIP(context.Background(), "x.test")
+
+	if models.ErrorCodeOf(err) != models.CodeNXDOMAIN {
+		t.Errorf("got %v, want NXDOMAIN of third upstream", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("lookup took %v, slow upstream isn't limited", elapsed)
+	}
+	if good.calls.Load() != 0 {
+		t.Error("negative answer fell back to next upstream")
+	}
+
+	health := c.Health()
+	if health[0].Fails != 1 || health[1].Fails != 1 || health[2].Fails != 0 {
+		t.Errorf("health %+v, want one failure of servfail and slow upstreams", health)
+	}
+}
+
+func TestCompositeRace(t *testing.T) {
+	var (
+		servfail = &stubResolver{err: &RcodeError{Name: "x.test", Type: "A", Rcode: dns.RcodeServerFailure}}
+		negative = &stubResolver{err: nxdomainErr("x.test", "A", 0), delay: 50 * time.Millisecond}
+		slow     = &stubResolver{delay: time.Second}
+	)
+
+	c, err := NewCompositeResolver(ResolveRace,
+		models.NamedResolver{Name: "servfail", Resolver: servfail},
+		models.NamedResolver{Name: "negative", Resolver: negative},
+		models.NamedResolver{Name: "slow", Resolver: slow},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// failure loses the race, negative answer wins it without waiting for slow upstream
+	start := time.Now()
+	_, err = c.ResolveIP(context.Background(), "x.test")
+
+	if models.ErrorCodeOf(err) != models.CodeNXDOMAIN {
+		t.Errorf("got %v, want NXDOMAIN", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("lookup took %v, negative answer waited for slow upstream", elapsed)
+	}
+
+	// only failures of all upstreams are joined
+	c, _ = NewCompositeResolver(ResolveRace,
+		models.NamedResolver{Name: "servfail", Resolver: servfail},
+		models.NamedResolver{Name: "timeout", Resolver: &stubResolver{err: context.DeadlineExceeded}},
+	)
+	_, err = c.ResolveIP(context.Background(), "x.test")
+	if err == nil || !strings.Contains(err.Error(), "servfail: ") || !strings.Contains(err.Error(), "timeout: ") {
+		t.Errorf("got %v, want errors of both upstreams", err)
+	}
+}
@@ -0,0 +1,801 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+)
+
+const (
+	// rootTrustAnchors - root zone KSK DS records of IANA root-anchors.xml: KSK-2017 and KSK-2024
+	rootTrustAnchors = `
+. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D
+. IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16
+`
+	// dnssecUDPSize - EDNS0 payload size of DNSSEC queries, larger answers are repeated over TCP
+	dnssecUDPSize = 1232
+	// dnssecMaxAliases - CNAME steps followed by validation, longer chains and loops are indeterminate
+	dnssecMaxAliases = 8
+)
+
+// dnssecAlgorithms - DNSKEY algorithms that can be verified, zones signed with others are insecure (RFC 4035 5.2)
+var dnssecAlgorithms = []uint8{
+	dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512,
+	dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519,
+}
+
+// dnssecDigests - supported DS digest types
+var dnssecDigests = []uint8{dns.SHA1, dns.SHA256, dns.SHA384}
+
+/*
+DnssecValidate - local DNSSEC validator over resolver transport
+
+	Queries are sent with DO and CD bits, so validating resolver returns bogus answers too and they are checked here.
+	Chain of trust is built from trust anchors (root KSK by default) over zone cuts of name:
+	DS of every zone is validated by parent keys, DNSKEY set - by key matching DS.
+	Unsigned delegation is insecure only if absence of DS is proven by signed NSEC/NSEC3 records.
+*/
+type DnssecValidate struct {
+	ex         dnsExchanger
+	anchorZone string
+	anchors    []*dns.DS
+}
+
+/*
+NewDnssecValidator - validator of resolver: DNS server, DoT, wire format or JSON DoH.
+
+	System resolver queries first /etc/resolv.conf nameserver directly.
+*/
+func NewDnssecValidator(rv models.Resolver) (*DnssecValidate, error) {
+	var ex dnsExchanger
+
+	switch r := UnwrapResolver(rv).(type) {
+	case *RemoteResolve:
+		ex = r.ex
+	case *TLSResolve:
+		ex = r.ex
+	case *DoHResolve:
+		if dex, ok := r.rs.(dnsExchanger); ok {
+			ex = dex
+		}
+	case LocalResolve:
+		addr, err := systemNameserver()
+		if err != nil {
+			return nil, fmt.Errorf("failed to init DNSSEC validator: %w", err)
+		}
+		ex = udpExchanger(addr)
+	}
+
+	if ex == nil {
+		return nil, errors.New("DNSSEC validation requires single DNS server, DoT, DoH or system resolver")
+	}
+
+	v := &DnssecValidate{ex: ex}
+	if err := v.setTrustAnchors(rootTrustAnchors); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+/*
+LoadTrustAnchors - replace root trust anchors by DS or DNSKEY records of zone file
+
+	All records must belong to single zone, only names of this zone can be validated then.
+	DNSKEY records are converted to SHA-256 DS.
+*/
+func (v *DnssecValidate) LoadTrustAnchors(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read trust anchors: %w", err)
+	}
+	return v.setTrustAnchors(string(data))
+}
+
+func (v *DnssecValidate) setTrustAnchors(zone string) error {
+	var (
+		anchors    []*dns.DS
+		anchorZone string
+		zp         = dns.NewZoneParser(strings.NewReader(zone), ".", "")
+	)
+
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		var ds *dns.DS
+
+		switch r := rr.(type) {
+		case *dns.DS:
+			ds = r
+		case *dns.DNSKEY:
+			ds = r.ToDS(dns.SHA256)
+		default:
+			continue
+		}
+
+		owner := dns.CanonicalName(rr.Header().Name)
+		if anchorZone != "" && owner != anchorZone {
+			return fmt.Errorf("trust anchors of several zones: %s and %s", anchorZone, owner)
+		}
+		anchorZone = owner
+		anchors = append(anchors, ds)
+	}
+
+	if err := zp.Err(); err != nil {
+		return fmt.Errorf("invalid trust anchors: %w", err)
+	}
+
+	if len(anchors) == 0 {
+		return errors.New("no DS or DNSKEY trust anchors")
+	}
+
+	v.anchorZone, v.anchors = anchorZone, anchors
+	return nil
+}
+
+// query - DNSSEC query with DO bit, CD bit disables validation of resolver
+func (v *DnssecValidate) query(ctx context.Context, name string, t uint16, cd bool) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, t)
+	msg.SetEdns0(dnssecUDPSize, true)
+	msg.CheckingDisabled = cd
+
+	res, err := v.ex.Exchange(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Rcode != dns.RcodeSuccess && res.Rcode != dns.RcodeNameError {
+		return nil, &RcodeError{Name: name, Type: dns.TypeToString[t], Rcode: res.Rcode}
+	}
+
+	// RFC 3225: DNSSEC aware server copies DO bit to answer. JSON DoH answers have no OPT record at all
+	if opt := res.IsEdns0(); opt != nil && !opt.Do() {
+		return nil, errors.New("resolver doesn't support DNSSEC: DO bit is cleared in answer")
+	}
+
+	return res, nil
+}
+
+// dnssecEnd - status with final state and reason
+func dnssecEnd(status models.DnssecStatus, state models.DnssecState, format string, args ...any) models.DnssecStatus {
+	status.State = state
+	status.Reason = fmt.Sprintf(format, args...)
+	return status
+}
+
+/*
+ValidateDNSSEC - validate A answer of name, CNAME chain of alias or denial of existence if there are no addresses
+
+	Alias chain is followed while it's secure: target of every signed CNAME is validated as name itself,
+	final state is state of first not secure step. Error is returned with indeterminate state only,
+	reason of other states is kept in status.
+*/
+func (v *DnssecValidate) ValidateDNSSEC(ctx context.Context, s string) (models.DnssecStatus, error) {
+	var (
+		name    = dns.CanonicalName(s)
+		status  = models.DnssecStatus{}
+		reasons []string
+		target  string
+		err     error
+	)
+
+	// opinion of resolver itself, SERVFAIL of validating resolver is bogus answer
+	if res, err := v.query(ctx, name, dns.TypeA, false); err == nil {
+		status.AD = res.AuthenticatedData
+	}
+
+	for step := 0; name != ""; step++ {
+		if step > dnssecMaxAliases {
+			err = fmt.Errorf("CNAME chain of %s is longer than %d steps", s, dnssecMaxAliases)
+			return dnssecEnd(status, models.DnssecIndeterminate, "%v", err), err
+		}
+
+		status, target, err = v.validateName(ctx, name, status)
+		if status.State != models.DnssecSecure {
+			if step > 0 {
+				status.Reason = fmt.Sprintf("CNAME target %s: %s", name, status.Reason)
+			}
+			return status, err
+		}
+
+		reasons = append(reasons, status.Reason)
+		name = target
+	}
+
+	status.Reason = strings.Join(reasons, ", ")
+	return status, nil
+}
+
+// validateName - one step of ValidateDNSSEC, returns CNAME target of secure alias
+func (v *DnssecValidate) validateName(ctx context.Context, name string, status models.DnssecStatus) (models.DnssecStatus, string, error) {
+	if !dns.IsSubDomain(v.anchorZone, name) {
+		return dnssecEnd(status, models.DnssecIndeterminate, "%s is out of trust anchor zone %s", name, v.anchorZone),
+			"", fmt.Errorf("no trust anchor for %s", name)
+	}
+
+	cuts, err := v.zoneCuts(ctx, name)
+	if err != nil {
+		return dnssecEnd(status, models.DnssecIndeterminate, "zone cuts lookup failed: %v", err), "", err
+	}
+
+	zone := v.anchorZone
+	keys, tags, err := v.zoneKeys(ctx, zone, v.anchors)
+	status = withZone(status, models.DnssecZone{Zone: zone, DS: dsTags(v.anchors), Keys: tags})
+	if err != nil {
+		status, err = v.failed(status, err, "trust anchor zone %s: %v", zone, err)
+		return status, "", err
+	}
+
+	for _, cut := range cuts {
+		res, err := v.query(ctx, cut, dns.TypeDS, true)
+		if err != nil {
+			return dnssecEnd(status, models.DnssecIndeterminate, "DS query of %s failed: %v", cut, err), "", err
+		}
+
+		set, sigs := rrsetOf(res.Answer, cut, dns.TypeDS)
+		if len(set) == 0 {
+			if err := verifyNoDS(res.Ns, cut, zone, keys); err != nil {
+				return dnssecEnd(status, models.DnssecBogus, "unsigned delegation %s: %v", cut, err), "", nil
+			}
+			return dnssecEnd(status, models.DnssecInsecure, "unsigned delegation: no DS of %s in zone %s", cut, zone), "", nil
+		}
+
+		if _, err := verifyRRset(set, sigs, zone, keys); err != nil {
+			return dnssecEnd(status, models.DnssecBogus, "DS of %s: %v", cut, err), "", nil
+		}
+
+		ds := supportedDS(set)
+		if len(ds) == 0 {
+			return dnssecEnd(status, models.DnssecInsecure, "DS of %s has unsupported algorithms only", cut), "", nil
+		}
+
+		zone = cut
+		keys, tags, err = v.zoneKeys(ctx, zone, ds)
+		status = withZone(status, models.DnssecZone{Zone: zone, DS: dsTags(ds), Keys: tags})
+		if err != nil {
+			status, err = v.failed(status, err, "zone %s: %v", zone, err)
+			return status, "", err
+		}
+	}
+
+	res, err := v.query(ctx, name, dns.TypeA, true)
+	if err != nil {
+		return dnssecEnd(status, models.DnssecIndeterminate, "A query failed: %v", err), "", err
+	}
+
+	for _, t := range []uint16{dns.TypeA, dns.TypeCNAME} {
+		set, sigs := rrsetOf(res.Answer, name, t)
+		if len(set) == 0 {
+			continue
+		}
+
+		sig, err := verifyRRset(set, sigs, zone, keys)
+		if err != nil {
+			return dnssecEnd(status, models.DnssecBogus, "%s of %s: %v", dns.TypeToString[t], name, err), "", nil
+		}
+
+		if int(sig.Labels) < dns.CountLabel(name) {
+			if err := verifyWildcard(res.Ns, name, int(sig.Labels), zone, keys); err != nil {
+				return dnssecEnd(status, models.DnssecBogus, "wildcard %s of %s: %v", dns.TypeToString[t], name, err), "", nil
+			}
+		}
+
+		status = dnssecEnd(status, models.DnssecSecure, "%s of %s is signed by zone %s", dns.TypeToString[t], name, zone)
+		if t == dns.TypeCNAME {
+			return status, dns.CanonicalName(set[0].(*dns.CNAME).Target), nil
+		}
+		return status, "", nil
+	}
+
+	if err := verifyDenial(res.Ns, name, dns.TypeA, res.Rcode == dns.RcodeNameError, zone, keys); err != nil {
+		return dnssecEnd(status, models.DnssecBogus, "denial of existence of %s: %v", name, err), "", nil
+	}
+	return dnssecEnd(status, models.DnssecSecure, "denial of existence of %s is proven by zone %s", name, zone), "", nil
+}
+
+// withZone - add zone to validated chain once, CNAME targets share zones of their aliases
+func withZone(status models.DnssecStatus, zone models.DnssecZone) models.DnssecStatus {
+	for _, z := range status.Chain {
+		if z.Zone == zone.Zone {
+			return status
+		}
+	}
+	status.Chain = append(status.Chain, zone)
+	return status
+}
+
+// failed - chain failure: indeterminate for resolver errors, bogus for broken keys
+func (v *DnssecValidate) failed(status models.DnssecStatus, err error, format string, args ...any) (models.DnssecStatus, error) {
+	var lerr *dnssecLookupError
+	if errors.As(err, &lerr) {
+		return dnssecEnd(status, models.DnssecIndeterminate, format, args...), lerr.err
+	}
+	return dnssecEnd(status, models.DnssecBogus, format, args...), nil
+}
+
+// dnssecLookupError - query failure of validation, not broken DNSSEC data
+type dnssecLookupError struct {
+	err error
+}
+
+func (e *dnssecLookupError) Error() string {
+	return e.err.Error()
+}
+
+/*
+zoneCuts - zones between trust anchor zone and name (name itself too if it's zone apex), top-down
+
+	Zone apex is found by SOA record with owner of queried name. Lookup stops at NXDOMAIN.
+*/
+func (v *DnssecValidate) zoneCuts(ctx context.Context, name string) ([]string, error) {
+	var (
+		labels = dns.SplitDomainName(name)
+		cuts   []string
+	)
+
+	for i := len(labels) - 1; i >= 0; i-- {
+		suffix := dns.Fqdn(strings.Join(labels[i:], "."))
+		if suffix == v.anchorZone || !dns.IsSubDomain(v.anchorZone, suffix) {
+			continue
+		}
+
+		res, err := v.query(ctx, suffix, dns.TypeSOA, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.Rcode == dns.RcodeNameError {
+			break
+		}
+
+		if set, _ := rrsetOf(res.Answer, suffix, dns.TypeSOA); len(set) > 0 {
+			cuts = append(cuts, suffix)
+		}
+	}
+
+	return cuts, nil
+}
+
+// zoneKeys - DNSKEY set of zone validated by key matching one of DS, returns key tags of whole set
+func (v *DnssecValidate) zoneKeys(ctx context.Context, zone string, ds []*dns.DS) ([]*dns.DNSKEY, []uint16, error) {
+	res, err := v.query(ctx, zone, dns.TypeDNSKEY, true)
+	if err != nil {
+		return nil, nil, &dnssecLookupError{err: fmt.Errorf("DNSKEY query failed: %w", err)}
+	}
+
+	set, sigs := rrsetOf(res.Answer, zone, dns.TypeDNSKEY)
+	if len(set) == 0 {
+		return nil, nil, errors.New("no DNSKEY records")
+	}
+
+	var (
+		keys  []*dns.DNSKEY
+		tags  []uint16
+		entry []*dns.DNSKEY
+	)
+
+	for _, rr := range set {
+		key := rr.(*dns.DNSKEY)
+		keys = append(keys, key)
+		tags = append(tags, key.KeyTag())
+
+		if key.Flags&dns.ZONE == 0 {
+			continue
+		}
+
+		for _, d := range ds {
+			if d.KeyTag != key.KeyTag() || d.Algorithm != key.Algorithm {
+				continue
+			}
+			if kd := key.ToDS(d.DigestType); kd != nil && strings.EqualFold(kd.Digest, d.Digest) {
+				entry = append(entry, key)
+				break
+			}
+		}
+	}
+
+	if len(entry) == 0 {
+		return nil, tags, fmt.Errorf("no DNSKEY matches DS %v", dsTags(ds))
+	}
+
+	if _, err := verifyRRset(set, sigs, zone, entry); err != nil {
+		return nil, tags, fmt.Errorf("DNSKEY set: %w", err)
+	}
+
+	return keys, tags, nil
+}
+
+// verifyRRset - RRset must have valid signature of zone key made in its validity period, returns this signature
+func verifyRRset(set []dns.RR, sigs []*dns.RRSIG, zone string, keys []*dns.DNSKEY) (*dns.RRSIG, error) {
+	if len(sigs) == 0 {
+		return nil, errors.New("no RRSIG records")
+	}
+
+	var (
+		now     = time.Now()
+		lastErr error
+	)
+
+	for _, sig := range sigs {
+		if !strings.EqualFold(sig.SignerName, zone) {
+			lastErr = fmt.Errorf("RRSIG signer %s is not zone %s", sig.SignerName, zone)
+			continue
+		}
+
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+				continue
+			}
+
+			if err := sig.Verify(key, set); err != nil {
+				lastErr = fmt.Errorf("RRSIG of key %d: %w", sig.KeyTag, err)
+				continue
+			}
+
+			if !sig.ValidityPeriod(now) {
+				lastErr = fmt.Errorf("RRSIG of key %d is expired or not yet valid: %s - %s", sig.KeyTag,
+					dns.TimeToString(sig.Inception), dns.TimeToString(sig.Expiration))
+				continue
+			}
+
+			return sig, nil
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no DNSKEY of RRSIG key tags %v", sigTags(sigs))
+	}
+	return nil, lastErr
+}
+
+/*
+verifyNoDS - authority section of DS NODATA answer must prove there is no DS of cut (RFC 4035 5.2)
+
+	NSEC of cut without DS type, NSEC3 matching cut without DS type or covering it with opt-out flag.
+*/
+func verifyNoDS(authority []dns.RR, cut, zone string, keys []*dns.DNSKEY) error {
+	nsec, nsec3, err := denialRecords(authority, zone, keys)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range nsec {
+		if dns.CanonicalName(r.Hdr.Name) == cut && !slices.Contains(r.TypeBitMap, dns.TypeDS) {
+			return nil
+		}
+	}
+
+	for _, r := range nsec3 {
+		if r.Match(cut) && !slices.Contains(r.TypeBitMap, dns.TypeDS) {
+			return nil
+		}
+		if nsec3Covers(r, cut) && r.Flags&1 == 1 {
+			return nil
+		}
+	}
+
+	return errors.New("absence of DS is not proven by NSEC/NSEC3")
+}
+
+/*
+verifyDenial - NSEC/NSEC3 records of negative answer must be signed by zone and prove it
+
+	NSEC (RFC 4035 3.1.3): NODATA - NSEC of name without queried type and CNAME, NSEC covering empty non-terminal name
+	or NSEC covering name with NSEC of wildcard of closest encloser without these types;
+	NXDOMAIN - NSEC covering name and NSEC covering wildcard of closest encloser.
+	NSEC3 (RFC 5155 8.4-8.7): NODATA - NSEC3 matching name without queried type and CNAME or closest encloser proof
+	with NSEC3 matching its wildcard without these types; NXDOMAIN - closest encloser proof and NSEC3 covering its wildcard.
+*/
+func verifyDenial(authority []dns.RR, name string, qtype uint16, nxdomain bool, zone string, keys []*dns.DNSKEY) error {
+	nsec, nsec3, err := denialRecords(authority, zone, keys)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(nsec) > 0:
+		return nsecDenial(nsec, name, qtype, nxdomain)
+	case len(nsec3) > 0:
+		return nsec3Denial(nsec3, name, qtype, nxdomain, zone)
+	}
+	return errors.New("no NSEC/NSEC3 records")
+}
+
+func nsecDenial(nsec []*dns.NSEC, name string, qtype uint16, nxdomain bool) error {
+	if r := nsecMatch(nsec, name); r != nil {
+		if nxdomain {
+			return fmt.Errorf("NSEC of %s proves it exists", name)
+		}
+		return noTypes(r.TypeBitMap, name, qtype)
+	}
+
+	cover := nsecCover(nsec, name)
+	if cover == nil {
+		return fmt.Errorf("no NSEC covers %s", name)
+	}
+
+	if dns.IsSubDomain(name, cover.NextDomain) {
+		if nxdomain {
+			return fmt.Errorf("NSEC %s proves %s is empty non-terminal", cover.Hdr.Name, name)
+		}
+		return nil
+	}
+
+	// closest encloser is the longest ancestor of name shared with either end of covering NSEC
+	labels := max(dns.CompareDomainName(name, cover.Hdr.Name), dns.CompareDomainName(name, cover.NextDomain))
+	wildcard := wildcardOf(ancestorOf(name, labels))
+
+	if nxdomain {
+		if nsecCover(nsec, wildcard) == nil {
+			return fmt.Errorf("no NSEC covers wildcard %s", wildcard)
+		}
+		return nil
+	}
+
+	r := nsecMatch(nsec, wildcard)
+	if r == nil {
+		return fmt.Errorf("no NSEC of %s or wildcard %s", name, wildcard)
+	}
+	return noTypes(r.TypeBitMap, wildcard, qtype)
+}
+
+func nsec3Denial(nsec3 []*dns.NSEC3, name string, qtype uint16, nxdomain bool, zone string) error {
+	if r := nsec3Match(nsec3, name); r != nil {
+		if nxdomain {
+			return fmt.Errorf("NSEC3 %s proves %s exists", r.Hdr.Name, name)
+		}
+		return noTypes(r.TypeBitMap, name, qtype)
+	}
+
+	encloser, err := nsec3Encloser(nsec3, name, zone)
+	if err != nil {
+		return err
+	}
+	wildcard := wildcardOf(encloser)
+
+	if nxdomain {
+		if nsec3Cover(nsec3, wildcard) == nil {
+			return fmt.Errorf("no NSEC3 covers wildcard %s", wildcard)
+		}
+		return nil
+	}
+
+	r := nsec3Match(nsec3, wildcard)
+	if r == nil {
+		return fmt.Errorf("no NSEC3 matches %s or wildcard %s", name, wildcard)
+	}
+	return noTypes(r.TypeBitMap, wildcard, qtype)
+}
+
+/*
+nsec3Encloser - closest encloser proof (RFC 5155 8.3)
+
+	The longest existing ancestor of name is matched by NSEC3 and next closer name (one label longer) is covered by NSEC3.
+	Delegation point or DNAME owner can't be closest encloser.
+*/
+func nsec3Encloser(nsec3 []*dns.NSEC3, name, zone string) (string, error) {
+	count := dns.CountLabel(name)
+
+	for labels := count - 1; labels >= 0; labels-- {
+		encloser := ancestorOf(name, labels)
+		if !dns.IsSubDomain(zone, encloser) {
+			break
+		}
+
+		r := nsec3Match(nsec3, encloser)
+		if r == nil {
+			continue
+		}
+
+		bitmap := r.TypeBitMap
+		if slices.Contains(bitmap, dns.TypeDNAME) || slices.Contains(bitmap, dns.TypeNS) && !slices.Contains(bitmap, dns.TypeSOA) {
+			return "", fmt.Errorf("closest encloser %s is delegation or DNAME", encloser)
+		}
+
+		next := ancestorOf(name, labels+1)
+		if nsec3Cover(nsec3, next) == nil {
+			return "", fmt.Errorf("no NSEC3 covers next closer name %s", next)
+		}
+		return encloser, nil
+	}
+
+	return "", fmt.Errorf("closest encloser of %s is not proven by NSEC3", name)
+}
+
+/*
+verifyWildcard - answer expanded from wildcard (RRSIG labels are less than owner labels) must prove there is no closer match
+
+	NSEC covering name (RFC 4035 5.3.4) or NSEC3 covering next closer name of wildcard (RFC 5155 8.8).
+*/
+func verifyWildcard(authority []dns.RR, name string, labels int, zone string, keys []*dns.DNSKEY) error {
+	nsec, nsec3, err := denialRecords(authority, zone, keys)
+	if err != nil {
+		return err
+	}
+
+	if nsecCover(nsec, name) != nil || nsec3Cover(nsec3, ancestorOf(name, labels+1)) != nil {
+		return nil
+	}
+	return fmt.Errorf("no NSEC/NSEC3 proves %s has no closer match", name)
+}
+
+// denialRecords - NSEC and NSEC3 records of authority section, every RRset must be signed by zone
+func denialRecords(authority []dns.RR, zone string, keys []*dns.DNSKEY) ([]*dns.NSEC, []*dns.NSEC3, error) {
+	var (
+		nsec  []*dns.NSEC
+		nsec3 []*dns.NSEC3
+	)
+
+	for _, t := range []uint16{dns.TypeNSEC, dns.TypeNSEC3} {
+		for _, owner := range rrOwners(authority, t) {
+			set, sigs := rrsetOf(authority, owner, t)
+			if _, err := verifyRRset(set, sigs, zone, keys); err != nil {
+				return nil, nil, fmt.Errorf("%s %s: %w", dns.TypeToString[t], owner, err)
+			}
+
+			for _, rr := range set {
+				switch r := rr.(type) {
+				case *dns.NSEC:
+					nsec = append(nsec, r)
+				case *dns.NSEC3:
+					nsec3 = append(nsec3, r)
+				}
+			}
+		}
+	}
+
+	return nsec, nsec3, nil
+}
+
+func nsecMatch(nsec []*dns.NSEC, name string) *dns.NSEC {
+	for _, r := range nsec {
+		if canonicalCompare(r.Hdr.Name, name) == 0 {
+			return r
+		}
+	}
+	return nil
+}
+
+// nsecCover - NSEC with name strictly between owner and next name, the last NSEC of zone points back to apex
+func nsecCover(nsec []*dns.NSEC, name string) *dns.NSEC {
+	for _, r := range nsec {
+		if canonicalCompare(r.Hdr.Name, name) >= 0 {
+			continue
+		}
+		if canonicalCompare(r.NextDomain, r.Hdr.Name) <= 0 || canonicalCompare(name, r.NextDomain) < 0 {
+			return r
+		}
+	}
+	return nil
+}
+
+func nsec3Match(nsec3 []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, r := range nsec3 {
+		if r.Match(name) {
+			return r
+		}
+	}
+	return nil
+}
+
+func nsec3Cover(nsec3 []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, r := range nsec3 {
+		if nsec3Covers(r, name) {
+			return r
+		}
+	}
+	return nil
+}
+
+// nsec3Covers - NSEC3 covers hash of name strictly, Cover of miekg/dns is true for matching owner hash too
+func nsec3Covers(r *dns.NSEC3, name string) bool {
+	return !r.Match(name) && r.Cover(name)
+}
+
+// noTypes - type bitmap of NODATA proof must have neither queried type nor CNAME
+func noTypes(bitmap []uint16, owner string, qtype uint16) error {
+	for _, t := range []uint16{qtype, dns.TypeCNAME} {
+		if slices.Contains(bitmap, t) {
+			return fmt.Errorf("type bitmap of %s has %s", owner, dns.TypeToString[t])
+		}
+	}
+	return nil
+}
+
+// ancestorOf - the last labels of name
+func ancestorOf(name string, labels int) string {
+	parts := dns.SplitDomainName(name)
+	return dns.Fqdn(strings.Join(parts[len(parts)-labels:], "."))
+}
+
+// wildcardOf - wildcard name of closest encloser
+func wildcardOf(encloser string) string {
+	return dns.Fqdn("*." + strings.TrimSuffix(encloser, "."))
+}
+
+// canonicalCompare - canonical order of names (RFC 4034 6.1): labels are compared from the right, case insensitive
+func canonicalCompare(a, b string) int {
+	la := dns.SplitDomainName(strings.ToLower(a))
+	lb := dns.SplitDomainName(strings.ToLower(b))
+
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(la), len(lb))
+}
+
+// rrsetOf - records of owner name and type with their RRSIG records
+func rrsetOf(rrs []dns.RR, owner string, t uint16) ([]dns.RR, []*dns.RRSIG) {
+	var (
+		set  []dns.RR
+		sigs []*dns.RRSIG
+	)
+
+	for _, rr := range rrs {
+		if !strings.EqualFold(rr.Header().Name, owner) {
+			continue
+		}
+
+		switch {
+		case rr.Header().Rrtype == t:
+			set = append(set, rr)
+		case rr.Header().Rrtype == dns.TypeRRSIG && rr.(*dns.RRSIG).TypeCovered == t:
+			sigs = append(sigs, rr.(*dns.RRSIG))
+		}
+	}
+
+	return set, sigs
+}
+
+// rrOwners - unique owner names of records of type
+func rrOwners(rrs []dns.RR, t uint16) []string {
+	var owners []string
+	for _, rr := range rrs {
+		owner := dns.CanonicalName(rr.Header().Name)
+		if rr.Header().Rrtype == t && !slices.Contains(owners, owner) {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+// supportedDS - DS records with supported key algorithm and digest type
+func supportedDS(set []dns.RR) []*dns.DS {
+	var ds []*dns.DS
+	for _, rr := range set {
+		d := rr.(*dns.DS)
+		if slices.Contains(dnssecAlgorithms, d.Algorithm) && slices.Contains(dnssecDigests, d.DigestType) {
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
+
+func dsTags(ds []*dns.DS) []uint16 {
+	tags := make([]uint16, len(ds))
+	for i, d := range ds {
+		tags[i] = d.KeyTag
+	}
+	return tags
+}
+
+func sigTags(sigs []*dns.RRSIG) []uint16 {
+	tags := make([]uint16, len(sigs))
+	for i, sig := range sigs {
+		tags[i] = sig.KeyTag
+	}
+	return tags
+}
@@ -0,0 +1,470 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+)
+
+// testZone - authoritative zone stand-in, signed by one ECDSA key with NSEC or NSEC3 chain, or unsigned
+type testZone struct {
+	origin  string
+	key     *dns.DNSKEY
+	nsec3   bool
+	owners  []string
+	records []dns.RR
+}
+
+func newTestZone(t *testing.T, origin string, signed, nsec3 bool, records ...string) *testZone {
+	t.Helper()
+
+	z := &testZone{origin: origin, nsec3: nsec3}
+	records = append([]string{
+		origin + " 3600 IN SOA ns." + origin + " hostmaster." + origin + " 1 7200 3600 86400 300",
+		origin + " 3600 IN NS ns." + origin,
+	}, records...)
+
+	for _, s := range records {
+		rr := mustRR(t, s)
+		z.records = append(z.records, rr)
+		if owner := dns.CanonicalName(rr.Header().Name); !slices.Contains(z.owners, owner) {
+			z.owners = append(z.owners, owner)
+		}
+	}
+
+	if !signed {
+		return z
+	}
+
+	z.key = &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := z.key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.records = append(z.records, z.key)
+
+	if nsec3 {
+		z.records = append(z.records, z.nsec3Chain()...)
+	} else {
+		z.records = append(z.records, z.nsecChain()...)
+	}
+
+	var (
+		now  = time.Now()
+		sigs []dns.RR
+		done []string
+	)
+
+	for _, rr := range z.records {
+		h := rr.Header()
+		id := dns.CanonicalName(h.Name) + " " + dns.TypeToString[h.Rrtype]
+		// NS of delegation is not signed by parent
+		if slices.Contains(done, id) || h.Rrtype == dns.TypeNS && dns.CanonicalName(h.Name) != origin {
+			continue
+		}
+		done = append(done, id)
+
+		set, _ := rrsetOf(z.records, h.Name, h.Rrtype)
+		sig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Ttl: h.Ttl},
+			KeyTag:     z.key.KeyTag(),
+			SignerName: origin,
+			Algorithm:  z.key.Algorithm,
+			Inception:  uint32(now.Add(-time.Hour).Unix()),
+			Expiration: uint32(now.Add(time.Hour).Unix()),
+		}
+		if err := sig.Sign(priv.(crypto.Signer), set); err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sig)
+	}
+
+	z.records = append(z.records, sigs...)
+	return z
+}
+
+// types - bitmap of owner name, empty for empty non-terminal
+func (z *testZone) types(owner string) []uint16 {
+	var types []uint16
+	for _, rr := range z.records {
+		if t := rr.Header().Rrtype; dns.CanonicalName(rr.Header().Name) == owner && !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+func (z *testZone) nsecChain() []dns.RR {
+	owners := slices.Clone(z.owners)
+	slices.SortFunc(owners, canonicalCompare)
+
+	var chain []dns.RR
+	for i, owner := range owners {
+		types := append(z.types(owner), dns.TypeRRSIG, dns.TypeNSEC)
+		slices.Sort(types)
+
+		chain = append(chain, &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: owners[(i+1)%len(owners)],
+			TypeBitMap: types,
+		})
+	}
+	return chain
+}
+
+func (z *testZone) nsec3Chain() []dns.RR {
+	var (
+		names  []string
+		hashes = map[string]string{}
+	)
+
+	// empty non-terminals have NSEC3 records too
+	for _, owner := range z.owners {
+		for labels := dns.CountLabel(owner); labels >= dns.CountLabel(z.origin); labels-- {
+			if name := ancestorOf(owner, labels); !slices.Contains(names, name) {
+				names = append(names, name)
+				hashes[name] = dns.HashName(name, dns.SHA1, 0, "ABCD")
+			}
+		}
+	}
+
+	slices.SortFunc(names, func(a, b string) int { return strings.Compare(hashes[a], hashes[b]) })
+
+	var chain []dns.RR
+	for i, name := range names {
+		types := z.types(name)
+		if len(types) > 0 {
+			types = append(types, dns.TypeRRSIG)
+		}
+		slices.Sort(types)
+
+		chain = append(chain, &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(hashes[name]) + "." + z.origin, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 300},
+			Hash:       dns.SHA1,
+			SaltLength: 2,
+			Salt:       "ABCD",
+			HashLength: 20,
+			NextDomain: hashes[names[(i+1)%len(names)]],
+			TypeBitMap: types,
+		})
+	}
+	return chain
+}
+
+// rrset - records of owner and type with their RRSIG records
+func (z *testZone) rrset(owner string, t uint16) []dns.RR {
+	set, sigs := rrsetOf(z.records, owner, t)
+	for _, sig := range sigs {
+		set = append(set, sig)
+	}
+	return set
+}
+
+// exists - name has records or is empty non-terminal
+func (z *testZone) exists(name string) bool {
+	return slices.ContainsFunc(z.owners, func(owner string) bool { return dns.IsSubDomain(name, owner) })
+}
+
+// matching - NSEC or NSEC3 of name
+func (z *testZone) matching(name string) []dns.RR {
+	for _, rr := range z.records {
+		switch r := rr.(type) {
+		case *dns.NSEC:
+			if dns.CanonicalName(r.Hdr.Name) == name {
+				return z.rrset(r.Hdr.Name, dns.TypeNSEC)
+			}
+		case *dns.NSEC3:
+			if r.Match(name) {
+				return z.rrset(r.Hdr.Name, dns.TypeNSEC3)
+			}
+		}
+	}
+	return nil
+}
+
+// covering - NSEC or NSEC3 covering name
+func (z *testZone) covering(name string) []dns.RR {
+	for _, rr := range z.records {
+		switch r := rr.(type) {
+		case *dns.NSEC:
+			if nsecCover([]*dns.NSEC{r}, name) != nil {
+				return z.rrset(r.Hdr.Name, dns.TypeNSEC)
+			}
+		case *dns.NSEC3:
+			if nsec3Covers(r, name) {
+				return z.rrset(r.Hdr.Name, dns.TypeNSEC3)
+			}
+		}
+	}
+	return nil
+}
+
+// noCloser - proof that name doesn't exist under its closest encloser
+func (z *testZone) noCloser(name, encloser string) []dns.RR {
+	if !z.nsec3 {
+		return z.covering(name)
+	}
+	return append(z.matching(encloser), z.covering(ancestorOf(name, dns.CountLabel(encloser)+1))...)
+}
+
+// answer - authoritative answer with DNSSEC records: data, CNAME, NODATA, wildcard expansion or NXDOMAIN
+func (z *testZone) answer(name string, qtype uint16, resp *dns.Msg) {
+	if set := z.rrset(name, qtype); len(set) > 0 {
+		resp.Answer = set
+		return
+	}
+	if set := z.rrset(name, dns.TypeCNAME); len(set) > 0 {
+		resp.Answer = set
+		return
+	}
+
+	resp.Ns = z.rrset(z.origin, dns.TypeSOA)
+	if z.exists(name) {
+		if z.key != nil {
+			if proof := z.matching(name); len(proof) > 0 {
+				resp.Ns = append(resp.Ns, proof...)
+			} else {
+				resp.Ns = append(resp.Ns, z.covering(name)...)
+			}
+		}
+		return
+	}
+
+	encloser := name
+	for !z.exists(encloser) {
+		encloser = ancestorOf(encloser, dns.CountLabel(encloser)-1)
+	}
+	wildcard := wildcardOf(encloser)
+
+	if set := z.rrset(wildcard, qtype); len(set) > 0 {
+		for _, rr := range set {
+			rr = dns.Copy(rr)
+			rr.Header().Name = name
+			resp.Answer = append(resp.Answer, rr)
+		}
+		resp.Ns = nil
+		if z.key != nil {
+			resp.Ns = z.noCloser(name, encloser)
+		}
+		return
+	}
+
+	resp.Rcode = dns.RcodeNameError
+	if z.key != nil {
+		for _, rr := range append(z.noCloser(name, encloser), z.covering(wildcard)...) {
+			if !slices.ContainsFunc(resp.Ns, func(x dns.RR) bool { return x.String() == rr.String() }) {
+				resp.Ns = append(resp.Ns, rr)
+			}
+		}
+	}
+}
+
+// forgery - tampered answer of A query of name
+type forgery func(z *testZone, name string, resp *dns.Msg)
+
+// serveZones - DNS server stand-in authoritative for all zones, DS queries of zone apex go to parent zone
+func serveZones(zones []*testZone, forged map[string]forgery) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(r)
+		resp.Authoritative = true
+
+		var (
+			q    = r.Question[0]
+			name = dns.CanonicalName(q.Name)
+			zone *testZone
+		)
+
+		for _, z := range zones {
+			if !dns.IsSubDomain(z.origin, name) || q.Qtype == dns.TypeDS && z.origin == name {
+				continue
+			}
+			if zone == nil || dns.CountLabel(z.origin) > dns.CountLabel(zone.origin) {
+				zone = z
+			}
+		}
+
+		switch f, ok := forged[name]; {
+		case zone == nil:
+			resp.Rcode = dns.RcodeRefused
+		case ok && q.Qtype == dns.TypeA:
+			f(zone, name, resp)
+		default:
+			zone.answer(name, q.Qtype, resp)
+		}
+
+		if opt := r.IsEdns0(); opt != nil {
+			resp.SetEdns0(opt.UDPSize(), opt.Do())
+		}
+		w.WriteMsg(resp)
+	}
+}
+
+func TestValidateDNSSEC(t *testing.T) {
+	var (
+		unsigned = newTestZone(t, "unsigned.test.", false, false,
+			"host.unsigned.test. 300 IN A 192.0.2.20",
+		)
+		hashed = newTestZone(t, "n3.test.", true, true,
+			"www.n3.test. 300 IN A 192.0.2.10",
+			"txt.n3.test. 300 IN TXT \"no address\"",
+			"*.wild.n3.test. 300 IN A 192.0.2.11",
+		)
+		parent = newTestZone(t, "test.", true, false,
+			"www.test. 300 IN A 192.0.2.1",
+			"bad.test. 300 IN A 192.0.2.2",
+			"mail.test. 300 IN MX 10 www.test.",
+			"*.wild.test. 300 IN A 192.0.2.3",
+			"alias.test. 300 IN CNAME www.n3.test.",
+			"alias-bad.test. 300 IN CNAME bad.test.",
+			"n3.test. 300 IN NS ns.n3.test.",
+			hashed.key.ToDS(dns.SHA256).String(),
+			"unsigned.test. 300 IN NS ns.unsigned.test.",
+		)
+		zones = []*testZone{parent, hashed, unsigned}
+	)
+
+	// signature of bad.test. doesn't match its address anymore
+	for _, rr := range parent.records {
+		if a, ok := rr.(*dns.A); ok && a.Hdr.Name == "bad.test." {
+			a.A = net.ParseIP("192.0.2.66")
+		}
+	}
+
+	var (
+		replay = func(other string) forgery {
+			return func(z *testZone, name string, resp *dns.Msg) {
+				z.answer(other, dns.TypeA, resp)
+			}
+		}
+		nodata = func(z *testZone, name string, resp *dns.Msg) {
+			resp.Ns = append(z.rrset(z.origin, dns.TypeSOA), z.matching(name)...)
+		}
+		bare = func(z *testZone, name string, resp *dns.Msg) {
+			z.answer(name, dns.TypeA, resp)
+			resp.Ns = nil
+		}
+	)
+
+	tests := []struct {
+		name   string
+		forged map[string]forgery
+		state  models.DnssecState
+		reason string
+		chain  []string
+	}{
+		{name: "www.test", state: models.DnssecSecure, reason: "A of www.test. is signed by zone test.", chain: []string{"test."}},
+		{name: "alias.test", state: models.DnssecSecure, reason: "A of www.n3.test. is signed by zone n3.test.", chain: []string{"test.", "n3.test."}},
+		{name: "mail.test", state: models.DnssecSecure, reason: "denial of existence of mail.test."},
+		{name: "nx.test", state: models.DnssecSecure, reason: "denial of existence of nx.test."},
+		{name: "a.wild.test", state: models.DnssecSecure, reason: "A of a.wild.test. is signed"},
+		{name: "nx.n3.test", state: models.DnssecSecure, reason: "denial of existence of nx.n3.test."},
+		{name: "txt.n3.test", state: models.DnssecSecure, reason: "denial of existence of txt.n3.test."},
+		{name: "a.wild.n3.test", state: models.DnssecSecure, reason: "A of a.wild.n3.test. is signed"},
+		{name: "host.unsigned.test", state: models.DnssecInsecure, reason: "no DS of unsigned.test."},
+		{name: "bad.test", state: models.DnssecBogus, reason: "A of bad.test.: RRSIG"},
+		{name: "alias-bad.test", state: models.DnssecBogus, reason: "CNAME target bad.test.: A of bad.test."},
+		{
+			name: "www.test", forged: map[string]forgery{"www.test.": replay("nx.test.")},
+			state: models.DnssecBogus, reason: "no NSEC covers www.test.",
+		},
+		{
+			name: "www.test", forged: map[string]forgery{"www.test.": nodata},
+			state: models.DnssecBogus, reason: "type bitmap of www.test. has A",
+		},
+		{
+			name: "www.n3.test", forged: map[string]forgery{"www.n3.test.": nodata},
+			state: models.DnssecBogus, reason: "type bitmap of www.n3.test. has A",
+		},
+		{
+			name: "txt.n3.test", forged: map[string]forgery{"txt.n3.test.": replay("nx.n3.test.")},
+			state: models.DnssecBogus, reason: "no NSEC3 covers next closer name txt.n3.test.",
+		},
+		{
+			name: "a.wild.test", forged: map[string]forgery{"a.wild.test.": bare},
+			state: models.DnssecBogus, reason: "has no closer match",
+		},
+		{
+			name: "a.wild.n3.test", forged: map[string]forgery{"a.wild.n3.test.": bare},
+			state: models.DnssecBogus, reason: "has no closer match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := startDNS(t, map[string]dns.HandlerFunc{"127.0.0.1": serveZones(zones, tt.forged)})
+
+			rs, err := NewRemoteResolver("127.0.0.1:" + port)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := NewDnssecValidator(rs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := v.setTrustAnchors(parent.key.String()); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			status, err := v.ValidateDNSSEC(ctx, tt.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v (%s)", err, status.Reason)
+			}
+			if status.State != tt.state || !strings.Contains(status.Reason, tt.reason) {
+				t.Fatalf("got %s %q, want %s with %q", status.State, status.Reason, tt.state, tt.reason)
+
+			}
+
+			if tt.chain != nil {
+				var zones []string
+				for _, z := range status.Chain {
+					zones = append(zones, z.Zone)
+				}
+				if !slices.Equal(zones, tt.chain) {
+					t.Fatalf("chain %v, want %v", zones, tt.chain)
+				}
+			}
+		})
+	}
+}
+
+func TestDnssecAliasLimit(t *testing.T) {
+	zone := newTestZone(t, "test.", true, false,
+		"a.test. 300 IN CNAME b.test.",
+		"b.test. 300 IN CNAME a.test.",
+	)
+	port := startDNS(t, map[string]dns.HandlerFunc{"127.0.0.1": serveZones([]*testZone{zone}, nil)})
+
+	rs, _ := NewRemoteResolver("127.0.0.1:" + port)
+	v, err := NewDnssecValidator(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.setTrustAnchors(zone.key.String()); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := v.ValidateDNSSEC(context.Background(), "a.test")
+	if err == nil || status.State != models.DnssecIndeterminate {
+		t.Fatalf("got %s %q, %v, want indeterminate CNAME loop", status.State, status.Reason, err)
+	}
+}
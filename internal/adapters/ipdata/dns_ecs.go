@@ -0,0 +1,163 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eterline/micro-utils/internal/models"
+	doh "github.com/eterline/micro-utils/pkg/DoH"
+	dns "github.com/miekg/dns"
+)
+
+const (
+	// ecsIPv4Bits - source prefix length of single IPv4 client address (RFC 7871 11.1)
+	ecsIPv4Bits = 24
+	// ecsIPv6Bits - source prefix length of single IPv6 client address (RFC 7871 11.1)
+	ecsIPv6Bits = 56
+)
+
+/*
+ParseClientSubnet - client subnet of EDNS Client Subnet option
+
+	"203.0.113.0/24", "2001:db8::/48" - host bits are dropped.
+	Single address is cut to recommended prefix: "203.0.113.7" -> "203.0.113.0/24", IPv6 address to /56.
+*/
+func ParseClientSubnet(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid client subnet: %s", s)
+		}
+
+		bits := ecsIPv6Bits
+		if addr.Unmap().Is4() {
+			addr, bits = addr.Unmap(), ecsIPv4Bits
+		}
+		return addr.Prefix(bits)
+	}
+
+	subnet, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid client subnet: %s", s)
+	}
+	return subnet.Masked(), nil
+}
+
+// subnetRecordsFunc - records query with client subnet. Scope of answer is nil if resolver ignored client subnet
+type subnetRecordsFunc func(ctx context.Context, s, rtype string, subnet netip.Prefix) ([]models.DnsRecord, *uint8, error)
+
+// resolveIPSubnet - A and AAAA records for client subnet, scope is the longest one of both answers
+func resolveIPSubnet(ctx context.Context, s string, subnet netip.Prefix, records subnetRecordsFunc) (models.SubnetAnswer, error) {
+	var (
+		ans models.SubnetAnswer
+		mu  sync.Mutex
+	)
+
+	ips, err := resolveIPRecords(ctx, s, func(ctx context.Context, s, rtype string) ([]models.DnsRecord, error) {
+		recs, scope, err := records(ctx, s, rtype, subnet)
+
+		if scope != nil {
+			mu.Lock()
+			if ans.Scope == nil || *scope > *ans.Scope {
+				ans.Scope = scope
+			}
+			mu.Unlock()
+		}
+
+		return recs, err
+	})
+
+	ans.IPs = ips
+	return ans, err
+}
+
+// exchangeSubnetRecords - exchangeRecords with client subnet option in query
+func exchangeSubnetRecords(ctx context.Context, ex dnsExchanger, s, rtype string, subnet netip.Prefix) ([]models.DnsRecord, *uint8, error) {
+	t, err := parseRecordType(rtype)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(s), t)
+	msg.SetEdns0(1232, false)
+
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option, doh.ClientSubnetOption(subnet))
+
+	res, err := ex.Exchange(ctx, msg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var scope *uint8
+	if ecs := doh.ClientSubnetOf(res); ecs != nil {
+		scope = &ecs.SourceScope
+	}
+
+	records, err := responseRecords(res, s, rtype, t)
+	return records, scope, err
+}
+
+// ResolveIPSubnet - IPs of name for clients of subnet. Works for DoT resolver too
+func (rs *RemoteResolve) ResolveIPSubnet(ctx context.Context, s string, subnet netip.Prefix) (models.SubnetAnswer, error) {
+	return resolveIPSubnet(ctx, s, subnet, func(ctx context.Context, s, rtype string, subnet netip.Prefix) ([]models.DnsRecord, *uint8, error) {
+		return exchangeSubnetRecords(ctx, rs.ex, s, rtype, subnet)
+	})
+}
+
+// =======================================
+
+// subnetQuerier - DoH service with client subnet queries
+type subnetQuerier interface {
+	QuerySubnet(ctx context.Context, d doh.Domain, t doh.Record, subnet netip.Prefix) (doh.DnsResponse, error)
+}
+
+/*
+ResolveIPSubnet - IPs of name for clients of subnet
+
+	Wire format DoH sends EDNS option, JSON API - edns_client_subnet parameter (supported by Google, ignored by Cloudflare).
+*/
+func (rs *DoHResolve) ResolveIPSubnet(ctx context.Context, s string, subnet netip.Prefix) (models.SubnetAnswer, error) {
+	sq, ok := rs.rs.(subnetQuerier)
+	if !ok {
+		return models.SubnetAnswer{}, fmt.Errorf("DoH service %s doesn't support client subnet", rs.rs.Service())
+	}
+
+	return resolveIPSubnet(ctx, s, subnet, func(ctx context.Context, s, rtype string, subnet netip.Prefix) ([]models.DnsRecord, *uint8, error) {
+		t, err := parseRecordType(rtype)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		res, err := sq.QuerySubnet(ctx, doh.Domain(s), doh.Record(dns.TypeToString[t]), subnet)
+		records, err := dohRecords(res, err, s, rtype, t)
+		return records, dohSubnetScope(res.ClientSubnet), err
+	})
+}
+
+// dohSubnetScope - scope prefix length of DoH response client subnet "address/scope", nil if it's empty or broken
+func dohSubnetScope(ecs string) *uint8 {
+	i := strings.LastIndexByte(ecs, '/')
+	if i < 0 {
+		return nil
+	}
+
+	scope, err := strconv.ParseUint(ecs[i+1:], 10, 8)
+	if err != nil {
+		return nil
+	}
+
+	s := uint8(scope)
+	return &s
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"testing"
+
+	doh "github.com/eterline/micro-utils/pkg/DoH"
+	dns "github.com/miekg/dns"
+)
+
+func TestParseClientSubnet(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"203.0.113.0/24", "203.0.113.0/24"},
+		{" 203.0.113.77/20 ", "203.0.112.0/20"},
+		{"203.0.113.7", "203.0.113.0/24"},
+		{"::ffff:203.0.113.7", "203.0.113.0/24"},
+		{"2001:db8:1:2:3::1", "2001:db8:1::/56"},
+		{"2001:db8:ffff::/48", "2001:db8:ffff::/48"},
+		{"0.0.0.0/0", "0.0.0.0/0"},
+
+		{"", ""},
+		{"203.0.113", ""},
+		{"203.0.113.0/33", ""},
+		{"example.test", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseClientSubnet(tt.in)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%q: got %s, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%q: got %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestClientSubnetOptionWire(t *testing.T) {
+	tests := []struct {
+		subnet string
+		// option in wire format (RFC 7871 6): code, length, family, source prefix, scope prefix, address bytes of prefix only
+		want []byte
+	}{
+		{"203.0.113.0/24", []byte{0, 8, 0, 7, 0, 1, 24, 0, 203, 0, 113}},
+		{"203.0.113.77/20", []byte{0, 8, 0, 7, 0, 1, 20, 0, 203, 0, 112}},
+		{"198.51.100.1/32", []byte{0, 8, 0, 8, 0, 1, 32, 0, 198, 51, 100, 1}},
+		{"0.0.0.0/0", []byte{0, 8, 0, 4, 0, 1, 0, 0}},
+		{"2001:db8:ff00::/40", []byte{0, 8, 0, 9, 0, 2, 40, 0, 0x20, 0x01, 0x0d, 0xb8, 0xff}},
+	}
+
+	for _, tt := range tests {
+		msg := new(dns.Msg)
+		msg.SetQuestion("example.test.", dns.TypeA)
+		msg.SetEdns0(1232, false)
+		msg.IsEdns0().Option = append(msg.IsEdns0().Option, doh.ClientSubnetOption(netip.MustParsePrefix(tt.subnet)))
+
+		packed, err := msg.Pack()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.subnet, err)
+		}
+		// option is the last data of OPT record at the end of message
+		if !bytes.HasSuffix(packed, tt.want) {
+			t.Errorf("%s: message %x doesn't end with option %x", tt.subnet, packed, tt.want)
+		}
+
+		unpacked := new(dns.Msg)
+		if err := unpacked.Unpack(packed); err != nil {
+			t.Fatal(err)
+		}
+		ecs := doh.ClientSubnetOf(unpacked)
+		if ecs == nil || netip.PrefixFrom(netip.MustParseAddr(ecs.Address.String()).Unmap(), int(ecs.SourceNetmask)) != netip.MustParsePrefix(tt.subnet).Masked() {
+			t.Errorf("%s: got option %v back", tt.subnet, ecs)
+		}
+	}
+}
+
+// ecsServer - answers A and AAAA of example.test with ECS scopes 16 and 24, ignores client subnet of other names
+func ecsServer(t *testing.T, want netip.Prefix) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		q := r.Question[0]
+
+		ecs := doh.ClientSubnetOf(r)
+		if ecs == nil || int(ecs.SourceNetmask) != want.Bits() || ecs.SourceScope != 0 {
+			t.Errorf("%s: got client subnet %v, want %s", q.Name, ecs, want)
+		}
+
+		resp := new(dns.Msg)
+		resp.SetReply(r)
+
+		switch q.Qtype {
+		case dns.TypeA:
+			resp.Answer = append(resp.Answer, mustRR(t, q.Name+" 60 IN A 192.0.2.1"))
+		case dns.TypeAAAA:
+			resp.Answer = append(resp.Answer, mustRR(t, q.Name+" 60 IN AAAA 2001:db8::1"))
+		}
+
+		if q.Name == "example.test." && ecs != nil {
+			scope := *ecs
+			scope.SourceScope = 16
+			if q.Qtype == dns.TypeAAAA {
+				scope.SourceScope = 24
+			}
+			resp.SetEdns0(1232, false)
+			resp.IsEdns0().Option = append(resp.IsEdns0().Option, &scope)
+		}
+
+		w.WriteMsg(resp)
+	}
+}
+
+func TestResolveIPSubnet(t *testing.T) {
+	subnet := netip.MustParsePrefix("203.0.113.0/24")
+	port := startDNS(t, map[string]dns.HandlerFunc{"127.0.0.1": ecsServer(t, subnet)})
+
+	rs, err := NewRemoteResolver("127.0.0.1:" + port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ans, err := rs.ResolveIPSubnet(context.Background(), "example.test", subnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ans.IPs) != 2 {
+		t.Errorf("got %v, want A and AAAA addresses", ans.IPs)
+	}
+	// the longest scope of A and AAAA answers
+	if ans.Scope == nil || *ans.Scope != 24 {
+		t.Errorf("got %+v, want scope 24", ans)
+	}
+
+	ans, err = rs.ResolveIPSubnet(context.Background(), "other.test", subnet)
+	if err != nil || len(ans.IPs) != 2 || ans.Scope != nil {
+		t.Errorf("got %+v, %v, want answer without scope", ans, err)
+	}
+}
+
+func TestDohSubnetScope(t *testing.T) {
+	tests := []struct {
+		ecs  string
+		want int
+	}{
+		{"203.0.113.0/24", 24},
+		{"2001:db8::/0", 0},
+		{"", -1},
+		{"203.0.113.0", -1},
+		{"203.0.113.0/x", -1},
+		{"203.0.113.0/300", -1},
+	}
+
+	for _, tt := range tests {
+		got := dohSubnetScope(tt.ecs)
+		switch {
+		case tt.want < 0 && got != nil:
+			t.Errorf("%q: got scope %d, want none", tt.ecs, *got)
+		case tt.want >= 0 && (got == nil || int(*got) != tt.want):
+			t.Errorf("%q: got scope %v, want %d", tt.ecs, got, tt.want)
+		}
+	}
+}
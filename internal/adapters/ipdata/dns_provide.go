@@ -8,12 +8,22 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"sync"
 
 	doh "github.com/eterline/micro-utils/pkg/DoH"
 	dns "github.com/miekg/dns"
 )
 
+// resolveErrors - errors of several queries: "[A query failed: ... AAAA query failed: ...]"
+type resolveErrors []error
+
+func (e resolveErrors) Error() string {
+	return fmt.Sprint([]error(e))
+}
+
+func (e resolveErrors) Unwrap() []error {
+	return e
+}
+
 type DoHqueryService interface {
 	Query(ctx context.Context, d doh.Domain, t doh.Record) (doh.DnsResponse, error)
 	Service() string
@@ -34,61 +44,40 @@ func NewCloudflareResolver() *DoHResolve {
 // NewGoogleResolver - google DNS over HTTP/s
 func NewGoogleResolver() *DoHResolve {
 	return &DoHResolve{
-		rs: doh.InitDnsCloudflareProvider(),
+		rs: doh.InitDnsGoogleProvider(),
 	}
 }
 
-func (rs *DoHResolve) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
-
-	var ips []net.IP
-	var errs []error
-
-	resA, errA := rs.rs.Query(ctx, doh.Domain(s), doh.TypeA)
-	if errA == nil && resA.Status == doh.NOERROR {
-		for _, ans := range resA.Answer {
-			if ip := net.ParseIP(ans.Data); ip != nil {
-				ips = append(ips, ip)
-			}
-		}
-	} else if errA != nil {
-		errs = append(errs, fmt.Errorf("A query failed: %w", errA))
-	} else {
-		errs = append(errs, fmt.Errorf("A query returned status %v", resA.Status))
-	}
-
-	resAAAA, errAAAA := rs.rs.Query(ctx, doh.Domain(s), doh.TypeAAAA)
-	if errAAAA == nil && resAAAA.Status == doh.NOERROR {
-		for _, ans := range resAAAA.Answer {
-			if ip := net.ParseIP(ans.Data); ip != nil {
-				ips = append(ips, ip)
-			}
-		}
-	} else if errAAAA != nil {
-		errs = append(errs, fmt.Errorf("AAAA query failed: %w", errAAAA))
-	} else {
-		errs = append(errs, fmt.Errorf("AAAA query returned status %v", resAAAA.Status))
-	}
+// NewDoHResolver - RFC 8484 DNS over HTTP/s of any DoH URL. Method is GET (if empty) or POST
+func NewDoHResolver(endpoint, method string) (*DoHResolve, error) {
+	return NewDoHResolverWith(doh.ProviderConfig{
+		URL:    endpoint,
+		Method: method,
+	})
+}
 
-	if len(ips) > 0 {
-		return ips, nil
+// NewDoHResolverWith - DNS over HTTP/s with provider settings: bootstrap IPs, proxy, timeout, EDNS etc.
+func NewDoHResolverWith(cfg doh.ProviderConfig) (*DoHResolve, error) {
+	provider, err := doh.InitDnsProvider(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("no IP resolved for %s: %v", s, errs)
+	return &DoHResolve{
+		rs: provider,
+	}, nil
+}
 
+func (rs *DoHResolve) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
+	return resolveIPRecords(ctx, s, rs.ResolveRecords)
 }
 
 func (rs *DoHResolve) ResolveNS(ctx context.Context, s string) ([]string, error) {
-	res, err := rs.rs.Query(ctx, doh.Domain(s), doh.TypeNS)
-	if err != nil {
-		return nil, err
-	}
-
-	var nss []string
-	for _, ans := range res.Answer {
-		nss = append(nss, ans.Data)
-	}
+	return resolveNameRecords(ctx, s, "NS", rs.ResolveRecords)
+}
 
-	return nss, nil
+func (rs *DoHResolve) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return resolvePTRRecords(ctx, ip, rs.ResolveRecords)
 }
 
 // =======================================
@@ -111,7 +100,7 @@ func (rs LocalResolve) ResolveIP(ctx context.Context, s string) ([]net.IP, error
 
 	ips, err := r.LookupIP(ctx, "ip", s)
 	if err != nil {
-		return nil, err
+		return nil, confirmNotFound(ctx, err, s, "A")
 	}
 
 	return ips, nil
@@ -128,7 +117,7 @@ func (rs LocalResolve) ResolveNS(ctx context.Context, s string) ([]string, error
 
 	nss, err := r.LookupNS(ctx, s)
 	if err != nil {
-		return nil, err
+		return nil, confirmNotFound(ctx, err, s, "NS")
 	}
 
 	nssL := make([]string, len(nss))
@@ -139,9 +128,73 @@ func (rs LocalResolve) ResolveNS(ctx context.Context, s string) ([]string, error
 	return nssL, nil
 }
 
-// LocalResolve - use certain DNS server as IP resolve server.
+func (rs LocalResolve) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{}
+			return d.DialContext(ctx, network, address)
+		},
+	}
+
+	names, err := r.LookupAddr(ctx, ip.String())
+	if err != nil {
+		return nil, confirmNotFound(ctx, err, ip.String(), "PTR")
+	}
+
+	return names, nil
+}
+
+// dnsExchanger - DNS messages transport of remote resolver
+type dnsExchanger interface {
+	Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error)
+}
+
+// udpExchanger - plain DNS server address. Truncated answers are repeated over TCP
+type udpExchanger string
+
+func (e udpExchanger) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	res, err := dns.ExchangeContext(ctx, msg, string(e))
+	if err == nil && res.Truncated {
+		res, _, err = (&dns.Client{Net: "tcp"}).ExchangeContext(ctx, msg, string(e))
+	}
+	return res, exchangeError(err)
+}
+
+// EDNSOptions - EDNS0 OPT record settings of outgoing queries
+type EDNSOptions struct {
+	// UDPSize - advertised UDP payload size, 1232 if 0
+	UDPSize uint16
+	// DNSSEC - set DNSSEC OK bit
+	DNSSEC bool
+}
+
+// ednsExchanger - adds EDNS0 OPT record to every query
+type ednsExchanger struct {
+	next dnsExchanger
+	opt  EDNSOptions
+}
+
+func (e ednsExchanger) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	if msg.IsEdns0() == nil {
+		msg = msg.Copy()
+		msg.SetEdns0(e.opt.UDPSize, e.opt.DNSSEC)
+	}
+	return e.next.Exchange(ctx, msg)
+}
+
+// RemoteResolve - use certain DNS server as IP resolve server.
 type RemoteResolve struct {
 	dnsSocket string
+	ex        dnsExchanger
+}
+
+// SetEDNS - send EDNS0 OPT record with every query
+func (rs *RemoteResolve) SetEDNS(opt EDNSOptions) {
+	if opt.UDPSize == 0 {
+		opt.UDPSize = 1232
+	}
+	rs.ex = ednsExchanger{next: rs.ex, opt: opt}
 }
 
 func correctDNSsrv(socket string) string {
@@ -166,82 +219,18 @@ func NewRemoteResolver(socket string) (*RemoteResolve, error) {
 
 	return &RemoteResolve{
 		dnsSocket: correctDNSsrv(socket),
+		ex:        udpExchanger(correctDNSsrv(socket)),
 	}, nil
 }
 
 func (rs *RemoteResolve) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
-
-	var (
-		fqdn = dns.Fqdn(s)
-		ips  []net.IP
-		errs []error
-		mu   sync.Mutex
-		wg   sync.WaitGroup
-	)
-
-	query := func(qtype uint16) {
-		defer wg.Done()
-		msg := new(dns.Msg)
-		msg.SetQuestion(fqdn, qtype)
-
-		res, err := dns.ExchangeContext(ctx, msg, rs.dnsSocket)
-		if err != nil {
-			mu.Lock()
-			errs = append(errs, err)
-			mu.Unlock()
-			return
-		}
-
-		mu.Lock()
-		defer mu.Unlock()
-
-		for _, ans := range res.Answer {
-			switch rr := ans.(type) {
-			case *dns.A:
-				ips = append(ips, rr.A)
-			case *dns.AAAA:
-				ips = append(ips, rr.AAAA)
-			}
-		}
-	}
-
-	wg.Add(2)
-	go query(dns.TypeA)    // request for IPv4
-	go query(dns.TypeAAAA) // request for IPv6
-	wg.Wait()
-
-	if len(ips) > 0 {
-		return ips, nil
-	}
-
-	if len(errs) > 0 {
-		return nil, fmt.Errorf("no IP resolved for %s: %v", s, errs)
-	}
-
-	return nil, fmt.Errorf("no IP resolved for %s", s)
+	return resolveIPRecords(ctx, s, rs.ResolveRecords)
 }
 
 func (rs *RemoteResolve) ResolveNS(ctx context.Context, s string) ([]string, error) {
-	resMsg := dns.Msg{}
-	fqdn := dns.Fqdn(s)
-
-	resMsg.SetQuestion(fqdn, dns.TypeNS)
-
-	res, err := dns.ExchangeContext(ctx, &resMsg, rs.dnsSocket)
-	if err != nil {
-		return nil, err
-	}
-
-	var nss []string
-	for _, ans := range res.Answer {
-		if a, ok := ans.(*dns.NS); ok {
-			nss = append(nss, a.Ns)
-		}
-	}
-
-	if len(nss) > 0 {
-		return nss, nil
-	}
+	return resolveNameRecords(ctx, s, "NS", rs.ResolveRecords)
+}
 
-	return nil, fmt.Errorf("no NS resolved for %s", s)
+func (rs *RemoteResolve) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return resolvePTRRecords(ctx, ip, rs.ResolveRecords)
 }
@@ -0,0 +1,535 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/eterline/micro-utils/internal/models"
+	doh "github.com/eterline/micro-utils/pkg/DoH"
+	dns "github.com/miekg/dns"
+)
+
+const systemResolvConf = "/etc/resolv.conf"
+
+// parseRecordType - DNS type code by name: "mx" -> dns.TypeMX
+func parseRecordType(rtype string) (uint16, error) {
+	t, ok := dns.StringToType[strings.ToUpper(strings.TrimSpace(rtype))]
+	if !ok {
+		return 0, fmt.Errorf("unknown DNS record type: %s", rtype)
+	}
+	return t, nil
+}
+
+// CheckRecordType - error if DNS record type name is unknown
+func CheckRecordType(rtype string) error {
+	_, err := parseRecordType(rtype)
+	return err
+}
+
+// recordFromRR - typed record from miekg/dns resource record
+func recordFromRR(rr dns.RR) models.DnsRecord {
+	hdr := rr.Header()
+
+	rec := models.DnsRecord{
+		Name: hdr.Name,
+		Type: dns.TypeToString[hdr.Rrtype],
+		TTL:  hdr.Ttl,
+		Data: strings.TrimSpace(strings.TrimPrefix(rr.String(), hdr.String())),
+	}
+
+	switch v := rr.(type) {
+	case *dns.MX:
+		rec.MX = &models.MXRecord{Preference: v.Preference, Exchange: v.Mx}
+	case *dns.SOA:
+		rec.SOA = &models.SOARecord{
+			Ns: v.Ns, Mbox: v.Mbox, Serial: v.Serial,
+			Refresh: v.Refresh, Retry: v.Retry, Expire: v.Expire, MinTTL: v.Minttl,
+		}
+	case *dns.SRV:
+		rec.SRV = &models.SRVRecord{Priority: v.Priority, Weight: v.Weight, Port: v.Port, Target: v.Target}
+	case *dns.CAA:
+		rec.CAA = &models.CAARecord{Flag: v.Flag, Tag: v.Tag, Value: v.Value}
+	case *dns.TXT:
+		rec.TXT = v.Txt
+	case *dns.SPF:
+		rec.TXT = v.Txt
+	}
+
+	return rec
+}
+
+// recordsOfType - typed records of answer section with requested type only
+func recordsOfType(answer []dns.RR, t uint16) []models.DnsRecord {
+	var records []models.DnsRecord
+	for _, rr := range answer {
+		if rr.Header().Rrtype == t {
+			records = append(records, recordFromRR(rr))
+		}
+	}
+	return records
+}
+
+/*
+NegativeAnswer - NXDOMAIN or NODATA (NOERROR without records) answer
+
+	TTL is negative caching TTL (RFC 2308): min(SOA TTL, SOA minimum) of authority section, 0 if unknown.
+*/
+type NegativeAnswer struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Rcode string `json:"rcode"`
+	TTL   uint32 `json:"ttl"`
+}
+
+func (e *NegativeAnswer) Error() string {
+	if e.Rcode == dns.RcodeToString[dns.RcodeNameError] {
+		return fmt.Sprintf("%s query for %s returned status %s", e.Type, e.Name, e.Rcode)
+	}
+	return fmt.Sprintf("no %s records resolved for %s", e.Type, e.Name)
+}
+
+func (e *NegativeAnswer) ErrorCode() models.ErrorCode {
+	if e.Rcode == dns.RcodeToString[dns.RcodeNameError] {
+		return models.CodeNXDOMAIN
+	}
+	return models.CodeNODATA
+}
+
+// RcodeError - unsuccessful DNS response code other than NXDOMAIN: SERVFAIL, REFUSED etc.
+type RcodeError struct {
+	Name  string
+	Type  string
+	Rcode int
+}
+
+func (e *RcodeError) Error() string {
+	return fmt.Sprintf("%s query for %s returned status %s", e.Type, e.Name, dns.RcodeToString[e.Rcode])
+}
+
+func (e *RcodeError) ErrorCode() models.ErrorCode {
+	return models.RcodeErrorCode(e.Rcode)
+}
+
+// exchangeError - DNS message parsing errors are malformed response
+func exchangeError(err error) error {
+	var dnsErr *dns.Error
+	if errors.As(err, &dnsErr) {
+		return &models.LookupError{Code: models.CodeMalformed, Err: err}
+	}
+	return err
+}
+
+func noRecordsErr(s, rtype string) error {
+	return &NegativeAnswer{Name: s, Type: strings.ToUpper(rtype), Rcode: dns.RcodeToString[dns.RcodeSuccess]}
+}
+
+func nxdomainErr(s, rtype string, ttl uint32) error {
+	return &NegativeAnswer{Name: s, Type: strings.ToUpper(rtype), Rcode: dns.RcodeToString[dns.RcodeNameError], TTL: ttl}
+}
+
+// negativeTTL - min(SOA TTL, SOA minimum) of authority section, 0 if there is no SOA
+func negativeTTL(ns []dns.RR) uint32 {
+	for _, rr := range ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return min(soa.Hdr.Ttl, soa.Minttl)
+		}
+	}
+	return 0
+}
+
+// exchangeRecords - query DNS server over transport
+func exchangeRecords(ctx context.Context, ex dnsExchanger, s, rtype string) ([]models.DnsRecord, error) {
+	t, err := parseRecordType(rtype)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(s), t)
+
+	res, err := ex.Exchange(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return responseRecords(res, s, rtype, t)
+}
+
+// responseRecords - records of response with type t, negative answers and failures as errors
+func responseRecords(res *dns.Msg, s, rtype string, t uint16) ([]models.DnsRecord, error) {
+	if res.Rcode == dns.RcodeNameError {
+		return nil, nxdomainErr(s, rtype, negativeTTL(res.Ns))
+	}
+
+	if res.Rcode != dns.RcodeSuccess {
+		return nil, &RcodeError{Name: s, Type: strings.ToUpper(rtype), Rcode: res.Rcode}
+	}
+
+	records := recordsOfType(res.Answer, t)
+	if len(records) > 0 {
+		return records, nil
+	}
+
+	negative := noRecordsErr(s, rtype).(*NegativeAnswer)
+	negative.TTL = negativeTTL(res.Ns)
+	return nil, negative
+}
+
+// =======================================
+
+func (rs *DoHResolve) ResolveRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	t, err := parseRecordType(rtype)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := rs.rs.Query(ctx, doh.Domain(s), doh.Record(dns.TypeToString[t]))
+	return dohRecords(res, err, s, rtype, t)
+}
+
+// dohRecords - records of DoH response with type t, negative answers and failures as errors
+func dohRecords(res doh.DnsResponse, err error, s, rtype string, t uint16) ([]models.DnsRecord, error) {
+	if status := doh.DoHStatusCode(0); errors.As(err, &status) && status == doh.NXDOMAIN {
+		return nil, nxdomainErr(s, rtype, dohNegativeTTL(res.Authority))
+	}
+
+	if err != nil {
+		return nil, exchangeError(err)
+	}
+
+	var records []models.DnsRecord
+	for _, ans := range res.Answer {
+		if ans.Type != int(t) {
+			continue
+		}
+
+		// JSON API answers carry RDATA in presentation format, parse it as zone file line
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(ans.Name), ans.TTL, dns.TypeToString[t], ans.Data))
+		if err != nil || rr == nil {
+			records = append(records, models.DnsRecord{
+				Name: ans.Name, Type: dns.TypeToString[t], TTL: uint32(ans.TTL), Data: ans.Data,
+			})
+			continue
+		}
+
+		records = append(records, recordFromRR(rr))
+	}
+
+	if len(records) > 0 {
+		return records, nil
+	}
+
+	return nil, noRecordsErr(s, rtype)
+}
+
+// dohNegativeTTL - negative caching TTL of DoH response authority section
+func dohNegativeTTL(authority []doh.Answer) uint32 {
+	for _, ans := range authority {
+		if ans.Type != int(dns.TypeSOA) {
+			continue
+		}
+
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN SOA %s", dns.Fqdn(ans.Name), ans.TTL, ans.Data))
+		if err == nil && rr != nil {
+			return negativeTTL([]dns.RR{rr})
+		}
+	}
+	return 0
+}
+
+// =======================================
+
+/*
+ResolveRecords - query records with system resolver
+
+	A, AAAA, MX, NS, TXT, SRV and PTR are resolved with Go resolver (TTL is unknown - 0).
+	PTR name is reverse name like other resolvers: "1.2.0.192.in-addr.arpa", IP is taken from it for Go resolver.
+	Other types are queried directly from the first /etc/resolv.conf nameserver.
+	CNAME is queried from nameserver too, Go resolver returns only canonical name of whole chain.
+*/
+func (rs LocalResolve) ResolveRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	records, err := rs.lookupRecords(ctx, s, rtype)
+	if err != nil {
+		return nil, confirmNotFound(ctx, err, s, rtype)
+	}
+	return records, nil
+}
+
+/*
+confirmNotFound - real negative answer of Go resolver "not found" error
+
+	Go resolver reports both NXDOMAIN and NODATA as not found without SOA TTL,
+	so query is repeated to the first /etc/resolv.conf nameserver for response code.
+	If it fails too, Go resolver error is returned as is. Other errors are returned as is.
+*/
+func confirmNotFound(ctx context.Context, err error, s, rtype string) error {
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		return err
+	}
+
+	server, srvErr := systemNameserver()
+	if srvErr != nil {
+		return err
+	}
+
+	name := s
+	if strings.EqualFold(rtype, "PTR") {
+		if arpa, arpaErr := dns.ReverseAddr(s); arpaErr == nil {
+			name = arpa
+		}
+	}
+
+	_, exErr := exchangeRecords(ctx, udpExchanger(server), name, rtype)
+
+	var negative *NegativeAnswer
+	if errors.As(exErr, &negative) {
+		negative.Name = s
+		return negative
+	}
+
+	return err
+}
+
+func (rs LocalResolve) lookupRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	t, err := parseRecordType(rtype)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		name    = dns.Fqdn(s)
+		records []models.DnsRecord
+		r       = &net.Resolver{PreferGo: true}
+	)
+
+	hdr := func() dns.RR_Header {
+		return dns.RR_Header{Name: name, Rrtype: t, Class: dns.ClassINET}
+	}
+
+	add := func(rr dns.RR) {
+		records = append(records, recordFromRR(rr))
+	}
+
+	switch t {
+	case dns.TypeA, dns.TypeAAAA:
+		network := "ip4"
+		if t == dns.TypeAAAA {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, s)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if t == dns.TypeA {
+				add(&dns.A{Hdr: hdr(), A: ip})
+			} else {
+				add(&dns.AAAA{Hdr: hdr(), AAAA: ip})
+			}
+		}
+
+	case dns.TypeCNAME:
+		if server, err := systemNameserver(); err == nil {
+			return exchangeRecords(ctx, udpExchanger(server), s, rtype)
+		}
+
+		cname, err := r.LookupCNAME(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if cname != name {
+			add(&dns.CNAME{Hdr: hdr(), Target: cname})
+		}
+
+	case dns.TypeMX:
+		mxs, err := r.LookupMX(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			add(&dns.MX{Hdr: hdr(), Preference: mx.Pref, Mx: mx.Host})
+		}
+
+	case dns.TypeNS:
+		nss, err := r.LookupNS(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			add(&dns.NS{Hdr: hdr(), Ns: ns.Host})
+		}
+
+	case dns.TypeTXT:
+		txts, err := r.LookupTXT(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		for _, txt := range txts {
+			add(&dns.TXT{Hdr: hdr(), Txt: []string{txt}})
+		}
+
+	case dns.TypeSRV:
+		_, srvs, err := r.LookupSRV(ctx, "", "", s)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range srvs {
+			add(&dns.SRV{Hdr: hdr(), Priority: srv.Priority, Weight: srv.Weight, Port: srv.Port, Target: srv.Target})
+		}
+
+	case dns.TypePTR:
+		ip, err := reverseNameIP(s)
+		if err != nil {
+			return nil, err
+		}
+		names, err := r.LookupAddr(ctx, ip.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			add(&dns.PTR{Hdr: hdr(), Ptr: n})
+		}
+
+	default:
+		server, err := systemNameserver()
+		if err != nil {
+			return nil, fmt.Errorf("%s records are not supported by local resolver: %w", dns.TypeToString[t], err)
+		}
+		return exchangeRecords(ctx, udpExchanger(server), s, rtype)
+	}
+
+	if len(records) > 0 {
+		return records, nil
+	}
+
+	return nil, noRecordsErr(s, rtype)
+}
+
+// systemNameserver - first nameserver of /etc/resolv.conf
+func systemNameserver() (string, error) {
+	conf, err := dns.ClientConfigFromFile(systemResolvConf)
+	if err != nil {
+		return "", err
+	}
+
+	if len(conf.Servers) == 0 {
+		return "", errors.New("no nameservers in " + systemResolvConf)
+	}
+
+	return net.JoinHostPort(conf.Servers[0], conf.Port), nil
+}
+
+// =======================================
+
+func (rs *RemoteResolve) ResolveRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	return exchangeRecords(ctx, rs.ex, s, rtype)
+}
+
+// recordsFunc - ResolveRecords of resolver
+type recordsFunc func(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error)
+
+// resolveIPRecords - addresses of A and AAAA records queried at once
+func resolveIPRecords(ctx context.Context, s string, records recordsFunc) ([]net.IP, error) {
+	var (
+		ips  []net.IP
+		errs []error
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+
+	for _, rtype := range []string{"A", "AAAA"} {
+		wg.Go(func() {
+			recs, err := records(ctx, s, rtype)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+
+			for _, rec := range recs {
+				if ip := net.ParseIP(rec.Data); ip != nil {
+					ips = append(ips, ip)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if len(ips) > 0 {
+		return ips, nil
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("no IP resolved for %s: %w", s, resolveErrors(errs))
+	}
+
+	return nil, fmt.Errorf("no IP resolved for %s: %w", s, noRecordsErr(s, "A"))
+}
+
+// resolveNameRecords - domain names of NS or PTR records
+func resolveNameRecords(ctx context.Context, s, rtype string, records recordsFunc) ([]string, error) {
+	recs, err := records(ctx, s, rtype)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		names = append(names, rec.Data)
+	}
+	return names, nil
+}
+
+/*
+reverseNameIP - IP of in-addr.arpa (RFC 1035 3.5) or ip6.arpa (RFC 3596 2.5) reverse name
+
+	Only full address names are accepted: "1.2.0.192.in-addr.arpa", nibble labels of all 32 IPv6 digits.
+*/
+func reverseNameIP(name string) (net.IP, error) {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+
+	switch n := len(labels); {
+	case n == 6 && labels[4] == "in-addr" && labels[5] == "arpa":
+		ip := net.ParseIP(labels[3] + "." + labels[2] + "." + labels[1] + "." + labels[0])
+		if ip != nil {
+			return ip, nil
+		}
+
+	case n == 34 && labels[32] == "ip6" && labels[33] == "arpa":
+		var digits strings.Builder
+		for i := 31; i >= 0; i-- {
+			if len(labels[i]) != 1 {
+				return nil, fmt.Errorf("invalid reverse name: %s", name)
+			}
+			digits.WriteString(labels[i])
+		}
+		if ip, err := hex.DecodeString(digits.String()); err == nil {
+			return net.IP(ip), nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid reverse name, in-addr.arpa or ip6.arpa name of address expected: %s", name)
+}
+
+// resolvePTRRecords - PTR names of IP reverse name
+func resolvePTRRecords(ctx context.Context, ip net.IP, records recordsFunc) ([]string, error) {
+	arpa, err := dns.ReverseAddr(ip.String())
+	if err != nil {
+		return nil, err
+	}
+	return resolveNameRecords(ctx, arpa, "PTR", records)
+}
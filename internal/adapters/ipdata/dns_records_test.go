@@ -0,0 +1,53 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	dns "github.com/miekg/dns"
+)
+
+func TestReverseNameIP(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"1.2.0.192.in-addr.arpa.", "192.0.2.1"},
+		{"1.2.0.192.IN-ADDR.ARPA", "192.0.2.1"},
+		{"b.a.9.8.7.6.5.0.4.0.0.0.3.0.0.0.2.0.0.0.1.0.0.0.0.0.0.0.1.2.3.4.ip6.arpa.", "4321:0:1:2:3:4:567:89ab"},
+		{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.B.D.0.1.0.0.2.IP6.ARPA", "2001:db8::1"},
+
+		// not full address names
+		{"192.0.2.1", ""},
+		{"2.0.192.in-addr.arpa.", ""},
+		{"1.2.0.192.in-addr.arpa.example.", ""},
+		{"256.2.0.192.in-addr.arpa.", ""},
+		{"01.2.0.192.in-addr.arpa.", ""},
+		{"0.8.b.d.0.1.0.0.2.ip6.arpa.", ""},
+		{"g.a.9.8.7.6.5.0.4.0.0.0.3.0.0.0.2.0.0.0.1.0.0.0.0.0.0.0.1.2.3.4.ip6.arpa.", ""},
+		{"ba.9.8.7.6.5.0.4.0.0.0.3.0.0.0.2.0.0.0.1.0.0.0.0.0.0.0.1.2.3.4.4.ip6.arpa.", ""},
+	}
+
+	for _, tt := range tests {
+		ip, err := reverseNameIP(tt.name)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: got %s, want error", tt.name, ip)
+			}
+			continue
+		}
+		if err != nil || !ip.Equal(net.ParseIP(tt.want)) {
+			t.Errorf("%s: got %s, %v, want %s", tt.name, ip, err, tt.want)
+		}
+
+		// reverse name of result is the same name
+		if arpa, _ := dns.ReverseAddr(ip.String()); !strings.EqualFold(arpa, dns.Fqdn(tt.name)) {
+			t.Errorf("%s: reverse name of %s is %s", tt.name, ip, arpa)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+/*
+RetryResolve - resolver decorator with single query timeout and retries
+
+	Query is repeated only on resolver failure (timeout, network error, SERVFAIL, REFUSED,
+	malformed or rate limited response), negative answers (NXDOMAIN, no records) are returned at once.
+*/
+type RetryResolve struct {
+	rv      models.Resolver
+	timeout time.Duration
+	retries int
+}
+
+// NewRetryResolver - every query attempt is limited by timeout (disabled if <= 0) and repeated up to retries times
+func NewRetryResolver(rv models.Resolver, timeout time.Duration, retries int) *RetryResolve {
+	return &RetryResolve{
+		rv:      rv,
+		timeout: timeout,
+		retries: max(retries, 0),
+	}
+}
+
+// Unwrap - wrapped resolver
+func (rs *RetryResolve) Unwrap() models.Resolver {
+	return rs.rv
+}
+
+// UnwrapResolver - innermost resolver of decorators like RetryResolve, to check its optional features
+func UnwrapResolver(rv models.Resolver) models.Resolver {
+	for {
+		w, ok := rv.(interface{ Unwrap() models.Resolver })
+		if !ok {
+			return rv
+		}
+		rv = w.Unwrap()
+	}
+}
+
+// Close - close wrapped resolver if it holds connections
+func (rs *RetryResolve) Close() error {
+	if closer, ok := rs.rv.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func retryQuery[T any](ctx context.Context, rs *RetryResolve, q func(context.Context) (T, error)) (T, error) {
+	var (
+		res  T
+		err  error
+		errs []error
+	)
+
+	for attempt := 0; attempt <= rs.retries; attempt++ {
+		attemptCtx, cancel := withTimeout(ctx, rs.timeout)
+		res, err = q(attemptCtx)
+		cancel()
+
+		if err == nil || !upstreamFailed(err) || ctx.Err() != nil {
+			return res, err
+		}
+
+		errs = append(errs, err)
+	}
+
+	return res, errors.Join(errs...)
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func (rs *RetryResolve) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
+	return retryQuery(ctx, rs, func(ctx context.Context) ([]net.IP, error) {
+		return rs.rv.ResolveIP(ctx, s)
+	})
+}
+
+func (rs *RetryResolve) ResolveNS(ctx context.Context, s string) ([]string, error) {
+	return retryQuery(ctx, rs, func(ctx context.Context) ([]string, error) {
+		return rs.rv.ResolveNS(ctx, s)
+	})
+}
+
+func (rs *RetryResolve) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return retryQuery(ctx, rs, func(ctx context.Context) ([]string, error) {
+		return rs.rv.ResolvePTR(ctx, ip)
+	})
+}
+
+func (rs *RetryResolve) ResolveRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	return retryQuery(ctx, rs, func(ctx context.Context) ([]models.DnsRecord, error) {
+		return rs.rv.ResolveRecords(ctx, s, rtype)
+	})
+}
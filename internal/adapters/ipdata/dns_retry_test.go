@@ -0,0 +1,59 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+)
+
+func TestRetryResolve(t *testing.T) {
+	for _, tt := range []struct {
+		err   error
+		calls int32
+	}{
+		{err: &RcodeError{Name: "x.test", Type: "A", Rcode: dns.RcodeRefused}, calls: 3},
+		{err: &net.DNSError{Err: "no such host", Name: "x.test", IsNotFound: true}, calls: 1},
+	} {
+		stub := &stubResolver{err: tt.err}
+
+		if _, err := NewRetryResolver(stub, time.Second, 2).ResolveIP(context.Background(), "x.test"); err == nil {
+			t.Fatal("error expected")
+		}
+		if stub.calls.Load() != tt.calls {
+			t.Errorf("%v: %d queries, want %d", tt.err, stub.calls.Load(), tt.calls)
+		}
+	}
+}
+
+func TestUnwrapResolver(t *testing.T) {
+	remote, err := NewRemoteResolver("127.0.0.1:5353")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped := NewRetryResolver(NewRetryResolver(remote, time.Second, 1), time.Second, 1)
+
+	if UnwrapResolver(wrapped) != models.Resolver(remote) {
+		t.Fatal("innermost resolver is not unwrapped")
+	}
+
+	if _, ok := UnwrapResolver(wrapped).(models.SubnetResolver); !ok {
+		t.Error("client subnet support is hidden by retries")
+	}
+
+	if _, err := NewDnssecValidator(wrapped); err != nil {
+		t.Errorf("DNSSEC validator of wrapped resolver: %v", err)
+	}
+
+	if _, err := NewDnssecValidator(NewRetryResolver(&stubResolver{}, time.Second, 1)); err == nil {
+		t.Error("DNSSEC validator of resolver without DNS transport: error expected")
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	dns "github.com/miekg/dns"
+)
+
+const (
+	// dotPort - DNS over TLS port: https://www.rfc-editor.org/rfc/rfc7858
+	dotPort = "853"
+	// dotScheme - DoT resolver address prefix: "tls://1.1.1.1@cloudflare-dns.com"
+	dotScheme = "tls://"
+	// dotMaxIdle - max kept idle connections of DoT resolver
+	dotMaxIdle = 4
+)
+
+/*
+TLSResolve - DNS over TLS (RFC 7858) resolver
+
+	Works like RemoteResolve, but every query goes over TLS connection.
+	Connections are kept open and reused by next queries.
+*/
+type TLSResolve struct {
+	RemoteResolve
+	pool *tlsExchanger
+}
+
+/*
+NewTLSResolver - DoT resolver of server address: "1.1.1.1" or "1.1.1.1:853"
+
+	Certificate is verified against serverName (server host if empty).
+	If pins (base64 SHA-256 of certificate SubjectPublicKeyInfo) are set, any certificate of chain must match one of them.
+*/
+func NewTLSResolver(addr, serverName string, pins ...string) (*TLSResolve, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = strings.Trim(addr, "[]"), dotPort
+	}
+
+	if host == "" {
+		return nil, fmt.Errorf("invalid DoT server address: %s", addr)
+	}
+
+	if serverName == "" {
+		serverName = host
+	}
+
+	cfg := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if len(pins) > 0 {
+		hashes := make([][]byte, 0, len(pins))
+
+		for _, pin := range pins {
+			hash, err := base64.StdEncoding.DecodeString(pin)
+			if err != nil || len(hash) != sha256.Size {
+				return nil, fmt.Errorf("invalid SPKI pin (base64 SHA-256 expected): %s", pin)
+			}
+			hashes = append(hashes, hash)
+		}
+
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, cert := range cs.PeerCertificates {
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				if slices.ContainsFunc(hashes, func(h []byte) bool { return bytes.Equal(h, sum[:]) }) {
+					return nil
+				}
+			}
+			return fmt.Errorf("no certificate of %s matches SPKI pins", serverName)
+		}
+	}
+
+	socket := net.JoinHostPort(host, port)
+	pool := &tlsExchanger{
+		addr:   socket,
+		client: &dns.Client{Net: "tcp-tls", TLSConfig: cfg},
+	}
+
+	return &TLSResolve{
+		RemoteResolve: RemoteResolve{
+			dnsSocket: socket,
+			ex:        pool,
+		},
+		pool: pool,
+	}, nil
+}
+
+/*
+ParseTLSResolver - DoT resolver by address string
+
+	"tls://1.1.1.1@cloudflare-dns.com"                 - server 1.1.1.1:853, certificate name cloudflare-dns.com
+	"tls://[2606:4700::1111]:853@cloudflare-dns.com"   - custom port
+	"tls://1.1.1.1@cloudflare-dns.com?pin=BASE64&pin=" - with SPKI pins
+*/
+func ParseTLSResolver(s string) (*TLSResolve, error) {
+	rest, ok := strings.CutPrefix(s, dotScheme)
+	if !ok {
+		return nil, fmt.Errorf("DoT resolver address must start with %s: %s", dotScheme, s)
+	}
+
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	addr, serverName, _ := strings.Cut(rest, "@")
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DoT resolver options: %w", err)
+	}
+
+	var pins []string
+	for _, pin := range query["pin"] {
+		// unescaped "+" of base64 is decoded as space
+		pins = append(pins, strings.ReplaceAll(pin, " ", "+"))
+	}
+
+	return NewTLSResolver(addr, serverName, pins...)
+}
+
+// IsTLSResolver - address string is DoT resolver: "tls://..."
+func IsTLSResolver(s string) bool {
+	return strings.HasPrefix(s, dotScheme)
+}
+
+// Close - close idle connections
+func (rs *TLSResolve) Close() error {
+	return rs.pool.Close()
+}
+
+// tlsExchanger - TLS transport with idle connections pool
+type tlsExchanger struct {
+	addr   string
+	client *dns.Client
+
+	mu   sync.Mutex
+	idle []*dns.Conn
+}
+
+func (e *tlsExchanger) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	for {
+		conn, reused, err := e.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := e.exchangeWith(ctx, msg, conn)
+		if err == nil {
+			e.put(conn)
+			return res, nil
+		}
+		conn.Close()
+
+		// idle connection may be already closed by server, retry with another one
+		if !reused || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
+
+func (e *tlsExchanger) exchangeWith(ctx context.Context, msg *dns.Msg, conn *dns.Conn) (*dns.Msg, error) {
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	res, _, err := e.client.ExchangeWithConnContext(ctx, msg, conn)
+	return res, exchangeError(err)
+}
+
+// get - idle connection or new one. Reports if connection is reused
+func (e *tlsExchanger) get(ctx context.Context) (*dns.Conn, bool, error) {
+	e.mu.Lock()
+	if n := len(e.idle); n > 0 {
+		conn := e.idle[n-1]
+		e.idle = e.idle[:n-1]
+		e.mu.Unlock()
+		return conn, true, nil
+	}
+	e.mu.Unlock()
+
+	conn, err := e.client.DialContext(ctx, e.addr)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return conn, false, nil
+}
+
+func (e *tlsExchanger) put(conn *dns.Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.idle) >= dotMaxIdle {
+		conn.Close()
+		return
+	}
+	e.idle = append(e.idle, conn)
+}
+
+func (e *tlsExchanger) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var errs []error
+	for _, conn := range e.idle {
+		errs = append(errs, conn.Close())
+	}
+	e.idle = nil
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dns "github.com/miekg/dns"
+)
+
+// dotStandIn - DNS over TLS server on loopback with self-signed "dns.test" certificate
+type dotStandIn struct {
+	addr  string
+	roots *x509.CertPool
+	pin   string
+	conns atomic.Int32
+}
+
+// countingListener - counts accepted connections
+type countingListener struct {
+	net.Listener
+	n *atomic.Int32
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.n.Add(1)
+	}
+	return conn, err
+}
+
+func startDoT(t *testing.T, idle time.Duration) *dotStandIn {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dns.test"},
+		DNSNames:     []string{"dns.test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sd := &dotStandIn{roots: x509.NewCertPool()}
+	sd.roots.AddCert(cert)
+
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	sd.pin = base64.StdEncoding.EncodeToString(sum[:])
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd.addr = ln.Addr().String()
+
+	srv := &dns.Server{
+		Listener: countingListener{Listener: ln, n: &sd.conns},
+		Net:      "tcp-tls",
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetReply(r)
+
+			if q := r.Question[0]; q.Qtype == dns.TypeA {
+				rr, _ := dns.NewRR(q.Name + " 60 IN A 192.0.2.53")
+				resp.Answer = append(resp.Answer, rr)
+			}
+			w.WriteMsg(resp)
+		}),
+		IdleTimeout: func() time.Duration { return idle },
+	}
+
+	started := make(chan struct{})
+	srv.NotifyStartedFunc = func() { close(started) }
+
+	go srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { srv.Shutdown() })
+
+	return sd
+}
+
+// trust - resolver accepts self-signed certificate of stand-in
+func (sd *dotStandIn) trust(rs *TLSResolve) *TLSResolve {
+	rs.pool.client.TLSConfig.RootCAs = sd.roots
+	return rs
+}
+
+func TestTLSResolvePooling(t *testing.T) {
+	sd := startDoT(t, time.Minute)
+
+	rs, err := NewTLSResolver(sd.addr, "dns.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd.trust(rs)
+	defer rs.Close()
+
+	ctx := context.Background()
+
+	for range 5 {
+		ips, err := rs.ResolveRecords(ctx, "www.example.test", "A")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ips) != 1 || ips[0].Data != "192.0.2.53" {
+			t.Fatalf("answers %+v", ips)
+		}
+	}
+
+	if n := sd.conns.Load(); n != 1 {
+		t.Errorf("%d connections for sequential queries, want 1 reused", n)
+	}
+
+	wg := sync.WaitGroup{}
+	for range 16 {
+		wg.Go(func() {
+			if _, err := rs.ResolveRecords(ctx, "www.example.test", "A"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	rs.pool.mu.Lock()
+	idle := len(rs.pool.idle)
+	rs.pool.mu.Unlock()
+
+	if idle < 1 || idle > dotMaxIdle {
+		t.Errorf("%d idle connections, want 1..%d", idle, dotMaxIdle)
+	}
+
+	if err := rs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.pool.idle) != 0 {
+		t.Error("idle connections are kept after Close")
+	}
+}
+
+func TestTLSResolveStaleConnection(t *testing.T) {
+	sd := startDoT(t, 50*time.Millisecond)
+
+	rs, err := NewTLSResolver(sd.addr, "dns.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd.trust(rs)
+	defer rs.Close()
+
+	for range 2 {
+		if _, err := rs.ResolveRecords(context.Background(), "www.example.test", "A"); err != nil {
+			t.Fatal(err)
+		}
+		// server closes idle connection
+		time.Sleep(150 * time.Millisecond)
+	}
+
+	if n := sd.conns.Load(); n != 2 {
+		t.Errorf("%d connections, want new one after server closed idle", n)
+	}
+}
+
+func TestTLSResolvePins(t *testing.T) {
+	sd := startDoT(t, time.Minute)
+
+	other := make([]byte, sha256.Size)
+	wrongPin := base64.StdEncoding.EncodeToString(other)
+
+	tests := []struct {
+		name string
+		pins []string
+		ok   bool
+	}{
+		{name: "matching pin", pins: []string{wrongPin, sd.pin}, ok: true},
+		{name: "no matching pin", pins: []string{wrongPin}},
+		{name: "no pins", ok: true},
+	}
+
+	for _, tt := range tests {
+		rs, err := NewTLSResolver(sd.addr, "dns.test", tt.pins...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sd.trust(rs)
+
+		_, err = rs.ResolveRecords(context.Background(), "www.example.test", "A")
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+		rs.Close()
+	}
+
+	// certificate name mismatch is not saved by pin
+	rs, err := NewTLSResolver(sd.addr, "other.test", sd.pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sd.trust(rs).ResolveRecords(context.Background(), "www.example.test", "A"); err == nil {
+		t.Error("certificate of dns.test is accepted for other.test")
+	}
+
+	if _, err := NewTLSResolver(sd.addr, "dns.test", "not-base64!"); err == nil {
+		t.Error("invalid pin is accepted")
+	}
+
+	// "+" of unescaped pin in query string
+	parsed, err := ParseTLSResolver("tls://" + sd.addr + "@dns.test?pin=" + url.PathEscape(sd.pin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sd.trust(parsed).ResolveRecords(context.Background(), "www.example.test", "A"); err != nil {
+		t.Errorf("parsed resolver: %v", err)
+	}
+}
@@ -0,0 +1,463 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+)
+
+const (
+	// traceMaxHops - max queries of single trace
+	traceMaxHops = 48
+	// traceMaxCNAME - max followed CNAME records of single trace
+	traceMaxCNAME = 8
+	// traceMaxDepth - max nested traces for nameservers without glue
+	traceMaxDepth = 3
+	// traceQueryTimeout - single server query timeout
+	traceQueryTimeout = 3 * time.Second
+)
+
+// rootHints - IPv4 addresses of root servers: https://www.iana.org/domains/root/servers
+var rootHints = []traceServer{
+	{name: "a.root-servers.net.", addrs: []string{"198.41.0.4"}},
+	{name: "b.root-servers.net.", addrs: []string{"170.247.170.2"}},
+	{name: "c.root-servers.net.", addrs: []string{"192.33.4.12"}},
+	{name: "d.root-servers.net.", addrs: []string{"199.7.91.13"}},
+	{name: "e.root-servers.net.", addrs: []string{"192.203.230.10"}},
+	{name: "f.root-servers.net.", addrs: []string{"192.5.5.241"}},
+	{name: "g.root-servers.net.", addrs: []string{"192.112.36.4"}},
+	{name: "h.root-servers.net.", addrs: []string{"198.97.190.53"}},
+	{name: "i.root-servers.net.", addrs: []string{"192.36.148.17"}},
+	{name: "j.root-servers.net.", addrs: []string{"192.58.128.30"}},
+	{name: "k.root-servers.net.", addrs: []string{"193.0.14.129"}},
+	{name: "l.root-servers.net.", addrs: []string{"199.7.83.42"}},
+	{name: "m.root-servers.net.", addrs: []string{"202.12.27.33"}},
+}
+
+// traceServer - nameserver of zone with its addresses
+type traceServer struct {
+	name  string
+	addrs []string
+}
+
+/*
+TraceResolve - iterative resolver that walks delegation from root servers like "dig +trace"
+
+	Every query is non-recursive, referrals are followed with glue records.
+	Nameservers without glue are resolved with nested trace.
+*/
+type TraceResolve struct {
+	roots []traceServer
+	port  string
+	udp   *dns.Client
+	tcp   *dns.Client
+}
+
+// NewTraceResolver - iterative resolver from IANA root servers
+func NewTraceResolver() *TraceResolve {
+	return &TraceResolve{
+		roots: rootHints,
+		port:  "53",
+		udp:   &dns.Client{Net: "udp"},
+		tcp:   &dns.Client{Net: "tcp"},
+	}
+}
+
+// NewTraceResolverWith - iterative resolver from custom root servers IPs. Port is used for every queried server
+func NewTraceResolverWith(port string, roots ...string) *TraceResolve {
+	rs := NewTraceResolver()
+	rs.port = port
+	rs.roots = make([]traceServer, 0, len(roots))
+
+	for _, root := range roots {
+		rs.roots = append(rs.roots, traceServer{name: root, addrs: []string{root}})
+	}
+
+	return rs
+}
+
+// Trace - iterative resolution of record type with every delegation hop
+func (rs *TraceResolve) Trace(ctx context.Context, s string, rtype string) (models.DnsTrace, error) {
+	t, err := parseRecordType(rtype)
+	if err != nil {
+		return models.DnsTrace{}, err
+	}
+
+	tr := models.DnsTrace{
+		Name: strings.ToLower(dns.Fqdn(s)),
+		Type: dns.TypeToString[t],
+		Hops: []models.TraceHop{},
+	}
+
+	if err := rs.trace(ctx, &tr, t, 0); err != nil {
+		tr.Err = err.Error()
+		return tr, err
+	}
+
+	return tr, nil
+}
+
+// trace - walk delegation from roots, restarts from roots for every followed CNAME
+func (rs *TraceResolve) trace(ctx context.Context, tr *models.DnsTrace, qtype uint16, depth int) error {
+	var (
+		name    = tr.Name
+		zone    = "."
+		servers = rs.shuffledRoots()
+		cnames  = 0
+	)
+
+	for {
+		resp, hop, err := rs.ask(ctx, tr, zone, servers, name, qtype)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.Rcode == dns.RcodeNameError:
+			return nxdomainErr(name, tr.Type, negativeTTL(resp.Ns))
+
+		case len(resp.Answer) > 0:
+			answers, target := traceAnswers(resp.Answer, name, qtype)
+			tr.Answers = append(tr.Answers, answers...)
+			hop.Answers = answers
+
+			if target == "" {
+				return nil
+			}
+
+			cnames++
+			if cnames > traceMaxCNAME {
+				return fmt.Errorf("%s: CNAME chain is longer than %d", tr.Name, traceMaxCNAME)
+			}
+
+			name, zone, servers = target, ".", rs.shuffledRoots()
+
+		case hop.Referral != "":
+			next, err := rs.referralServers(ctx, resp, hop, depth)
+			if err != nil {
+				return err
+			}
+			zone, servers = hop.Referral, next
+
+		default:
+			// authoritative NODATA
+			negative := noRecordsErr(name, tr.Type).(*NegativeAnswer)
+			negative.TTL = negativeTTL(resp.Ns)
+			return negative
+		}
+	}
+}
+
+/*
+ask - query zone servers one by one until usable response
+
+	Response is usable if it's authoritative answer, NXDOMAIN or referral to child zone.
+	Other responses (REFUSED, SERVFAIL, upward referrals) mark hop as lame.
+*/
+func (rs *TraceResolve) ask(
+	ctx context.Context, tr *models.DnsTrace, zone string, servers []traceServer, name string, qtype uint16,
+) (*dns.Msg, *models.TraceHop, error) {
+	for _, srv := range servers {
+		for _, addr := range srv.addrs {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+
+			if len(tr.Hops) >= traceMaxHops {
+				return nil, nil, fmt.Errorf("%s: trace is longer than %d queries", tr.Name, traceMaxHops)
+			}
+
+			hop, resp := rs.query(ctx, zone, srv.name, addr, name, qtype)
+			tr.Hops = append(tr.Hops, hop)
+			h := &tr.Hops[len(tr.Hops)-1]
+
+			if resp == nil {
+				continue
+			}
+
+			if child, ns, glue := traceReferral(resp, zone, name); child != "" {
+				h.Referral, h.NameServers, h.Glue = child, ns, glue
+				return resp, h, nil
+			}
+
+			usable := resp.Rcode == dns.RcodeSuccess || resp.Rcode == dns.RcodeNameError
+			if usable && (resp.Authoritative || len(resp.Answer) > 0) {
+				return resp, h, nil
+			}
+
+			h.Lame = true
+		}
+	}
+
+	// recursive resolvers answer SERVFAIL in this case
+	return nil, nil, &models.LookupError{
+		Code: models.CodeSERVFAIL,
+		Err:  fmt.Errorf("no usable nameserver of zone %s for %s", zone, name),
+	}
+}
+
+// query - single non-recursive query, truncated UDP response is repeated over TCP
+func (rs *TraceResolve) query(ctx context.Context, zone, server, addr, name string, qtype uint16) (models.TraceHop, *dns.Msg) {
+	hop := models.TraceHop{
+		Zone:   zone,
+		Server: server,
+		Addr:   net.JoinHostPort(addr, rs.port),
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.RecursionDesired = false
+
+	ctx, cancel := context.WithTimeout(ctx, traceQueryTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	resp, _, err := rs.udp.ExchangeContext(ctx, msg, hop.Addr)
+	if err == nil && resp.Truncated {
+		resp, _, err = rs.tcp.ExchangeContext(ctx, msg, hop.Addr)
+	}
+
+	hop.LatencyMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		hop.Err = err.Error()
+		return hop, nil
+	}
+
+	hop.Rcode = dns.RcodeToString[resp.Rcode]
+	hop.Authoritative = resp.Authoritative
+
+	return hop, resp
+}
+
+// traceReferral - child zone of downward referral with its nameservers and glue
+func traceReferral(resp *dns.Msg, zone, name string) (string, []string, []models.DnsRecord) {
+	if resp.Authoritative || len(resp.Answer) > 0 || resp.Rcode != dns.RcodeSuccess {
+		return "", nil, nil
+	}
+
+	var (
+		child string
+		ns    []string
+	)
+
+	for _, rr := range resp.Ns {
+		rec, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+
+		owner := strings.ToLower(rec.Hdr.Name)
+		if owner == zone || !dns.IsSubDomain(zone, owner) || !dns.IsSubDomain(owner, name) {
+			continue
+		}
+
+		child = owner
+		ns = append(ns, strings.ToLower(rec.Ns))
+	}
+
+	if child == "" {
+		return "", nil, nil
+	}
+
+	var glue []models.DnsRecord
+	for _, rr := range resp.Extra {
+		switch rr.(type) {
+		case *dns.A, *dns.AAAA:
+			if containsName(ns, rr.Header().Name) {
+				glue = append(glue, recordFromRR(rr))
+			}
+		}
+	}
+
+	return child, ns, glue
+}
+
+// traceAnswers - answers of query and CNAME target if name has no records of query type
+func traceAnswers(answer []dns.RR, name string, qtype uint16) ([]models.DnsRecord, string) {
+	var (
+		records []models.DnsRecord
+		target  string
+		found   bool
+	)
+
+	for _, rr := range answer {
+		records = append(records, recordFromRR(rr))
+
+		switch {
+		case rr.Header().Rrtype == qtype:
+			found = true
+		case rr.Header().Rrtype == dns.TypeCNAME && strings.EqualFold(rr.Header().Name, name):
+			target = strings.ToLower(rr.(*dns.CNAME).Target)
+		}
+	}
+
+	if found || qtype == dns.TypeCNAME {
+		return records, ""
+	}
+
+	return records, target
+}
+
+/*
+referralServers - nameservers of referral hop with glue addresses
+
+	Glueless ones are resolved by nested trace, every nested trace is recorded in hop.
+*/
+func (rs *TraceResolve) referralServers(ctx context.Context, resp *dns.Msg, hop *models.TraceHop, depth int) ([]traceServer, error) {
+	var (
+		ns       = hop.NameServers
+		servers  []traceServer
+		glueless []string
+	)
+
+	for _, name := range ns {
+		srv := traceServer{name: name}
+
+		// IPv4 glue first
+		for _, rr := range resp.Extra {
+			if a, ok := rr.(*dns.A); ok && strings.EqualFold(a.Hdr.Name, name) {
+				srv.addrs = append(srv.addrs, a.A.String())
+			}
+		}
+		for _, rr := range resp.Extra {
+			if a, ok := rr.(*dns.AAAA); ok && strings.EqualFold(a.Hdr.Name, name) {
+				srv.addrs = append(srv.addrs, a.AAAA.String())
+			}
+		}
+
+		if len(srv.addrs) == 0 {
+			glueless = append(glueless, name)
+			continue
+		}
+		servers = append(servers, srv)
+	}
+
+	if len(servers) > 0 {
+		return servers, nil
+	}
+
+	if depth >= traceMaxDepth {
+		return nil, fmt.Errorf("nameservers %v have no glue, nested trace is deeper than %d", ns, traceMaxDepth)
+	}
+
+	var errs []error
+	for _, name := range glueless {
+		sub := models.DnsTrace{Name: name, Type: "A", Hops: []models.TraceHop{}}
+
+		err := rs.trace(ctx, &sub, dns.TypeA, depth+1)
+		if err != nil {
+			sub.Err = err.Error()
+		}
+		hop.NSTraces = append(hop.NSTraces, sub)
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		srv := traceServer{name: name}
+		for _, rec := range sub.Answers {
+			if rec.Type == "A" {
+				srv.addrs = append(srv.addrs, rec.Data)
+			}
+		}
+
+		if len(srv.addrs) > 0 {
+			return []traceServer{srv}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to resolve nameservers %v: %w", ns, errors.Join(errs...))
+}
+
+func (rs *TraceResolve) shuffledRoots() []traceServer {
+	roots := make([]traceServer, len(rs.roots))
+	for i, j := range rand.Perm(len(rs.roots)) {
+		roots[i] = rs.roots[j]
+	}
+	return roots
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// traceRecords - records of type from trace answers
+func (rs *TraceResolve) traceRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	tr, err := rs.Trace(ctx, s, rtype)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.DnsRecord
+	for _, rec := range tr.Answers {
+		if rec.Type == tr.Type {
+			records = append(records, rec)
+		}
+	}
+
+	if len(records) > 0 {
+		return records, nil
+	}
+
+	return nil, noRecordsErr(s, rtype)
+}
+
+func (rs *TraceResolve) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
+	return resolveIPRecords(ctx, s, rs.traceRecords)
+}
+
+func (rs *TraceResolve) ResolveNS(ctx context.Context, s string) ([]string, error) {
+	records, err := rs.traceRecords(ctx, s, "NS")
+	if err != nil {
+		return nil, fmt.Errorf("no NS resolved for %s: %w", s, err)
+	}
+
+	nss := make([]string, 0, len(records))
+	for _, rec := range records {
+		nss = append(nss, rec.Data)
+	}
+
+	return nss, nil
+}
+
+func (rs *TraceResolve) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	arpa, err := dns.ReverseAddr(ip.String())
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := rs.traceRecords(ctx, arpa, "PTR")
+	if err != nil {
+		return nil, fmt.Errorf("no PTR resolved for %s: %w", ip, err)
+	}
+
+	names := make([]string, 0, len(records))
+	for _, rec := range records {
+		names = append(names, rec.Data)
+	}
+
+	return names, nil
+}
+
+func (rs *TraceResolve) ResolveRecords(ctx context.Context, s string, rtype string) ([]models.DnsRecord, error) {
+	return rs.traceRecords(ctx, s, rtype)
+}
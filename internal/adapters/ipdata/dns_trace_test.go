@@ -0,0 +1,218 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eterline/micro-utils/internal/models"
+	dns "github.com/miekg/dns"
+)
+
+// startDNS - UDP DNS servers on loopback IPs sharing one port, returns the port
+func startDNS(t *testing.T, handlers map[string]dns.HandlerFunc) string {
+	t.Helper()
+
+	for attempt := 0; attempt < 10; attempt++ {
+		var (
+			conns []net.PacketConn
+			port  = "0"
+			err   error
+		)
+
+		for ip := range handlers {
+			var pc net.PacketConn
+			if pc, err = net.ListenPacket("udp", net.JoinHostPort(ip, port)); err != nil {
+				break
+			}
+			conns = append(conns, pc)
+			port = strconv.Itoa(pc.LocalAddr().(*net.UDPAddr).Port)
+		}
+
+		if err != nil {
+			for _, pc := range conns {
+				pc.Close()
+			}
+			continue
+		}
+
+		for _, pc := range conns {
+			ip := pc.LocalAddr().(*net.UDPAddr).IP.String()
+			srv := &dns.Server{PacketConn: pc, Handler: handlers[ip]}
+
+			started := make(chan struct{})
+			srv.NotifyStartedFunc = func() { close(started) }
+
+			go srv.ActivateAndServe()
+			<-started
+			t.Cleanup(func() { srv.Shutdown() })
+		}
+
+		return port
+	}
+
+	t.Fatal("no free port shared by loopback addresses")
+	return ""
+}
+
+func mustRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rr
+}
+
+// delegation - referral handler: child zones by suffix, nameservers and optional glue
+func delegation(t *testing.T, zones map[string][]string) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(r)
+
+		name := r.Question[0].Name
+		found := false
+
+		for zone, records := range zones {
+			if !dns.IsSubDomain(zone, name) {
+				continue
+			}
+			found = true
+
+			for _, s := range records {
+				rr := mustRR(t, s)
+				if rr.Header().Rrtype == dns.TypeNS {
+					resp.Ns = append(resp.Ns, rr)
+				} else {
+					resp.Extra = append(resp.Extra, rr)
+				}
+			}
+		}
+
+		if !found {
+			resp.Rcode = dns.RcodeRefused
+		}
+		w.WriteMsg(resp)
+	}
+}
+
+// authority - authoritative handler of zone with records by owner name
+func authority(t *testing.T, zone string, records ...string) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(r)
+		resp.Authoritative = true
+
+		q := r.Question[0]
+		exists := false
+
+		for _, s := range records {
+			rr := mustRR(t, s)
+			if !strings.EqualFold(rr.Header().Name, q.Name) {
+				continue
+			}
+			exists = true
+
+			if rr.Header().Rrtype == q.Qtype || rr.Header().Rrtype == dns.TypeCNAME {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+
+		if len(resp.Answer) == 0 {
+			resp.Ns = append(resp.Ns, mustRR(t, zone+" 300 IN SOA ns. hostmaster. 1 3600 600 86400 60"))
+			if !exists {
+				resp.Rcode = dns.RcodeNameError
+			}
+		}
+		w.WriteMsg(resp)
+	}
+}
+
+func TestTraceHierarchy(t *testing.T) {
+	port := startDNS(t, map[string]dns.HandlerFunc{
+		// root
+		"127.0.0.1": delegation(t, map[string][]string{
+			"test.": {"test. 86400 IN NS ns.nic.test.", "ns.nic.test. 86400 IN A 127.0.0.2"},
+		}),
+		// test. TLD: example.test nameserver has no glue
+		"127.0.0.2": delegation(t, map[string][]string{
+			"example.test.": {"example.test. 3600 IN NS ns1.dnshost.test."},
+			"dnshost.test.": {"dnshost.test. 3600 IN NS ns.dnshost.test.", "ns.dnshost.test. 3600 IN A 127.0.0.3"},
+		}),
+		"127.0.0.3": authority(t, "dnshost.test.",
+			"ns.dnshost.test. 3600 IN A 127.0.0.3",
+			"ns1.dnshost.test. 3600 IN A 127.0.0.4",
+		),
+		"127.0.0.4": authority(t, "example.test.",
+			"www.example.test. 300 IN CNAME web.example.test.",
+			"web.example.test. 300 IN A 192.0.2.10",
+		),
+	})
+
+	rs := NewTraceResolverWith(port, "127.0.0.1")
+
+	tr, err := rs.Trace(context.Background(), "WWW.example.test", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var zones []string
+	for _, hop := range tr.Hops {
+		zones = append(zones, hop.Zone)
+	}
+
+	// CNAME restarts from root
+	want := ". test. example.test. . test. example.test."
+	if got := strings.Join(zones, " "); got != want {
+		t.Errorf("hop zones %q, want %q", got, want)
+	}
+
+	last := tr.Answers[len(tr.Answers)-1]
+	if last.Type != "A" || last.Data != "192.0.2.10" {
+		t.Errorf("answers %+v, want final A 192.0.2.10", tr.Answers)
+	}
+
+	referral := tr.Hops[1]
+	if referral.Referral != "example.test." || len(referral.Glue) != 0 || len(referral.NSTraces) != 1 {
+		t.Fatalf("glueless referral hop %+v, want one nested trace", referral)
+	}
+
+	sub := referral.NSTraces[0]
+	if sub.Name != "ns1.dnshost.test." || sub.Err != "" || len(sub.Hops) != 3 {
+		t.Errorf("nested trace %+v, want 3 hops of ns1.dnshost.test.", sub)
+	}
+	if len(sub.Answers) != 1 || sub.Answers[0].Data != "127.0.0.4" {
+		t.Errorf("nested trace answers %+v, want 127.0.0.4", sub.Answers)
+	}
+
+	if !tr.Hops[2].Authoritative || tr.Hops[2].Addr != net.JoinHostPort("127.0.0.4", port) {
+		t.Errorf("authoritative hop %+v", tr.Hops[2])
+	}
+
+	_, err = rs.Trace(context.Background(), "missing.example.test", "A")
+	if models.ErrorCodeOf(err) != models.CodeNXDOMAIN {
+		t.Errorf("got %v, want NXDOMAIN", err)
+	}
+
+	// glueless nameserver which doesn't resolve
+	broken := startDNS(t, map[string]dns.HandlerFunc{
+		"127.0.0.5": delegation(t, map[string][]string{
+			"test.": {"test. 86400 IN NS ns.nowhere.test."},
+		}),
+	})
+
+	tr, err = NewTraceResolverWith(broken, "127.0.0.5").Trace(context.Background(), "www.example.test", "A")
+	if err == nil {
+		t.Fatal("error expected for unresolvable glueless nameserver")
+	}
+	if len(tr.Hops) == 0 || len(tr.Hops[0].NSTraces) != 1 || tr.Hops[0].NSTraces[0].Err == "" {
+		t.Errorf("failed nested trace is not recorded: %+v", tr.Hops)
+	}
+}
@@ -5,42 +5,299 @@
 package ipdata
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/eterline/micro-utils/internal/models"
 )
 
+const (
+	ipApiEndpoint = "http://ip-api.com"
+	// all fields of https://ip-api.com/docs/api:json include "query" and "message"
+	ipApiFields = "66846719"
+	// ipApiBatchSize - max IP count per one /batch request
+	ipApiBatchSize = 100
+	// ipApiRetries - max retries of rate limited request
+	ipApiRetries = 3
+	// ipApiDefaultTtl - rate limit window wait if X-Ttl is not provided
+	ipApiDefaultTtl = 60 * time.Second
+	// ipApiDefaultTimeout - single HTTP request timeout if it's not provided
+	ipApiDefaultTimeout = 10 * time.Second
+)
+
+var (
+	ErrApiPrivateRange  = errors.New("private range")
+	ErrApiReservedRange = errors.New("reserved range")
+	ErrApiInvalidQuery  = errors.New("invalid query")
+	ErrApiRateLimited   = &models.LookupError{Code: models.CodeRateLimited, Err: errors.New("rate limited")}
+)
+
+// ApiFailError - ip-api.com "fail" status response or unsuccessful HTTP status
+type ApiFailError struct {
+	Query   string
+	Message string
+}
+
+func (e *ApiFailError) Error() string {
+	if e.Query == "" {
+		return fmt.Sprintf("ip-api request failed: %s", e.Message)
+	}
+	return fmt.Sprintf("ip-api request for %s failed: %s", e.Query, e.Message)
+}
+
+// Unwrap - known fail messages matches to ErrApi* errors
+func (e *ApiFailError) Unwrap() error {
+	switch strings.ToLower(e.Message) {
+	case "private range":
+		return ErrApiPrivateRange
+	case "reserved range":
+		return ErrApiReservedRange
+	case "invalid query":
+		return ErrApiInvalidQuery
+	case "rate limited":
+		return ErrApiRateLimited
+	}
+	return nil
+}
+
+// ipApiResponse - response object with service fields
+type ipApiResponse struct {
+	models.AboutIPobject
+	Message string `json:"message"`
+	Query   string `json:"query"`
+}
+
+func (r ipApiResponse) result() (models.AboutIPobject, error) {
+	if r.Status != "success" {
+		return models.AboutIPobject{}, &ApiFailError{Query: r.Query, Message: r.Message}
+	}
+
+	obj := r.AboutIPobject
+	obj.RequestTime = time.Now()
+	return obj, nil
+}
+
+/*
+IpInfoExternalApi - ip-api.com IP resumer
+
+	Requests share rate limit state from X-Rl/X-Ttl headers:
+	when limit is exhausted, requests wait for the next window instead of failing.
+	Every HTTP request is limited by timeout, waiting for rate limit window - by context only.
+*/
 type IpInfoExternalApi struct {
-	client *http.Client
+	client   *http.Client
+	endpoint string
+	timeout  time.Duration
+
+	mu        sync.Mutex
+	remaining int
+	resetAt   time.Time
 }
 
-func NewExternalApi() *IpInfoExternalApi {
+// NewExternalApi - ip-api.com resumer. If timeout <= 0, 10s per request is used
+func NewExternalApi(timeout time.Duration) *IpInfoExternalApi {
+	return NewExternalApiEndpoint(ipApiEndpoint, timeout)
+}
+
+// NewExternalApiEndpoint - ip-api.com compatible service on custom endpoint
+func NewExternalApiEndpoint(endpoint string, timeout time.Duration) *IpInfoExternalApi {
+	if timeout <= 0 {
+		timeout = ipApiDefaultTimeout
+	}
+
 	return &IpInfoExternalApi{
-		client: &http.Client{},
+		client:   &http.Client{},
+		endpoint: strings.TrimRight(endpoint, "/"),
+		timeout:  timeout,
 	}
 }
 
-func (ea *IpInfoExternalApi) ResumeIP(ip net.IP) (models.AboutIPobject, error) {
+func (ea *IpInfoExternalApi) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	if ip == nil {
+		return models.AboutIPobject{}, errors.New("ip is nil")
+	}
+
+	api := fmt.Sprintf("%s/json/%s?fields=%s", ea.endpoint, ip.String(), ipApiFields)
 
-	api := fmt.Sprintf("http://ip-api.com/json/%s?fields=66846719", ip.String())
+	var res ipApiResponse
 
-	resp, err := ea.client.Get(api)
+	err := ea.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, api, nil)
+	}, &res)
 	if err != nil {
 		return models.AboutIPobject{}, err
 	}
+
+	return res.result()
+}
+
+/*
+ResumeIPs - resume IP list with /batch endpoint
+
+	IPs are grouped by 100 per request. Results have the same order as ips.
+*/
+func (ea *IpInfoExternalApi) ResumeIPs(ctx context.Context, ips []net.IP) ([]models.AboutIPobject, []error) {
+	var (
+		objs = make([]models.AboutIPobject, len(ips))
+		errs = make([]error, len(ips))
+	)
+
+	for start := 0; start < len(ips); start += ipApiBatchSize {
+		end := min(start+ipApiBatchSize, len(ips))
+		ea.resumeBatch(ctx, ips[start:end], objs[start:end], errs[start:end])
+	}
+
+	return objs, errs
+}
+
+func (ea *IpInfoExternalApi) resumeBatch(ctx context.Context, ips []net.IP, objs []models.AboutIPobject, errs []error) {
+	query := make([]string, len(ips))
+	for i, ip := range ips {
+		query[i] = ip.String()
+	}
+
+	body, err := json.Marshal(query)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return
+	}
+
+	api := fmt.Sprintf("%s/batch?fields=%s", ea.endpoint, ipApiFields)
+
+	var res []ipApiResponse
+
+	err = ea.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, api, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, err
+	}, &res)
+
+	if err == nil && len(res) != len(ips) {
+		err = fmt.Errorf("ip-api batch response size mismatch: %d of %d", len(res), len(ips))
+	}
+
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return
+	}
+
+	for i := range res {
+		objs[i], errs[i] = res[i].result()
+	}
+}
+
+// do - send request with respect of rate limit and decode JSON body to v
+func (ea *IpInfoExternalApi) do(ctx context.Context, newReq func(context.Context) (*http.Request, error), v any) error {
+	for attempt := 0; ; attempt++ {
+		if err := ea.waitLimit(ctx); err != nil {
+			return err
+		}
+
+		limited, err := ea.try(ctx, newReq, v)
+		if err != nil {
+			return err
+		}
+
+		if !limited {
+			return nil
+		}
+
+		if attempt >= ipApiRetries {
+			return &ApiFailError{Message: "rate limited"}
+		}
+	}
+}
+
+// try - single request attempt limited by timeout
+func (ea *IpInfoExternalApi) try(ctx context.Context, newReq func(context.Context) (*http.Request, error), v any) (limited bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, ea.timeout)
+	defer cancel()
+
+	req, err := newReq(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := ea.client.Do(req)
+	if err != nil {
+		return false, err
+	}
 	defer resp.Body.Close()
 
-	var ipObj models.AboutIPobject
+	if ea.updateLimit(resp) {
+		return true, nil
+	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&ipObj); err != nil {
-		return models.AboutIPobject{}, err
+	if resp.StatusCode != http.StatusOK {
+		return false, &ApiFailError{Message: resp.Status}
 	}
 
-	ipObj.RequestTime = time.Now()
+	return false, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// waitLimit - blocks until rate limit window allows request or ctx done
+func (ea *IpInfoExternalApi) waitLimit(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ea.mu.Lock()
+		wait := time.Until(ea.resetAt)
+		if ea.remaining > 0 || wait <= 0 {
+			ea.remaining--
+			ea.mu.Unlock()
+			return nil
+		}
+		ea.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// updateLimit - read X-Rl/X-Ttl headers. Returns true if request was rate limited
+func (ea *IpInfoExternalApi) updateLimit(resp *http.Response) bool {
+	limited := resp.StatusCode == http.StatusTooManyRequests
+
+	rl, rlErr := strconv.Atoi(resp.Header.Get("X-Rl"))
+	ttl, ttlErr := strconv.Atoi(resp.Header.Get("X-Ttl"))
+
+	ea.mu.Lock()
+	defer ea.mu.Unlock()
+
+	switch {
+	case rlErr == nil && ttlErr == nil:
+		ea.remaining = rl
+		ea.resetAt = time.Now().Add(time.Duration(ttl) * time.Second)
+	case limited:
+		ea.remaining = 0
+		ea.resetAt = time.Now().Add(ipApiDefaultTtl)
+	}
+
+	if limited && ea.remaining > 0 {
+		ea.remaining = 0
+	}
 
-	return ipObj, nil
+	return limited
 }
@@ -0,0 +1,231 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// ipApiAnswer - ip-api.com like answer of query: 10.x and 240.x are failed ranges
+func ipApiAnswer(query string) map[string]any {
+	switch {
+	case strings.HasPrefix(query, "10."):
+		return map[string]any{"status": "fail", "message": "private range", "query": query}
+	case strings.HasPrefix(query, "240."):
+		return map[string]any{"status": "fail", "message": "reserved range", "query": query}
+	}
+	return map[string]any{"status": "success", "country": "Testland", "as": "AS64500 " + query, "query": query}
+}
+
+func ipsRange(n int) []net.IP {
+	ips := make([]net.IP, n)
+	for i := range ips {
+		ips[i] = net.IPv4(198, 51, byte(i/250), byte(i%250+1))
+	}
+	return ips
+}
+
+func TestExternalApiBatchSplit(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		sizes []int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/batch" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+
+		var query []string
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		sizes = append(sizes, len(query))
+		mu.Unlock()
+
+		res := make([]map[string]any, len(query))
+		for i, q := range query {
+			res[i] = ipApiAnswer(q)
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+
+	ips := ipsRange(2*ipApiBatchSize + 50)
+	ips[7] = net.ParseIP("10.0.0.7")
+
+	objs, errs := NewExternalApiEndpoint(srv.URL, time.Second).ResumeIPs(context.Background(), ips)
+
+	if fmt.Sprint(sizes) != "[100 100 50]" {
+		t.Fatalf("batch sizes = %v, want [100 100 50]", sizes)
+	}
+
+	for i, ip := range ips {
+		if i == 7 {
+			if !errors.Is(errs[i], ErrApiPrivateRange) {
+				t.Errorf("%s: got error %v, want private range", ip, errs[i])
+			}
+			continue
+		}
+
+		if errs[i] != nil {
+			t.Fatalf("%s: unexpected error %v", ip, errs[i])
+		}
+		if want := "AS64500 " + ip.String(); objs[i].As != want {
+			t.Fatalf("result %d out of order: as %q, want %q", i, objs[i].As, want)
+		}
+	}
+}
+
+func TestExternalApiRateLimitWait(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		n := len(times)
+		mu.Unlock()
+
+		switch n {
+		case 1:
+			// window is exhausted by this request
+			w.Header().Set("X-Rl", "0")
+			w.Header().Set("X-Ttl", "1")
+		case 2:
+			// limited anyway: request is retried after the window
+			w.Header().Set("X-Rl", "0")
+			w.Header().Set("X-Ttl", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		default:
+			w.Header().Set("X-Rl", "44")
+			w.Header().Set("X-Ttl", "60")
+		}
+
+		json.NewEncoder(w).Encode(ipApiAnswer(strings.TrimPrefix(r.URL.Path, "/json/")))
+	}))
+	defer srv.Close()
+
+	ea := NewExternalApiEndpoint(srv.URL, time.Second)
+
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		obj, err := ea.ResumeIP(context.Background(), net.ParseIP(ip))
+		if err != nil {
+			t.Fatalf("%s: %v", ip, err)
+		}
+		if obj.Status != "success" {
+			t.Fatalf("%s: status %q", ip, obj.Status)
+		}
+	}
+
+	if len(times) != 3 {
+		t.Fatalf("request count = %d, want 3", len(times))
+	}
+
+	for i := 1; i < len(times); i++ {
+		// X-Ttl is whole seconds, allow timer coarseness
+		if gap := times[i].Sub(times[i-1]); gap < 900*time.Millisecond {
+			t.Errorf("request %d sent %v after previous, rate limit window is ignored", i+1, gap)
+		}
+	}
+
+	// exhausted window waits are limited by context
+	ea.mu.Lock()
+	ea.remaining, ea.resetAt = 0, time.Now().Add(time.Minute)
+	ea.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := ea.ResumeIP(ctx, net.ParseIP("192.0.2.3")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context deadline while waiting for window", err)
+	}
+}
+
+func TestExternalApiFailMapping(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := strings.TrimPrefix(r.URL.Path, "/json/")
+
+		switch query {
+		case "203.0.113.5":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "203.0.113.9":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			json.NewEncoder(w).Encode(ipApiAnswer(query))
+		}
+	}))
+	defer srv.Close()
+
+	ea := NewExternalApiEndpoint(srv.URL, time.Second)
+
+	tests := []struct {
+		ip      string
+		target  error
+		code    models.ErrorCode
+		message string
+	}{
+		{ip: "10.1.2.3", target: ErrApiPrivateRange, code: models.CodeUnknown, message: "private range"},
+		{ip: "240.0.0.1", target: ErrApiReservedRange, code: models.CodeUnknown, message: "reserved range"},
+		{ip: "203.0.113.5", code: models.CodeUnknown, message: "500 Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		_, err := ea.ResumeIP(context.Background(), net.ParseIP(tt.ip))
+
+		var fail *ApiFailError
+		if !errors.As(err, &fail) {
+			t.Fatalf("%s: got %v, want ApiFailError", tt.ip, err)
+		}
+		if fail.Message != tt.message {
+			t.Errorf("%s: message %q, want %q", tt.ip, fail.Message, tt.message)
+		}
+		if tt.target != nil && !errors.Is(err, tt.target) {
+			t.Errorf("%s: %v doesn't match %v", tt.ip, err, tt.target)
+		}
+		if code := models.ErrorCodeOf(err); code != tt.code {
+			t.Errorf("%s: code %s, want %s", tt.ip, code, tt.code)
+		}
+	}
+
+	// 429 without X-Rl/X-Ttl waits the default window: stop it by context, then check retries exhaust
+	ea.mu.Lock()
+	ea.resetAt = time.Time{}
+	ea.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := ea.ResumeIP(ctx, net.ParseIP("203.0.113.9")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context deadline while waiting for window", err)
+	}
+
+	if err := (&ApiFailError{Message: "rate limited"}); models.ErrorCodeOf(err) != models.CodeRateLimited {
+		t.Errorf("rate limited fail code %s, want %s", models.ErrorCodeOf(err), models.CodeRateLimited)
+	}
+
+	if _, err := ea.ResumeIP(context.Background(), nil); err == nil {
+		t.Error("nil IP: error expected")
+	}
+}
@@ -0,0 +1,477 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	"github.com/eterline/micro-utils/pkg/mailsec"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// mtastsMinMaxAge - policies living less than a day are refetched too often (RFC 8461 3.2 recommends weeks)
+	mtastsMinMaxAge = 86400
+	// mtastsFetchTimeout - policy download timeout if lookup timeout is not set
+	mtastsFetchTimeout = 15 * time.Second
+)
+
+/*
+MailSecCheck - email security posture checks over resolver
+
+	Every DNS lookup goes through resolver, including address of MTA-STS policy host.
+	DKIM selectors can't be listed, so common ones and selectors set by user are probed.
+*/
+type MailSecCheck struct {
+	rv         models.Resolver
+	client     *http.Client
+	selectors  []string
+	lookupTime time.Duration
+}
+
+func NewMailSecCheck(rv models.Resolver) *MailSecCheck {
+	mc := &MailSecCheck{
+		rv:        rv,
+		selectors: slices.Clone(mailsec.DKIMSelectors),
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = nil
+	tr.DialContext = mc.dial
+
+	mc.client = &http.Client{Transport: tr}
+	return mc
+}
+
+// SetDKIMSelectors - probe selectors in addition to common ones
+func (mc *MailSecCheck) SetDKIMSelectors(selectors []string) {
+	for _, s := range selectors {
+		if s = strings.TrimSpace(s); s != "" && !slices.Contains(mc.selectors, s) {
+			mc.selectors = append(mc.selectors, s)
+		}
+	}
+}
+
+// SetLookupTimeout - set deadline of every single DNS lookup and policy download. Disabled if d <= 0
+func (mc *MailSecCheck) SetLookupTimeout(d time.Duration) {
+	mc.lookupTime = d
+}
+
+// dial - connect to host resolved with checker resolver
+func (mc *MailSecCheck) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := mc.rv.ResolveIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		d    net.Dialer
+		errs []error
+	)
+
+	for _, ip := range ips {
+		conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no addresses resolved for %s", host)
+	}
+	return nil, errors.Join(errs...)
+}
+
+/*
+CheckEmail - SPF, DMARC, DKIM, MTA-STS and TLS-RPT of domain
+
+	Checks run at once, findings are ordered by check. Lookup failures are fail findings:
+	receivers can't evaluate policy either.
+*/
+func (mc *MailSecCheck) CheckEmail(ctx context.Context, domain string) models.EmailPosture {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+
+	var (
+		posture = models.EmailPosture{Status: models.FindingPass}
+		wg      = &sync.WaitGroup{}
+
+		spf, dmarc, dkim, mtasts, tlsrpt []models.EmailFinding
+	)
+
+	wg.Go(func() { posture.SPF, spf = mc.checkSPF(ctx, domain) })
+	wg.Go(func() { posture.DMARC, dmarc = mc.checkDMARC(ctx, domain) })
+	wg.Go(func() { posture.DKIM, dkim = mc.checkDKIM(ctx, domain) })
+	wg.Go(func() { posture.MX, posture.MTASTS, mtasts = mc.checkMTASTS(ctx, domain) })
+	wg.Go(func() { posture.TLSRPT, tlsrpt = mc.checkTLSRPT(ctx, domain) })
+	wg.Wait()
+
+	posture.Findings = slices.Concat(spf, dmarc, dkim, mtasts, tlsrpt)
+	for _, f := range posture.Findings {
+		posture.Status = posture.Status.Worse(f.Status)
+	}
+
+	return posture
+}
+
+// checkFinding - finding constructor of check
+func checkFinding(check string) func(status models.FindingStatus, format string, args ...any) models.EmailFinding {
+	return func(status models.FindingStatus, format string, args ...any) models.EmailFinding {
+		return models.EmailFinding{
+			Check:   check,
+			Status:  status,
+			Message: fmt.Sprintf(format, args...),
+		}
+	}
+}
+
+/*
+txt - TXT records of name, character strings of record are joined (RFC 7208 3.3)
+
+	NXDOMAIN and NODATA are not errors: no records and void is set.
+*/
+func (mc *MailSecCheck) txt(ctx context.Context, name string) (txts []string, void bool, err error) {
+	lookupCtx, cancel := withTimeout(ctx, mc.lookupTime)
+	defer cancel()
+
+	records, err := mc.rv.ResolveRecords(lookupCtx, name, "TXT")
+	if err != nil {
+		switch models.ErrorCodeOf(err) {
+		case models.CodeNXDOMAIN, models.CodeNODATA:
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+
+	for _, rec := range records {
+		if rec.Type == "TXT" {
+			txts = append(txts, strings.Join(rec.TXT, ""))
+		}
+	}
+
+	return txts, len(txts) == 0, nil
+}
+
+func filterTXT(txts []string, match func(string) bool) []string {
+	var res []string
+	for _, txt := range txts {
+		if match(txt) {
+			res = append(res, txt)
+		}
+	}
+	return res
+}
+
+/*
+checkDMARC - DMARC policy of domain
+
+	If domain has no record, policy of organizational domain is applied (RFC 7489 6.6.3):
+	its subdomain policy for domain.
+*/
+func (mc *MailSecCheck) checkDMARC(ctx context.Context, domain string) (*models.DMARCReport, []models.EmailFinding) {
+	finding := checkFinding("dmarc")
+
+	lookup := func(name string) ([]string, error) {
+		txts, _, err := mc.txt(ctx, "_dmarc."+name)
+		return filterTXT(txts, mailsec.IsDMARC), err
+	}
+
+	var (
+		policyDomain = domain
+		inherited    bool
+	)
+
+	records, err := lookup(domain)
+	if err == nil && len(records) == 0 {
+		if org, orgErr := publicsuffix.EffectiveTLDPlusOne(domain); orgErr == nil && org != domain {
+			policyDomain, inherited = org, true
+			records, err = lookup(org)
+		}
+	}
+
+	switch {
+	case err != nil:
+		return nil, []models.EmailFinding{finding(models.FindingFail, "TXT lookup failed: %v", err)}
+	case len(records) == 0:
+		return nil, []models.EmailFinding{finding(models.FindingFail, "no DMARC record, spoofed mail is not rejected")}
+	case len(records) > 1:
+		return nil, []models.EmailFinding{finding(models.FindingFail, "%d DMARC records, receivers ignore them", len(records))}
+	}
+
+	report := &models.DMARCReport{Record: records[0], Domain: policyDomain}
+
+	rec, err := mailsec.ParseDMARC(records[0])
+	if err != nil {
+		return report, []models.EmailFinding{finding(models.FindingFail, "invalid record: %v", err)}
+	}
+
+	report.Policy, report.SubdomainPolicy = rec.Policy, rec.SubdomainPolicy
+	report.Percent, report.RUA, report.RUF = rec.Percent, rec.AggregateURIs, rec.ForensicURIs
+
+	var (
+		findings []models.EmailFinding
+		policy   = rec.Policy
+		source   = ""
+	)
+
+	if inherited {
+		policy, source = rec.SubdomainPolicy, " inherited from "+policyDomain
+	}
+
+	if policy == "none" {
+		findings = append(findings, finding(models.FindingWarn, "policy none%s only monitors, spoofed mail is delivered", source))
+	} else {
+		findings = append(findings, finding(models.FindingPass, "policy %s%s", policy, source))
+	}
+
+	if rec.Percent < 100 {
+		findings = append(findings, finding(models.FindingWarn, "policy applies to %d%% of failed mail", rec.Percent))
+	}
+
+	if len(rec.AggregateURIs) == 0 {
+		findings = append(findings, finding(models.FindingWarn, "no aggregate reports address (rua)"))
+	}
+
+	return report, findings
+}
+
+// checkDKIM - probe selectors for DKIM keys at once
+func (mc *MailSecCheck) checkDKIM(ctx context.Context, domain string) ([]models.DKIMReport, []models.EmailFinding) {
+	type probe struct {
+		records []string
+		err     error
+	}
+
+	var (
+		finding = checkFinding("dkim")
+		probes  = make([]probe, len(mc.selectors))
+		wg      = &sync.WaitGroup{}
+	)
+
+	for i, sel := range mc.selectors {
+		wg.Go(func() {
+			txts, _, err := mc.txt(ctx, mailsec.DKIMName(sel, domain))
+			probes[i] = probe{records: txts, err: err}
+		})
+	}
+	wg.Wait()
+
+	var (
+		reports  []models.DKIMReport
+		findings []models.EmailFinding
+		failed   int
+	)
+
+	for i, sel := range mc.selectors {
+		if probes[i].err != nil {
+			failed++
+			continue
+		}
+
+		for _, txt := range probes[i].records {
+			key, err := mailsec.ParseDKIM(txt)
+			if err != nil {
+				findings = append(findings, finding(models.FindingFail, "selector %s: invalid key record: %v", sel, err))
+				continue
+			}
+
+			reports = append(reports, models.DKIMReport{
+				Selector: sel,
+				KeyType:  key.KeyType,
+				Bits:     key.Bits,
+				Revoked:  key.Revoked,
+				Testing:  key.Testing,
+			})
+
+			switch {
+			case key.Revoked:
+				findings = append(findings, finding(models.FindingWarn, "selector %s: key is revoked", sel))
+				continue
+			case key.KeyType == "rsa" && key.Bits < 1024:
+				findings = append(findings, finding(models.FindingFail, "selector %s: %d-bit RSA key is breakable", sel, key.Bits))
+			case key.KeyType == "rsa" && key.Bits < 2048:
+				findings = append(findings, finding(models.FindingWarn, "selector %s: %d-bit RSA key, 2048 bits recommended", sel, key.Bits))
+			default:
+				findings = append(findings, finding(models.FindingPass, "selector %s: %d-bit %s key", sel, key.Bits, key.KeyType))
+			}
+
+			if key.Testing {
+				findings = append(findings, finding(models.FindingWarn, "selector %s: testing mode (t=y)", sel))
+			}
+		}
+	}
+
+	switch {
+	case len(findings) > 0:
+	case failed == len(mc.selectors):
+		findings = append(findings, finding(models.FindingFail, "TXT lookups of all %d selectors failed", failed))
+	default:
+		findings = append(findings, finding(models.FindingWarn,
+			"no DKIM key found for %d probed selectors, set known selectors to probe them", len(mc.selectors)))
+	}
+
+	return reports, findings
+}
+
+// mxHosts - MX hosts of domain, null MX (RFC 7505) is skipped
+func (mc *MailSecCheck) mxHosts(ctx context.Context, domain string) ([]string, error) {
+	lookupCtx, cancel := withTimeout(ctx, mc.lookupTime)
+	defer cancel()
+
+	records, err := mc.rv.ResolveRecords(lookupCtx, domain, "MX")
+	if err != nil {
+		switch models.ErrorCodeOf(err) {
+		case models.CodeNXDOMAIN, models.CodeNODATA:
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var hosts []string
+	for _, rec := range records {
+		if rec.MX == nil {
+			continue
+		}
+		if host := strings.ToLower(strings.TrimSuffix(rec.MX.Exchange, ".")); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	slices.Sort(hosts)
+	return slices.Compact(hosts), nil
+}
+
+/*
+checkMTASTS - MTA-STS record, policy and MX hosts of domain
+
+	In enforce mode every MX host must match policy, otherwise senders can't deliver mail.
+*/
+func (mc *MailSecCheck) checkMTASTS(ctx context.Context, domain string) ([]string, *models.MTASTSReport, []models.EmailFinding) {
+	finding := checkFinding("mta-sts")
+
+	var (
+		mx    []string
+		mxErr error
+		wg    = &sync.WaitGroup{}
+	)
+
+	wg.Go(func() { mx, mxErr = mc.mxHosts(ctx, domain) })
+
+	txts, _, err := mc.txt(ctx, "_mta-sts."+domain)
+	records := filterTXT(txts, mailsec.IsMTASTS)
+	wg.Wait()
+
+	switch {
+	case err != nil:
+		return mx, nil, []models.EmailFinding{finding(models.FindingFail, "TXT lookup failed: %v", err)}
+	case len(records) == 0:
+		return mx, nil, []models.EmailFinding{finding(models.FindingWarn, "no MTA-STS record, SMTP TLS can be downgraded")}
+	case len(records) > 1:
+		return mx, nil, []models.EmailFinding{finding(models.FindingFail, "%d MTA-STS records, senders ignore policy", len(records))}
+	}
+
+	report := &models.MTASTSReport{Record: records[0]}
+
+	rec, err := mailsec.ParseMTASTSRecord(records[0])
+	if err != nil {
+		return mx, report, []models.EmailFinding{finding(models.FindingFail, "invalid record: %v", err)}
+	}
+	report.ID = rec.ID
+
+	fetchTime := mc.lookupTime
+	if fetchTime <= 0 {
+		fetchTime = mtastsFetchTimeout
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTime)
+	policy, err := mailsec.FetchMTASTSPolicy(fetchCtx, mc.client, domain)
+	cancel()
+
+	if err != nil {
+		report.Err = err.Error()
+		return mx, report, []models.EmailFinding{finding(models.FindingFail, "policy fetch failed: %v", err)}
+	}
+
+	report.Mode, report.MX, report.MaxAge = policy.Mode, policy.MX, policy.MaxAge
+
+	var findings []models.EmailFinding
+
+	switch policy.Mode {
+	case "enforce":
+		findings = append(findings, finding(models.FindingPass, "policy mode enforce"))
+	case "testing":
+		findings = append(findings, finding(models.FindingWarn, "policy mode testing, failures are only reported"))
+	default:
+		findings = append(findings, finding(models.FindingWarn, "policy mode none, policy is withdrawn"))
+	}
+
+	if policy.Mode != "none" {
+		var unmatched []string
+		for _, host := range mx {
+			if !policy.MatchMX(host) {
+				unmatched = append(unmatched, host)
+			}
+		}
+
+		switch {
+		case mxErr != nil:
+			findings = append(findings, finding(models.FindingFail, "MX lookup failed: %v", mxErr))
+		case len(unmatched) > 0 && policy.Mode == "enforce":
+			findings = append(findings, finding(models.FindingFail,
+				"MX hosts are not allowed by policy, mail to them is not delivered: %s", strings.Join(unmatched, ", ")))
+		case len(unmatched) > 0:
+			findings = append(findings, finding(models.FindingWarn,
+				"MX hosts are not allowed by policy: %s", strings.Join(unmatched, ", ")))
+		}
+	}
+
+	if policy.MaxAge < mtastsMinMaxAge {
+		findings = append(findings, finding(models.FindingWarn,
+			"max_age %d is less than a day, weeks are recommended", policy.MaxAge))
+	}
+
+	return mx, report, findings
+}
+
+// checkTLSRPT - SMTP TLS reporting record of domain
+func (mc *MailSecCheck) checkTLSRPT(ctx context.Context, domain string) (*models.TLSRPTReport, []models.EmailFinding) {
+	finding := checkFinding("tls-rpt")
+
+	txts, _, err := mc.txt(ctx, "_smtp._tls."+domain)
+	records := filterTXT(txts, mailsec.IsTLSRPT)
+
+	switch {
+	case err != nil:
+		return nil, []models.EmailFinding{finding(models.FindingFail, "TXT lookup failed: %v", err)}
+	case len(records) == 0:
+		return nil, []models.EmailFinding{finding(models.FindingWarn, "no TLS-RPT record, SMTP TLS failures are not reported")}
+	case len(records) > 1:
+		return nil, []models.EmailFinding{finding(models.FindingFail, "%d TLS-RPT records, senders ignore them", len(records))}
+	}
+
+	report := &models.TLSRPTReport{Record: records[0]}
+
+	rec, err := mailsec.ParseTLSRPT(records[0])
+	if err != nil {
+		return report, []models.EmailFinding{finding(models.FindingFail, "invalid record: %v", err)}
+	}
+	report.RUA = rec.URIs
+
+	return report, []models.EmailFinding{finding(models.FindingPass, "reports to %s", strings.Join(rec.URIs, ", "))}
+}
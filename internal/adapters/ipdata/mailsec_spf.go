@@ -0,0 +1,196 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eterline/micro-utils/internal/models"
+	"github.com/eterline/micro-utils/pkg/mailsec"
+)
+
+/*
+spfWalk - expansion of SPF include and redirect tree with lookup counting
+
+	Lookups count every DNS querying term of evaluated records (RFC 7208 4.6.4), void lookups -
+	includes and redirects without records. Policy is "all" of root record or of record reached
+	by redirect chain: only those records give final result.
+*/
+type spfWalk struct {
+	mc      *MailSecCheck
+	lookups int
+	void    int
+	ptr     bool
+	policy  *mailsec.SPFTerm
+	path    map[string]bool
+	errs    []string
+}
+
+// expand - count lookups of record terms and expand its includes and redirect
+func (w *spfWalk) expand(ctx context.Context, rec mailsec.SPFRecord, policy bool, depth int) []models.SPFInclude {
+	var (
+		includes []models.SPFInclude
+		all, ok  = rec.All()
+	)
+
+	if policy && ok {
+		w.policy = &all
+	}
+
+	for _, t := range rec.Terms {
+		// redirect is ignored if record has "all" (RFC 7208 6.1)
+		if t.Modifier && t.Name == "redirect" && ok {
+			continue
+		}
+
+		if t.Lookup() {
+			w.lookups++
+		}
+
+		if !t.Modifier && t.Name == "ptr" {
+			w.ptr = true
+		}
+
+		switch {
+		case !t.Modifier && t.Name == "include":
+			includes = append(includes, w.include(ctx, t.Target(), false, depth))
+		case t.Modifier && t.Name == "redirect":
+			includes = append(includes, w.include(ctx, t.Target(), policy, depth))
+		}
+	}
+
+	return includes
+}
+
+// include - included or redirected record. Errors make receivers return permerror or temperror
+func (w *spfWalk) include(ctx context.Context, domain string, policy bool, depth int) models.SPFInclude {
+	var (
+		inc = models.SPFInclude{Domain: domain}
+		key = strings.ToLower(strings.TrimSuffix(domain, "."))
+	)
+
+	switch {
+	case mailsec.HasMacro(domain):
+		inc.Err = "domain with macros can't be expanded"
+		return inc
+	case w.path[key]:
+		inc.Err = "include loop"
+		w.errs = append(w.errs, fmt.Sprintf("%s: include loop", domain))
+		return inc
+	case depth > mailsec.SPFLookupLimit:
+		inc.Err = "include depth limit exceeded"
+		return inc
+	}
+
+	w.path[key] = true
+	defer delete(w.path, key)
+
+	txts, void, err := w.mc.txt(ctx, domain)
+	if err != nil {
+		inc.Err = err.Error()
+		w.errs = append(w.errs, fmt.Sprintf("%s: lookup failed: %v", domain, err))
+		return inc
+	}
+
+	if void {
+		w.void++
+	}
+
+	spf := filterTXT(txts, mailsec.IsSPF)
+	switch {
+	case len(spf) == 0:
+		inc.Err = "no SPF record"
+	case len(spf) > 1:
+		inc.Err = fmt.Sprintf("%d SPF records", len(spf))
+	}
+
+	if inc.Err != "" {
+		w.errs = append(w.errs, fmt.Sprintf("%s: %s", domain, inc.Err))
+		return inc
+	}
+
+	inc.Record = spf[0]
+
+	rec, err := mailsec.ParseSPF(spf[0])
+	if err != nil {
+		inc.Err = err.Error()
+		w.errs = append(w.errs, fmt.Sprintf("%s: %v", domain, err))
+		return inc
+	}
+
+	inc.Includes = w.expand(ctx, rec, policy, depth+1)
+	return inc
+}
+
+// checkSPF - SPF record of domain with expanded include tree
+func (mc *MailSecCheck) checkSPF(ctx context.Context, domain string) (*models.SPFReport, []models.EmailFinding) {
+	finding := checkFinding("spf")
+
+	txts, _, err := mc.txt(ctx, domain)
+	if err != nil {
+		return nil, []models.EmailFinding{finding(models.FindingFail, "TXT lookup failed: %v", err)}
+	}
+
+	spf := filterTXT(txts, mailsec.IsSPF)
+	switch {
+	case len(spf) == 0:
+		return nil, []models.EmailFinding{finding(models.FindingFail, "no SPF record, any host can send mail as %s", domain)}
+	case len(spf) > 1:
+		return nil, []models.EmailFinding{finding(models.FindingFail, "%d SPF records, receivers return permerror", len(spf))}
+	}
+
+	report := &models.SPFReport{Record: spf[0]}
+
+	rec, err := mailsec.ParseSPF(spf[0])
+	if err != nil {
+		return report, []models.EmailFinding{finding(models.FindingFail, "invalid record: %v", err)}
+	}
+
+	walk := &spfWalk{
+		mc:   mc,
+		path: map[string]bool{strings.ToLower(strings.TrimSuffix(domain, ".")): true},
+	}
+
+	report.Includes = walk.expand(ctx, rec, true, 1)
+	report.Lookups, report.VoidLookups = walk.lookups, walk.void
+
+	var findings []models.EmailFinding
+
+	for _, e := range walk.errs {
+		findings = append(findings, finding(models.FindingFail, "broken include %s", e))
+	}
+
+	if walk.lookups > mailsec.SPFLookupLimit {
+		findings = append(findings, finding(models.FindingFail,
+			"%d DNS lookups, limit is %d: receivers return permerror", walk.lookups, mailsec.SPFLookupLimit))
+	} else {
+		findings = append(findings, finding(models.FindingPass,
+			"%d of %d DNS lookups", walk.lookups, mailsec.SPFLookupLimit))
+	}
+
+	if walk.void > mailsec.SPFVoidLookupLimit {
+		findings = append(findings, finding(models.FindingWarn,
+			"%d void lookups, limit is %d", walk.void, mailsec.SPFVoidLookupLimit))
+	}
+
+	if walk.ptr {
+		findings = append(findings, finding(models.FindingWarn, "ptr mechanism is slow and deprecated (RFC 7208 5.5)"))
+	}
+
+	switch {
+	case walk.policy == nil:
+		findings = append(findings, finding(models.FindingWarn, "no all mechanism, unlisted senders get neutral result"))
+	case walk.policy.Qualifier == "+":
+		findings = append(findings, finding(models.FindingFail, "+all permits any sender"))
+	case walk.policy.Qualifier == "?":
+		findings = append(findings, finding(models.FindingWarn, "?all gives neutral result to unlisted senders"))
+	default:
+		findings = append(findings, finding(models.FindingPass, "%s rejects or marks unlisted senders", walk.policy))
+	}
+
+	return report, findings
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// txtResolver - TXT records by name: nil slice is name without TXT (NODATA), missing name is NXDOMAIN
+type txtResolver struct {
+	txt   map[string][]string
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (r *txtResolver) ResolveRecords(ctx context.Context, name, rtype string) ([]models.DnsRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.calls == nil {
+		r.calls = map[string]int{}
+	}
+	r.calls[name]++
+
+	txts, ok := r.txt[name]
+	switch {
+	case !ok:
+		return nil, nxdomainErr(name, rtype, 0)
+	case len(txts) == 0:
+		return nil, noRecordsErr(name, rtype)
+	}
+
+	var records []models.DnsRecord
+	for _, txt := range txts {
+		records = append(records, models.DnsRecord{Name: name, Type: "TXT", TXT: []string{txt}})
+	}
+	return records, nil
+}
+
+func (r *txtResolver) ResolveIP(ctx context.Context, name string) ([]net.IP, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *txtResolver) ResolveNS(ctx context.Context, name string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *txtResolver) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestCheckSPF(t *testing.T) {
+	tests := []struct {
+		name     string
+		txt      map[string][]string
+		lookups  int
+		void     int
+		findings []string
+		// unqueried - names that must not be looked up
+		unqueried []string
+	}{
+		{
+			name: "lookups within limit",
+			txt: map[string][]string{
+				"example.test": {"v=spf1 include:one.test include:two.test -all"},
+				"one.test":     {"v=spf1 a mx exists:x.test a:b.test -all"},
+				"two.test":     {"v=spf1 mx:a.test mx:b.test ip4:192.0.2.0/24 -all"},
+			},
+			lookups:  8,
+			findings: []string{"pass: 8 of 10 DNS lookups", "pass: -all rejects"},
+		},
+		{
+			name: "lookup limit exceeded",
+			txt: map[string][]string{
+				"example.test": {"v=spf1 include:one.test include:two.test ~all"},
+				"one.test":     {"v=spf1 a mx ptr exists:x.test a:b.test -all"},
+				"two.test":     {"v=spf1 mx:a.test mx:b.test a:c.test a:d.test -all"},
+			},
+			lookups:  11,
+			findings: []string{"fail: 11 DNS lookups, limit is 10", "warn: ptr mechanism", "pass: ~all rejects"},
+		},
+		{
+			name: "void lookups",
+			txt: map[string][]string{
+				"example.test": {"v=spf1 include:gone1.test include:gone2.test include:empty.test -all"},
+				"empty.test":   nil,
+			},
+			lookups: 3,
+			void:    3,
+			findings: []string{
+				"fail: broken include gone1.test: no SPF record",
+				"fail: broken include gone2.test: no SPF record",
+				"fail: broken include empty.test: no SPF record",
+				"warn: 3 void lookups, limit is 2",
+			},
+		},
+		{
+			name: "include loop",
+			txt: map[string][]string{
+				"example.test": {"v=spf1 include:a.test -all"},
+				"a.test":       {"v=spf1 include:b.test ~all"},
+				"b.test":       {"v=spf1 include:Example.test. ~all"},
+			},
+			lookups:  3,
+			findings: []string{"fail: broken include Example.test.: include loop"},
+		},
+		{
+			name: "redirect is ignored with all",
+			txt: map[string][]string{
+				"example.test":      {"v=spf1 ip4:192.0.2.1 -all redirect=_spf.example.test"},
+				"_spf.example.test": {"v=spf1 +all"},
+			},
+			lookups:   0,
+			findings:  []string{"pass: 0 of 10 DNS lookups", "pass: -all rejects"},
+			unqueried: []string{"_spf.example.test"},
+		},
+		{
+			name: "redirect policy",
+			txt: map[string][]string{
+				"example.test":      {"v=spf1 ip4:192.0.2.1 redirect=_spf.example.test"},
+				"_spf.example.test": {"v=spf1 include:other.test ?all"},
+				"other.test":        {"v=spf1 +all"},
+			},
+			lookups: 2,
+			// +all of include doesn't make policy, ?all of redirect target does
+			findings: []string{"pass: 2 of 10 DNS lookups", "warn: ?all gives neutral result"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rv := &txtResolver{txt: tt.txt}
+
+			report, findings := NewMailSecCheck(rv).checkSPF(context.Background(), "example.test")
+			if report == nil {
+				t.Fatalf("no report: %v", findings)
+			}
+			if report.Lookups != tt.lookups || report.VoidLookups != tt.void {
+				t.Errorf("got %d lookups and %d void, want %d and %d", report.Lookups, report.VoidLookups, tt.lookups, tt.void)
+			}
+
+			var got []string
+			for _, f := range findings {
+				got = append(got, string(f.Status)+": "+f.Message)
+			}
+
+			for _, want := range tt.findings {
+				found := false
+				for _, g := range got {
+					found = found || strings.HasPrefix(g, want)
+				}
+				if !found {
+					t.Errorf("no finding %q in %q", want, got)
+				}
+			}
+
+			for _, name := range tt.unqueried {
+				if rv.calls[name] != 0 {
+					t.Errorf("%s is queried", name)
+				}
+			}
+		})
+	}
+}
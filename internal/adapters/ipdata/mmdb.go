@@ -0,0 +1,165 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+const mmdbLang = "en"
+
+// mmdbRecord - common fields of GeoLite2/DB-IP City, Country and ASN databases
+type mmdbRecord struct {
+	Continent struct {
+		Code  string            `maxminddb:"code"`
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"continent"`
+
+	Country struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+
+	Subdivisions []struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+		TimeZone  string  `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+
+	Traits struct {
+		IsAnonymousProxy bool `maxminddb:"is_anonymous_proxy"`
+	} `maxminddb:"traits"`
+
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+/*
+IpInfoMMDB - offline IP resumer over local MaxMind format databases
+
+	Supports GeoLite2 City/Country/ASN and DB-IP lite databases.
+	Several databases are combined: e.g. City for location and ASN for network owner.
+*/
+type IpInfoMMDB struct {
+	readers []*maxminddb.Reader
+}
+
+// NewIpInfoMMDB - open .mmdb database files
+func NewIpInfoMMDB(paths ...string) (*IpInfoMMDB, error) {
+	if len(paths) < 1 {
+		return nil, errors.New("no mmdb database files provided")
+	}
+
+	self := &IpInfoMMDB{
+		readers: make([]*maxminddb.Reader, 0, len(paths)),
+	}
+
+	for _, path := range paths {
+		rd, err := maxminddb.Open(path)
+		if err != nil {
+			self.Close()
+			return nil, fmt.Errorf("failed to open mmdb database %s: %w", path, err)
+		}
+		self.readers = append(self.readers, rd)
+	}
+
+	return self, nil
+}
+
+// Close - closes all databases
+func (d *IpInfoMMDB) Close() error {
+	var errs []error
+	for _, rd := range d.readers {
+		if err := rd.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *IpInfoMMDB) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	if err := ctx.Err(); err != nil {
+		return models.AboutIPobject{}, err
+	}
+
+	if ip == nil {
+		return models.AboutIPobject{}, errors.New("ip is nil")
+	}
+
+	var (
+		rec   mmdbRecord
+		found bool
+	)
+
+	// every database fills only own fields of the record
+	for _, rd := range d.readers {
+		_, ok, err := rd.LookupNetwork(ip, &rec)
+		if err != nil {
+			return models.AboutIPobject{}, fmt.Errorf("mmdb lookup %s failed: %w", ip, err)
+		}
+		found = found || ok
+	}
+
+	if !found {
+		return models.AboutIPobject{}, fmt.Errorf("ip %s not found in mmdb databases", ip)
+	}
+
+	return rec.aboutIP(), nil
+}
+
+func (rec mmdbRecord) aboutIP() models.AboutIPobject {
+	obj := models.AboutIPobject{
+		Status:        "success",
+		Continent:     rec.Continent.Names[mmdbLang],
+		ContinentCode: rec.Continent.Code,
+		Country:       rec.Country.Names[mmdbLang],
+		CountryCode:   rec.Country.IsoCode,
+		City:          rec.City.Names[mmdbLang],
+		Zip:           rec.Postal.Code,
+		Lat:           rec.Location.Latitude,
+		Lon:           rec.Location.Longitude,
+		Timezone:      rec.Location.TimeZone,
+		Isp:           rec.AutonomousSystemOrganization,
+		Org:           rec.AutonomousSystemOrganization,
+		Asname:        rec.AutonomousSystemOrganization,
+		Proxy:         rec.Traits.IsAnonymousProxy,
+		RequestTime:   time.Now(),
+	}
+
+	if len(rec.Subdivisions) > 0 {
+		obj.Region = rec.Subdivisions[0].IsoCode
+		obj.RegionName = rec.Subdivisions[0].Names[mmdbLang]
+	}
+
+	if rec.AutonomousSystemNumber > 0 {
+		obj.As = fmt.Sprintf("AS%d %s", rec.AutonomousSystemNumber, rec.AutonomousSystemOrganization)
+	}
+
+	if loc, err := time.LoadLocation(obj.Timezone); err == nil && obj.Timezone != "" {
+		_, obj.Offset = time.Now().In(loc).Zone()
+	}
+
+	return obj
+}
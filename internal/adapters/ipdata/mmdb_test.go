@@ -0,0 +1,179 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+// writeMMDB - database fixture with records by network
+func writeMMDB(t *testing.T, dbType string, records map[string]mmdbtype.Map) string {
+	t.Helper()
+
+	w, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: dbType, RecordSize: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for cidr, rec := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Insert(network, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), dbType+".mmdb")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := w.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func names(en string) mmdbtype.Map {
+	return mmdbtype.Map{"names": mmdbtype.Map{"en": mmdbtype.String(en)}}
+}
+
+func cityFixture(t *testing.T) string {
+	return writeMMDB(t, "GeoLite2-City", map[string]mmdbtype.Map{
+		"8.8.8.0/24": {
+			"continent": mmdbtype.Map{"code": mmdbtype.String("NA"), "names": mmdbtype.Map{"en": mmdbtype.String("North America")}},
+			"country":   mmdbtype.Map{"iso_code": mmdbtype.String("US"), "names": mmdbtype.Map{"en": mmdbtype.String("United States")}},
+			"subdivisions": mmdbtype.Slice{
+				mmdbtype.Map{"iso_code": mmdbtype.String("CA"), "names": mmdbtype.Map{"en": mmdbtype.String("California")}},
+			},
+			"city":     names("Mountain View"),
+			"postal":   mmdbtype.Map{"code": mmdbtype.String("94043")},
+			"location": mmdbtype.Map{"latitude": mmdbtype.Float64(37.4), "longitude": mmdbtype.Float64(-122.1), "time_zone": mmdbtype.String("America/Los_Angeles")},
+			"traits":   mmdbtype.Map{"is_anonymous_proxy": mmdbtype.Bool(true)},
+		},
+		"2a00:1450::/32": {
+			"country": mmdbtype.Map{"iso_code": mmdbtype.String("DE"), "names": mmdbtype.Map{"en": mmdbtype.String("Germany")}},
+		},
+	})
+}
+
+func asnFixture(t *testing.T) string {
+	return writeMMDB(t, "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"8.8.8.0/24": {
+			"autonomous_system_number":       mmdbtype.Uint32(15169),
+			"autonomous_system_organization": mmdbtype.String("GOOGLE"),
+		},
+		"1.1.1.0/24": {
+			"autonomous_system_number":       mmdbtype.Uint32(13335),
+			"autonomous_system_organization": mmdbtype.String("CLOUDFLARENET"),
+		},
+	})
+}
+
+func TestIpInfoMMDBCombinesDatabases(t *testing.T) {
+	db, err := NewIpInfoMMDB(cityFixture(t), asnFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	obj, err := db.ResumeIP(context.Background(), net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string][2]string{
+		"status":       {obj.Status, "success"},
+		"continent":    {obj.ContinentCode, "NA"},
+		"country":      {obj.Country, "United States"},
+		"country_code": {obj.CountryCode, "US"},
+		"region":       {obj.Region, "CA"},
+		"region_name":  {obj.RegionName, "California"},
+		"city":         {obj.City, "Mountain View"},
+		"zip":          {obj.Zip, "94043"},
+		"timezone":     {obj.Timezone, "America/Los_Angeles"},
+		"as":           {obj.As, "AS15169 GOOGLE"},
+		"org":          {obj.Org, "GOOGLE"},
+	}
+	for field, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("%s = %q, want %q", field, c[0], c[1])
+		}
+	}
+
+	if obj.Lat != 37.4 || obj.Lon != -122.1 {
+		t.Errorf("location = %v,%v, want 37.4,-122.1", obj.Lat, obj.Lon)
+	}
+	if !obj.Proxy {
+		t.Error("proxy trait is lost")
+	}
+}
+
+func TestIpInfoMMDBPartialMatch(t *testing.T) {
+	db, err := NewIpInfoMMDB(cityFixture(t), asnFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// only ASN database knows the network
+	obj, err := db.ResumeIP(context.Background(), net.ParseIP("1.1.1.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.As != "AS13335 CLOUDFLARENET" || obj.Country != "" {
+		t.Errorf("got as %q country %q, want ASN fields only", obj.As, obj.Country)
+	}
+
+	obj, err = db.ResumeIP(context.Background(), net.ParseIP("2a00:1450::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.CountryCode != "DE" || obj.As != "" {
+		t.Errorf("got country %q as %q, want City fields only", obj.CountryCode, obj.As)
+	}
+}
+
+func TestIpInfoMMDBErrors(t *testing.T) {
+	if _, err := NewIpInfoMMDB(); err == nil {
+		t.Error("no databases: error expected")
+	}
+
+	if _, err := NewIpInfoMMDB(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("missing file: error expected")
+	}
+
+	db, err := NewIpInfoMMDB(cityFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ResumeIP(context.Background(), net.ParseIP("192.0.2.1")); err == nil {
+		t.Error("unknown IP: error expected")
+	}
+
+	if _, err := db.ResumeIP(context.Background(), nil); err == nil {
+		t.Error("nil IP: error expected")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.ResumeIP(ctx, net.ParseIP("8.8.8.8")); err != context.Canceled {
+		t.Errorf("canceled ctx: got %v, want context.Canceled", err)
+	}
+}
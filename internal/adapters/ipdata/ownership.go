@@ -0,0 +1,60 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"net"
+
+	"github.com/eterline/micro-utils/internal/models"
+	"github.com/eterline/micro-utils/pkg/rdap"
+)
+
+// RDAPOwnerLookup - RDAP (with WHOIS fallback) ownership lookup adapter
+type RDAPOwnerLookup struct {
+	cl *rdap.Client
+}
+
+func NewRDAPOwnerLookup() *RDAPOwnerLookup {
+	return &RDAPOwnerLookup{
+		cl: rdap.NewClient(),
+	}
+}
+
+func (ol *RDAPOwnerLookup) LookupIP(ctx context.Context, ip net.IP) (models.Ownership, error) {
+	rec, err := ol.cl.QueryIP(ctx, ip)
+	if err != nil {
+		return models.Ownership{}, err
+	}
+	return ownership(rec), nil
+}
+
+func (ol *RDAPOwnerLookup) LookupDomain(ctx context.Context, domain string) (models.Ownership, error) {
+	rec, err := ol.cl.QueryDomain(ctx, domain)
+	if err != nil {
+		return models.Ownership{}, err
+	}
+	return ownership(rec), nil
+}
+
+func ownership(rec rdap.Record) models.Ownership {
+	return models.Ownership{
+		Source:       string(rec.Source),
+		Server:       rec.Server,
+		Handle:       rec.Handle,
+		Name:         rec.Name,
+		Org:          rec.Org,
+		Country:      rec.Country,
+		StartAddress: rec.StartAddress,
+		EndAddress:   rec.EndAddress,
+		CIDR:         rec.CIDR,
+		AbuseEmail:   rec.AbuseEmail,
+		AbusePhone:   rec.AbusePhone,
+		Registrar:    rec.Registrar,
+		Registered:   rec.Registered,
+		LastChanged:  rec.LastChanged,
+		Expires:      rec.Expires,
+	}
+}
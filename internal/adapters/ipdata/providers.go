@@ -0,0 +1,280 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+	"github.com/eterline/micro-utils/pkg/rdap"
+)
+
+const (
+	ipinfoEndpoint = "https://ipinfo.io"
+	ipwhoEndpoint  = "https://ipwho.is"
+)
+
+// getJSON - GET request limited by timeout with JSON decoding of response body. Secrets go in header: api is shown in errors
+func getJSON(ctx context.Context, client *http.Client, timeout time.Duration, api string, header http.Header, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%s: %w", resp.Status, ErrApiRateLimited)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request %s failed: %s", api, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func providerTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return ipApiDefaultTimeout
+	}
+	return timeout
+}
+
+// =======================================
+
+// IpInfoIpinfo - ipinfo.io IP resumer. Token is optional for free tier
+type IpInfoIpinfo struct {
+	client   *http.Client
+	endpoint string
+	token    string
+	timeout  time.Duration
+}
+
+// NewIpinfoApi - ipinfo.io resumer. If timeout <= 0, 10s per request is used
+func NewIpinfoApi(token string, timeout time.Duration) *IpInfoIpinfo {
+	return &IpInfoIpinfo{
+		client:   &http.Client{},
+		endpoint: ipinfoEndpoint,
+		token:    token,
+		timeout:  providerTimeout(timeout),
+	}
+}
+
+type ipinfoResponse struct {
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	Bogon    bool   `json:"bogon"`
+	City     string `json:"city"`
+	Region   string `json:"region"`
+	Country  string `json:"country"`
+	Loc      string `json:"loc"`
+	Org      string `json:"org"`
+	Postal   string `json:"postal"`
+	Timezone string `json:"timezone"`
+	Privacy  struct {
+		Vpn     bool `json:"vpn"`
+		Proxy   bool `json:"proxy"`
+		Tor     bool `json:"tor"`
+		Hosting bool `json:"hosting"`
+	} `json:"privacy"`
+	Error *struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (ea *IpInfoIpinfo) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	if ip == nil {
+		return models.AboutIPobject{}, errors.New("ip is nil")
+	}
+
+	api := fmt.Sprintf("%s/%s/json", ea.endpoint, ip.String())
+	header := http.Header{}
+	if ea.token != "" {
+		header.Set("Authorization", "Bearer "+ea.token)
+	}
+
+	var res ipinfoResponse
+	if err := getJSON(ctx, ea.client, ea.timeout, api, header, &res); err != nil {
+		return models.AboutIPobject{}, err
+	}
+
+	if res.Error != nil {
+		return models.AboutIPobject{}, fmt.Errorf("ipinfo request for %s failed: %s", ip, res.Error.Message)
+	}
+
+	if res.Bogon {
+		return models.AboutIPobject{}, &ApiFailError{Query: res.IP, Message: "reserved range"}
+	}
+
+	obj := models.AboutIPobject{
+		Status:      "success",
+		CountryCode: res.Country,
+		RegionName:  res.Region,
+		City:        res.City,
+		Zip:         res.Postal,
+		Timezone:    res.Timezone,
+		As:          res.Org,
+		Reverse:     res.Hostname,
+		Proxy:       res.Privacy.Proxy || res.Privacy.Vpn || res.Privacy.Tor,
+		Hosting:     res.Privacy.Hosting,
+		RequestTime: time.Now(),
+	}
+
+	// "org": "AS15169 Google LLC"
+	if asn, org, ok := strings.Cut(res.Org, " "); ok && strings.HasPrefix(asn, "AS") {
+		obj.Org = org
+	}
+
+	if lat, lon, ok := strings.Cut(res.Loc, ","); ok {
+		obj.Lat, _ = strconv.ParseFloat(lat, 64)
+		obj.Lon, _ = strconv.ParseFloat(lon, 64)
+	}
+
+	return obj, nil
+}
+
+// =======================================
+
+// IpInfoIpwho - ipwho.is IP resumer
+type IpInfoIpwho struct {
+	client   *http.Client
+	endpoint string
+	timeout  time.Duration
+}
+
+// NewIpwhoApi - ipwho.is resumer. If timeout <= 0, 10s per request is used
+func NewIpwhoApi(timeout time.Duration) *IpInfoIpwho {
+	return &IpInfoIpwho{
+		client:   &http.Client{},
+		endpoint: ipwhoEndpoint,
+		timeout:  providerTimeout(timeout),
+	}
+}
+
+type ipwhoResponse struct {
+	IP            string  `json:"ip"`
+	Success       bool    `json:"success"`
+	Message       string  `json:"message"`
+	Continent     string  `json:"continent"`
+	ContinentCode string  `json:"continent_code"`
+	Country       string  `json:"country"`
+	CountryCode   string  `json:"country_code"`
+	Region        string  `json:"region"`
+	RegionCode    string  `json:"region_code"`
+	City          string  `json:"city"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	Postal        string  `json:"postal"`
+	Connection    struct {
+		Asn int    `json:"asn"`
+		Org string `json:"org"`
+		Isp string `json:"isp"`
+	} `json:"connection"`
+	Timezone struct {
+		ID     string `json:"id"`
+		Offset int    `json:"offset"`
+	} `json:"timezone"`
+}
+
+func (ea *IpInfoIpwho) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	if ip == nil {
+		return models.AboutIPobject{}, errors.New("ip is nil")
+	}
+
+	api := fmt.Sprintf("%s/%s", ea.endpoint, ip.String())
+
+	var res ipwhoResponse
+	if err := getJSON(ctx, ea.client, ea.timeout, api, nil, &res); err != nil {
+		return models.AboutIPobject{}, err
+	}
+
+	if !res.Success {
+		return models.AboutIPobject{}, &ApiFailError{Query: res.IP, Message: res.Message}
+	}
+
+	obj := models.AboutIPobject{
+		Status:        "success",
+		Continent:     res.Continent,
+		ContinentCode: res.ContinentCode,
+		Country:       res.Country,
+		CountryCode:   res.CountryCode,
+		Region:        res.RegionCode,
+		RegionName:    res.Region,
+		City:          res.City,
+		Zip:           res.Postal,
+		Lat:           res.Latitude,
+		Lon:           res.Longitude,
+		Timezone:      res.Timezone.ID,
+		Offset:        res.Timezone.Offset,
+		Isp:           res.Connection.Isp,
+		Org:           res.Connection.Org,
+		RequestTime:   time.Now(),
+	}
+
+	if res.Connection.Asn > 0 {
+		obj.As = fmt.Sprintf("AS%d %s", res.Connection.Asn, res.Connection.Org)
+	}
+
+	return obj, nil
+}
+
+// =======================================
+
+// IpInfoRDAP - RDAP IP network registration resumer. Fills network owner fields only
+type IpInfoRDAP struct {
+	cl      *rdap.Client
+	timeout time.Duration
+}
+
+// NewRDAPApi - RDAP resumer with IANA bootstrap servers discovery. If timeout <= 0, 10s per request is used
+func NewRDAPApi(timeout time.Duration) *IpInfoRDAP {
+	return &IpInfoRDAP{
+		cl:      rdap.NewClient(),
+		timeout: providerTimeout(timeout),
+	}
+}
+
+func (ea *IpInfoRDAP) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	if ip == nil {
+		return models.AboutIPobject{}, errors.New("ip is nil")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ea.timeout)
+	defer cancel()
+
+	rec, err := ea.cl.QueryIP(ctx, ip)
+	if err != nil {
+		return models.AboutIPobject{}, err
+	}
+
+	return models.AboutIPobject{
+		Status:      "success",
+		CountryCode: rec.Country,
+		Isp:         rec.Name,
+		Org:         rec.Org,
+		RequestTime: time.Now(),
+	}, nil
+}
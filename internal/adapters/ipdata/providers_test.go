@@ -0,0 +1,58 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIpinfoToken(t *testing.T) {
+	const token = "secret-token-42"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "" {
+			http.Error(w, "query string: "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "no token", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/192.0.2.1/json" {
+			http.Error(w, "broken", http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"ip": "198.51.100.1", "country": "DE", "org": "AS64500 Example"})
+	}))
+	defer srv.Close()
+
+	ea := NewIpinfoApi(token, time.Second)
+	ea.endpoint = srv.URL
+
+	obj, err := ea.ResumeIP(context.Background(), net.ParseIP("198.51.100.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.CountryCode != "DE" {
+		t.Fatalf("got %+v", obj)
+	}
+
+	// status and transport errors keep the token out of messages
+	if _, err := ea.ResumeIP(context.Background(), net.ParseIP("192.0.2.1")); err == nil || strings.Contains(err.Error(), token) {
+		t.Fatalf("status error %v, want error without token", err)
+	}
+
+	ea.endpoint = "http://127.0.0.1:1"
+	if _, err := ea.ResumeIP(context.Background(), net.ParseIP("198.51.100.1")); err == nil || strings.Contains(err.Error(), token) {
+		t.Fatalf("transport error %v, want error without token", err)
+	}
+}
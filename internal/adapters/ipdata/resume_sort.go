@@ -4,40 +4,101 @@
 
 package ipdata
 
-import "github.com/eterline/micro-utils/internal/models"
+import (
+	"net"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
 
 type ResumeInfo struct {
-	ResolveDurationMs int64                  `json:"resolve_duration_ms" yaml:"resolve_duration_ms"`
-	Resumes           []models.ResumeAboutIP `json:"resumes,omitempty" yaml:"resumes,omitempty"`
-	NameServers       []string               `json:"ns,omitempty" yaml:"ns,omitempty"`
-	ErrorIPs          string                 `json:"ip_error,omitempty" yaml:"ip_error,omitempty"`
-	ErrorNS           string                 `json:"ns_error,omitempty" yaml:"ns_error,omitempty"`
+	ResolveDurationMs int64                         `json:"resolve_duration_ms" yaml:"resolve_duration_ms"`
+	Resumes           []models.ResumeAboutIP        `json:"resumes,omitempty" yaml:"resumes,omitempty"`
+	NameServers       []string                      `json:"ns,omitempty" yaml:"ns,omitempty"`
+	ErrorIPs          string                        `json:"ip_error,omitempty" yaml:"ip_error,omitempty"`
+	ErrorIPsCode      models.ErrorCode              `json:"ip_error_code,omitempty" yaml:"ip_error_code,omitempty"`
+	ErrorNS           string                        `json:"ns_error,omitempty" yaml:"ns_error,omitempty"`
+	ErrorNSCode       models.ErrorCode              `json:"ns_error_code,omitempty" yaml:"ns_error_code,omitempty"`
+	Owner             *models.Ownership             `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Records           map[string][]models.DnsRecord `json:"records,omitempty" yaml:"records,omitempty"`
+	ErrorRecords      map[string]string             `json:"records_error,omitempty" yaml:"records_error,omitempty"`
+	ErrorRecordsCode  map[string]models.ErrorCode   `json:"records_error_code,omitempty" yaml:"records_error_code,omitempty"`
+	Chain             *models.CnameChain            `json:"chain,omitempty" yaml:"chain,omitempty"`
+	Trace             *models.DnsTrace              `json:"trace,omitempty" yaml:"trace,omitempty"`
+	Subnets           map[string]SubnetResumeInfo   `json:"subnets,omitempty" yaml:"subnets,omitempty"`
+	Dnssec            *models.DnssecStatus          `json:"dnssec,omitempty" yaml:"dnssec,omitempty"`
+	Transfer          *models.ZoneTransfer          `json:"axfr,omitempty" yaml:"axfr,omitempty"`
+}
+
+// SubnetResumeInfo - answer for client subnet with resumes of its IPs
+type SubnetResumeInfo struct {
+	Scope        *uint8                 `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Resumes      []models.ResumeAboutIP `json:"resumes,omitempty" yaml:"resumes,omitempty"`
+	ErrorIPs     string                 `json:"ip_error,omitempty" yaml:"ip_error,omitempty"`
+	ErrorIPsCode models.ErrorCode       `json:"ip_error_code,omitempty" yaml:"ip_error_code,omitempty"`
+}
+
+// NamedResumeInfo - ResumeInfo with its name, single line of streamed output
+type NamedResumeInfo struct {
+	Name       string `json:"name" yaml:"name"`
+	ResumeInfo `yaml:",inline"`
 }
 
 func SortResolvedAndResume(res map[string]models.AboutResolve, rsvl []models.ResumeAboutIP) map[string]ResumeInfo {
 	result := make(map[string]ResumeInfo)
 
 	for key, resolve := range res {
-		info := ResumeInfo{
-			ResolveDurationMs: resolve.ResolveDurationMs,
-			NameServers:       resolve.NameServers,
-			ErrorIPs:          resolve.ErrorIPs,
-			ErrorNS:           resolve.ErrorNS,
-		}
+		result[key] = ResumeInfoOf(resolve, rsvl)
+	}
+
+	return result
+}
 
-		var matched []models.ResumeAboutIP
-		for _, resume := range rsvl {
-			for _, ip := range resolve.IPs {
-				if resume.RequestIP.Equal(ip) {
-					matched = append(matched, resume)
-					break
-				}
+// ResumeInfoOf - resolve info with resumes of its IPs
+func ResumeInfoOf(resolve models.AboutResolve, rsvl []models.ResumeAboutIP) ResumeInfo {
+	info := ResumeInfo{
+		ResolveDurationMs: resolve.ResolveDurationMs,
+		NameServers:       resolve.NameServers,
+		ErrorIPs:          resolve.ErrorIPs,
+		ErrorIPsCode:      resolve.ErrorIPsCode,
+		ErrorNS:           resolve.ErrorNS,
+		ErrorNSCode:       resolve.ErrorNSCode,
+		Owner:             resolve.Owner,
+		Records:           resolve.Records,
+		ErrorRecords:      resolve.ErrorRecords,
+		ErrorRecordsCode:  resolve.ErrorRecordsCode,
+		Chain:             resolve.Chain,
+		Trace:             resolve.Trace,
+		Dnssec:            resolve.Dnssec,
+		Transfer:          resolve.Transfer,
+	}
+
+	info.Resumes = resumesOf(resolve.IPs, rsvl)
+
+	if len(resolve.Subnets) > 0 {
+		info.Subnets = make(map[string]SubnetResumeInfo, len(resolve.Subnets))
+		for subnet, ans := range resolve.Subnets {
+			info.Subnets[subnet] = SubnetResumeInfo{
+				Scope:        ans.Scope,
+				Resumes:      resumesOf(ans.IPs, rsvl),
+				ErrorIPs:     ans.ErrorIPs,
+				ErrorIPsCode: ans.ErrorIPsCode,
 			}
 		}
-		info.Resumes = matched
-
-		result[key] = info
 	}
 
-	return result
+	return info
+}
+
+// resumesOf - resumes of ips
+func resumesOf(ips []net.IP, rsvl []models.ResumeAboutIP) []models.ResumeAboutIP {
+	var matched []models.ResumeAboutIP
+	for _, resume := range rsvl {
+		for _, ip := range ips {
+			if resume.RequestIP.Equal(ip) {
+				matched = append(matched, resume)
+				break
+			}
+		}
+	}
+	return matched
 }
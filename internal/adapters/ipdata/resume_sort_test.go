@@ -0,0 +1,67 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"net"
+	"testing"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+func resumeOf(ip, country string) models.ResumeAboutIP {
+	return models.ResumeAboutIP{RequestIP: net.ParseIP(ip), Resume: models.AboutIPobject{CountryCode: country}}
+}
+
+func TestSortResolvedAndResume(t *testing.T) {
+	var (
+		scope   = uint8(24)
+		resumes = []models.ResumeAboutIP{
+			resumeOf("192.0.2.1", "US"),
+			resumeOf("198.51.100.1", "DE"),
+			resumeOf("2001:db8::1", "NL"),
+		}
+		resolves = map[string]models.AboutResolve{
+			"www.example.test": {
+				IPs: []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+				Subnets: map[string]models.SubnetAnswer{
+					"203.0.113.0/24": {IPs: []net.IP{net.ParseIP("198.51.100.1")}, Scope: &scope},
+					"2001:db8::/56":  {ErrorIPs: "timeout", ErrorIPsCode: models.CodeTimeout},
+				},
+			},
+			"gone.example.test": {ErrorIPs: "no such host", ErrorIPsCode: models.CodeNXDOMAIN},
+		}
+	)
+
+	sorted := SortResolvedAndResume(resolves, resumes)
+	if len(sorted) != 2 {
+		t.Fatalf("got %d names, want 2", len(sorted))
+	}
+
+	countries := func(rs []models.ResumeAboutIP) (codes []string) {
+		for _, r := range rs {
+			codes = append(codes, r.Resume.CountryCode)
+		}
+		return codes
+	}
+
+	www := sorted["www.example.test"]
+	if got := countries(www.Resumes); len(got) != 2 || got[0] != "US" || got[1] != "NL" {
+		t.Errorf("got resumes %v, want US and NL of resolved IPs", got)
+	}
+
+	// every subnet gets resumes of its own IPs
+	if sub := www.Subnets["203.0.113.0/24"]; len(sub.Resumes) != 1 || sub.Resumes[0].Resume.CountryCode != "DE" || sub.Scope == nil || *sub.Scope != 24 {
+		t.Errorf("got subnet %+v, want DE resume with scope 24", sub)
+	}
+	if sub := www.Subnets["2001:db8::/56"]; len(sub.Resumes) != 0 || sub.ErrorIPsCode != models.CodeTimeout || sub.Scope != nil {
+		t.Errorf("got subnet %+v, want timeout without resumes", sub)
+	}
+
+	gone := sorted["gone.example.test"]
+	if len(gone.Resumes) != 0 || gone.Subnets != nil || gone.ErrorIPsCode != models.CodeNXDOMAIN {
+		t.Errorf("got %+v, want nxdomain without resumes", gone)
+	}
+}
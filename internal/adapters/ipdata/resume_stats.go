@@ -0,0 +1,154 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// statUnknown - value of IPs without resumed field
+const statUnknown = "unknown"
+
+/*
+ResumeStats - aggregate statistics collector of lookup results
+
+	Results are added one by one, so streamed batches are counted without keeping them.
+	Only counters and IP to names index are kept.
+*/
+type ResumeStats struct {
+	top     int
+	names   int
+	failed  int
+	ipNames map[string][]string
+	ipv6    int
+	unknown int
+	hosting int
+	proxy   int
+	mobile  int
+	country map[string]int
+	asn     map[string]int
+	org     map[string]int
+	errCode map[string]int
+}
+
+// NewResumeStats - collector with top entries of country, ASN and org lists (all if top <= 0)
+func NewResumeStats(top int) *ResumeStats {
+	return &ResumeStats{
+		top:     top,
+		ipNames: map[string][]string{},
+		country: map[string]int{},
+		asn:     map[string]int{},
+		org:     map[string]int{},
+		errCode: map[string]int{},
+	}
+}
+
+// Add - count resume info of name. Not safe for concurrent use
+func (s *ResumeStats) Add(name string, info ResumeInfo) {
+	s.names++
+	if len(info.Resumes) == 0 {
+		s.failed++
+		s.errCode[statValue(string(info.ErrorIPsCode))]++
+	}
+
+	for _, resume := range info.Resumes {
+		ip := resume.RequestIP.String()
+
+		names, seen := s.ipNames[ip]
+		if !slices.Contains(names, name) {
+			s.ipNames[ip] = append(names, name)
+		}
+		if seen {
+			continue
+		}
+
+		if resume.RequestIP.To4() == nil {
+			s.ipv6++
+		}
+
+		if resume.Err != "" {
+			s.unknown++
+		}
+
+		obj := resume.Resume
+		asn, _ := splitAS(obj.As)
+
+		s.country[statValue(obj.Country)]++
+		s.asn[statValue(asn)]++
+		s.org[statValue(obj.Org)]++
+
+		if obj.Hosting {
+			s.hosting++
+		}
+		if obj.Proxy {
+			s.proxy++
+		}
+		if obj.Mobile {
+			s.mobile++
+		}
+	}
+}
+
+// Report - statistics of all added results
+func (s *ResumeStats) Report() models.BatchStats {
+	report := models.BatchStats{
+		Names:       s.names,
+		FailedNames: s.failed,
+		IPs:         len(s.ipNames),
+		IPv6:        s.ipv6,
+		Unresumed:   s.unknown,
+		Hosting:     s.hosting,
+		Proxy:       s.proxy,
+		Mobile:      s.mobile,
+		Countries:   s.topOf(s.country),
+		ASNs:        s.topOf(s.asn),
+		Orgs:        s.topOf(s.org),
+		ErrorCodes:  s.topOf(s.errCode),
+	}
+
+	if report.IPs > 0 {
+		report.IPv6Share = math.Round(float64(s.ipv6)/float64(report.IPs)*1000) / 1000
+	}
+
+	for ip, names := range s.ipNames {
+		if len(names) > 1 {
+			report.SharedIPs = append(report.SharedIPs, models.SharedIP{IP: ip, Names: slices.Sorted(slices.Values(names))})
+		}
+	}
+
+	slices.SortFunc(report.SharedIPs, func(a, b models.SharedIP) int {
+		return cmp.Or(cmp.Compare(len(b.Names), len(a.Names)), cmp.Compare(a.IP, b.IP))
+	})
+
+	return report
+}
+
+// topOf - counts in descending order, equal counts by value
+func (s *ResumeStats) topOf(counts map[string]int) []models.StatCount {
+	list := make([]models.StatCount, 0, len(counts))
+	for value, count := range counts {
+		list = append(list, models.StatCount{Value: value, Count: count})
+	}
+
+	slices.SortFunc(list, func(a, b models.StatCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value))
+	})
+
+	if s.top > 0 && len(list) > s.top {
+		list = list[:s.top]
+	}
+	return list
+}
+
+func statValue(s string) string {
+	if s == "" {
+		return statUnknown
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// statsBatch - names with shared, IPv6, failed and unresumed IPs
+func statsBatch() map[string]ResumeInfo {
+	var (
+		cloud = models.ResumeAboutIP{
+			RequestIP: net.ParseIP("192.0.2.1"),
+			Resume:    models.AboutIPobject{Country: "US", As: "AS64500 Cloud", Org: "Cloud", Hosting: true},
+		}
+		v6 = models.ResumeAboutIP{
+			RequestIP: net.ParseIP("2001:db8::1"),
+			Resume:    models.AboutIPobject{Country: "DE", As: "AS64501", Proxy: true, Mobile: true},
+		}
+		limited = models.ResumeAboutIP{RequestIP: net.ParseIP("198.51.100.1"), Err: "rate limited", ErrCode: models.CodeRateLimited}
+		other   = models.ResumeAboutIP{
+			RequestIP: net.ParseIP("203.0.113.1"),
+			Resume:    models.AboutIPobject{Country: "US", As: "AS64500 Cloud", Org: "Cloud"},
+		}
+	)
+
+	return map[string]ResumeInfo{
+		"a.test": {Resumes: []models.ResumeAboutIP{cloud, v6}},
+		"b.test": {Resumes: []models.ResumeAboutIP{cloud, limited}},
+		"c.test": {ErrorIPs: "no such host", ErrorIPsCode: models.CodeNXDOMAIN},
+		"d.test": {ErrorIPs: "boom"},
+		"e.test": {Resumes: []models.ResumeAboutIP{other, cloud}},
+	}
+}
+
+func stat(value string, count int) models.StatCount {
+	return models.StatCount{Value: value, Count: count}
+}
+
+func TestResumeStats(t *testing.T) {
+	tests := []struct {
+		name string
+		top  int
+		want models.BatchStats
+	}{
+		{
+			name: "all",
+			want: models.BatchStats{
+				Names: 5, FailedNames: 2, IPs: 4, IPv6: 1, IPv6Share: 0.25,
+				Unresumed: 1, Hosting: 1, Proxy: 1, Mobile: 1,
+				// equal counts are sorted by value
+				Countries:  []models.StatCount{stat("US", 2), stat("DE", 1), stat("unknown", 1)},
+				ASNs:       []models.StatCount{stat("AS64500", 2), stat("AS64501", 1), stat("unknown", 1)},
+				Orgs:       []models.StatCount{stat("Cloud", 2), stat("unknown", 2)},
+				ErrorCodes: []models.StatCount{stat("nxdomain", 1), stat("unknown", 1)},
+				SharedIPs:  []models.SharedIP{{IP: "192.0.2.1", Names: []string{"a.test", "b.test", "e.test"}}},
+			},
+		},
+		{
+			name: "top",
+			top:  1,
+			want: models.BatchStats{
+				Names: 5, FailedNames: 2, IPs: 4, IPv6: 1, IPv6Share: 0.25,
+				Unresumed: 1, Hosting: 1, Proxy: 1, Mobile: 1,
+				Countries:  []models.StatCount{stat("US", 2)},
+				ASNs:       []models.StatCount{stat("AS64500", 2)},
+				Orgs:       []models.StatCount{stat("Cloud", 2)},
+				ErrorCodes: []models.StatCount{stat("nxdomain", 1)},
+				SharedIPs:  []models.SharedIP{{IP: "192.0.2.1", Names: []string{"a.test", "b.test", "e.test"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		stats := NewResumeStats(tt.top)
+		// order of streamed names doesn't change report
+		for name, info := range statsBatch() {
+			stats.Add(name, info)
+		}
+
+		if got := stats.Report(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResumeStatsEmpty(t *testing.T) {
+	report := NewResumeStats(10).Report()
+
+	if report.Names != 0 || report.IPs != 0 || report.IPv6Share != 0 || len(report.Countries) != 0 || report.SharedIPs != nil {
+		t.Fatalf("got %+v, want empty report", report)
+	}
+	// empty lists, not null of JSON output
+	if report.Countries == nil || report.ASNs == nil || report.Orgs == nil {
+		t.Fatalf("got nil lists: %+v", report)
+	}
+}
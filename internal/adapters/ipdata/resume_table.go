@@ -0,0 +1,335 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// DefaultResumeColumns - columns of table and CSV outputs if they are not set
+var DefaultResumeColumns = []string{"name", "ip", "country", "asn", "org", "hosting", "proxy"}
+
+/*
+ResumeRow - flat view of single resumed IP of name: row of table, CSV and template outputs
+
+	Name without resolved IPs makes single row with empty IP and resolve error.
+	Answers for client subnets follow answer of name with Subnet set.
+*/
+type ResumeRow struct {
+	Name        string
+	Subnet      string
+	IP          string
+	Country     string
+	CountryCode string
+	Region      string
+	City        string
+	ASN         string
+	ASName      string
+	Org         string
+	ISP         string
+	Hosting     bool
+	Proxy       bool
+	Mobile      bool
+	Cached      bool
+	PTR         string
+	NameServers []string
+	Error       string
+	ErrorCode   models.ErrorCode
+	Dnssec      models.DnssecState
+	// AXFR - open | closed, empty if zone transfer wasn't tried
+	AXFR string
+
+	// Resume - whole IP info object for templates
+	Resume models.AboutIPobject
+}
+
+// resumeColumns - column values by column name
+var resumeColumns = map[string]func(r ResumeRow) string{
+	"name":         func(r ResumeRow) string { return r.Name },
+	"subnet":       func(r ResumeRow) string { return r.Subnet },
+	"ip":           func(r ResumeRow) string { return r.IP },
+	"country":      func(r ResumeRow) string { return r.Country },
+	"country_code": func(r ResumeRow) string { return r.CountryCode },
+	"region":       func(r ResumeRow) string { return r.Region },
+	"city":         func(r ResumeRow) string { return r.City },
+	"asn":          func(r ResumeRow) string { return r.ASN },
+	"as_name":      func(r ResumeRow) string { return r.ASName },
+	"org":          func(r ResumeRow) string { return r.Org },
+	"isp":          func(r ResumeRow) string { return r.ISP },
+	"hosting":      func(r ResumeRow) string { return strconv.FormatBool(r.Hosting) },
+	"proxy":        func(r ResumeRow) string { return strconv.FormatBool(r.Proxy) },
+	"mobile":       func(r ResumeRow) string { return strconv.FormatBool(r.Mobile) },
+	"cached":       func(r ResumeRow) string { return strconv.FormatBool(r.Cached) },
+	"ptr":          func(r ResumeRow) string { return r.PTR },
+	"ns":           func(r ResumeRow) string { return strings.Join(r.NameServers, " ") },
+	"error":        func(r ResumeRow) string { return r.Error },
+	"error_code":   func(r ResumeRow) string { return string(r.ErrorCode) },
+	"dnssec":       func(r ResumeRow) string { return string(r.Dnssec) },
+	"axfr":         func(r ResumeRow) string { return r.AXFR },
+}
+
+// CheckResumeColumns - check that every column is known
+func CheckResumeColumns(columns []string) error {
+	for _, col := range columns {
+		if _, ok := resumeColumns[col]; !ok {
+			return fmt.Errorf("unknown output column: %s", col)
+		}
+	}
+	return nil
+}
+
+// Column - value of row column by name. Empty for unknown column
+func (r ResumeRow) Column(name string) string {
+	if value, ok := resumeColumns[name]; ok {
+		return value(r)
+	}
+	return ""
+}
+
+// ResumeRows - flatten resume info of name: row per resumed IP, client subnets are sorted
+func ResumeRows(name string, info ResumeInfo) []ResumeRow {
+	rows := resumeRows(name, "", info.Resumes, info.NameServers, info.ErrorIPs, info.ErrorIPsCode)
+
+	for _, subnet := range slices.Sorted(maps.Keys(info.Subnets)) {
+		ans := info.Subnets[subnet]
+		rows = append(rows, resumeRows(name, subnet, ans.Resumes, info.NameServers, ans.ErrorIPs, ans.ErrorIPsCode)...)
+	}
+
+	if info.Dnssec != nil {
+		for i := range rows {
+			rows[i].Dnssec = info.Dnssec.State
+		}
+	}
+
+	if info.Transfer != nil {
+		axfr := "closed"
+		if info.Transfer.Open {
+			axfr = "open"
+		}
+		for i := range rows {
+			rows[i].AXFR = axfr
+		}
+	}
+
+	return rows
+}
+
+// resumeRows - rows of resumes answered for subnet (empty for usual answer). Single row with error if there are no resumes
+func resumeRows(
+	name, subnet string, resumes []models.ResumeAboutIP, ns []string, errIPs string, errCode models.ErrorCode,
+) []ResumeRow {
+	if len(resumes) == 0 {
+		return []ResumeRow{{
+			Name:        name,
+			Subnet:      subnet,
+			NameServers: ns,
+			Error:       errIPs,
+			ErrorCode:   errCode,
+		}}
+	}
+
+	rows := make([]ResumeRow, 0, len(resumes))
+
+	for _, resume := range resumes {
+		obj := resume.Resume
+		asn, asName := splitAS(obj.As)
+		if obj.Asname != "" {
+			asName = obj.Asname
+		}
+
+		row := ResumeRow{
+			Name:        name,
+			Subnet:      subnet,
+			IP:          resume.RequestIP.String(),
+			Country:     obj.Country,
+			CountryCode: obj.CountryCode,
+			Region:      obj.RegionName,
+			City:        obj.City,
+			ASN:         asn,
+			ASName:      asName,
+			Org:         obj.Org,
+			ISP:         obj.Isp,
+			Hosting:     obj.Hosting,
+			Proxy:       obj.Proxy,
+			Mobile:      obj.Mobile,
+			Cached:      resume.Cached,
+			NameServers: ns,
+			Error:       resume.Err,
+			ErrorCode:   resume.ErrCode,
+			Resume:      obj,
+		}
+
+		if resume.PTR != nil && len(resume.PTR.Names) > 0 {
+			row.PTR = strings.Join(resume.PTR.Names, " ")
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// splitAS - "AS15169 Google LLC" to "AS15169" and "Google LLC"
+func splitAS(as string) (string, string) {
+	num, name, _ := strings.Cut(strings.TrimSpace(as), " ")
+	if !strings.HasPrefix(strings.ToUpper(num), "AS") {
+		return "", as
+	}
+	return num, name
+}
+
+// =======================================
+
+// ResumeRowWriter - writer of flat resume rows. Flush must be called after last row
+type ResumeRowWriter interface {
+	Write(rows ...ResumeRow) error
+	Flush() error
+}
+
+/*
+NewResumeRowWriter - row writer of output format
+
+	table    - aligned columns with header for terminals, written out on Flush
+	csv, tsv - header row and row per IP
+	template - Go text/template executed for every ResumeRow, new line is added after each
+*/
+func NewResumeRowWriter(w io.Writer, format string, columns []string, tmpl string) (ResumeRowWriter, error) {
+	if len(columns) == 0 {
+		columns = DefaultResumeColumns
+	}
+
+	if err := CheckResumeColumns(columns); err != nil {
+		return nil, err
+	}
+
+	switch format {
+
+	case "table":
+		return &tableRowWriter{
+			tw:      tabwriter.NewWriter(w, 0, 0, 2, ' ', 0),
+			columns: columns,
+		}, nil
+
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		return &csvRowWriter{cw: cw, columns: columns}, nil
+
+	case "template":
+		if tmpl == "" {
+			return nil, fmt.Errorf("template output requires template text")
+		}
+		t, err := template.New("row").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output template: %w", err)
+		}
+		return &templateRowWriter{w: w, t: t}, nil
+	}
+
+	return nil, fmt.Errorf("unknown output format: %s", format)
+}
+
+type tableRowWriter struct {
+	tw      *tabwriter.Writer
+	columns []string
+	header  bool
+}
+
+func (t *tableRowWriter) Write(rows ...ResumeRow) error {
+	if !t.header {
+		t.header = true
+		if _, err := fmt.Fprintln(t.tw, strings.ToUpper(strings.Join(t.columns, "\t"))); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		values := make([]string, len(t.columns))
+		for i, col := range t.columns {
+			// tabs and new lines break alignment
+			values[i] = strings.Join(strings.Fields(row.Column(col)), " ")
+			if values[i] == "" {
+				values[i] = "-"
+			}
+		}
+
+		if _, err := fmt.Fprintln(t.tw, strings.Join(values, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tableRowWriter) Flush() error {
+	return t.tw.Flush()
+}
+
+type csvRowWriter struct {
+	cw      *csv.Writer
+	columns []string
+	header  bool
+}
+
+func (c *csvRowWriter) Write(rows ...ResumeRow) error {
+	if !c.header {
+		c.header = true
+		if err := c.cw.Write(c.columns); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		values := make([]string, len(c.columns))
+		for i, col := range c.columns {
+			values[i] = row.Column(col)
+		}
+
+		if err := c.cw.Write(values); err != nil {
+			return err
+		}
+	}
+
+	// rows are streamed as they come
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.cw.Flush()
+	return c.cw.Error()
+}
+
+type templateRowWriter struct {
+	w io.Writer
+	t *template.Template
+}
+
+func (t *templateRowWriter) Write(rows ...ResumeRow) error {
+	for _, row := range rows {
+		if err := t.t.Execute(t.w, row); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(t.w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *templateRowWriter) Flush() error {
+	return nil
+}
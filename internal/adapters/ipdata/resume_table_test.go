@@ -0,0 +1,195 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// tableInfo - resumed name with two IPs, failed and resumed client subnets
+func tableInfo() ResumeInfo {
+	return ResumeInfo{
+		NameServers: []string{"ns1.example.test", "ns2.example.test"},
+		Resumes: []models.ResumeAboutIP{
+			{
+				RequestIP: net.ParseIP("192.0.2.1"),
+				Resume: models.AboutIPobject{
+					Country: "United States", CountryCode: "US", City: "Ashburn",
+					As: "AS64500 Example Cloud, Inc.", Org: "Example\tCloud", Hosting: true,
+				},
+				PTR: &models.ReverseDNS{Names: []string{"a.example.test.", "b.example.test."}},
+			},
+			{
+				RequestIP: net.ParseIP("2001:db8::1"),
+				Resume:    models.AboutIPobject{CountryCode: "DE", As: "AS64501", Asname: "EXAMPLE-DE"},
+				Cached:    true,
+				Err:       "rate limited", ErrCode: models.CodeRateLimited,
+			},
+		},
+		Subnets: map[string]SubnetResumeInfo{
+			"203.0.113.0/24":  {Resumes: []models.ResumeAboutIP{{RequestIP: net.ParseIP("198.51.100.1"), Resume: models.AboutIPobject{CountryCode: "NL"}}}},
+			"198.51.100.0/24": {ErrorIPs: "timeout", ErrorIPsCode: models.CodeTimeout},
+		},
+		Dnssec:   &models.DnssecStatus{State: models.DnssecSecure},
+		Transfer: &models.ZoneTransfer{Open: false},
+	}
+}
+
+func TestResumeRows(t *testing.T) {
+	rows := ResumeRows("example.test", tableInfo())
+
+	type view struct{ subnet, ip, code, asn, asName, ptr string }
+	var got []view
+	for _, r := range rows {
+		got = append(got, view{r.Subnet, r.IP, string(r.ErrorCode), r.ASN, r.ASName, r.PTR})
+
+		if r.Name != "example.test" || r.Dnssec != models.DnssecSecure || r.AXFR != "closed" || len(r.NameServers) != 2 {
+			t.Errorf("row %+v misses name, dnssec, axfr or nameservers", r)
+		}
+	}
+
+	// answer of name goes first, then sorted subnets
+	want := []view{
+		{"", "192.0.2.1", "", "AS64500", "Example Cloud, Inc.", "a.example.test. b.example.test."},
+		{"", "2001:db8::1", "rate_limited", "AS64501", "EXAMPLE-DE", ""},
+		{"198.51.100.0/24", "", "timeout", "", "", ""},
+		{"203.0.113.0/24", "198.51.100.1", "", "", "", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got rows\n%+v\nwant\n%+v", got, want)
+	}
+
+	// name without IPs is single error row
+	rows = ResumeRows("gone.test", ResumeInfo{ErrorIPs: "no such host", ErrorIPsCode: models.CodeNXDOMAIN})
+	if len(rows) != 1 || rows[0].IP != "" || rows[0].ErrorCode != models.CodeNXDOMAIN || rows[0].AXFR != "" || rows[0].Dnssec != "" {
+		t.Errorf("got %+v, want single nxdomain row", rows)
+	}
+}
+
+func TestSplitAS(t *testing.T) {
+	tests := []struct{ as, num, name string }{
+		{"AS15169 Google LLC", "AS15169", "Google LLC"},
+		{" as64500 Example ", "as64500", "Example"},
+		{"AS64500", "AS64500", ""},
+		{"Google LLC", "", "Google LLC"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		if num, name := splitAS(tt.as); num != tt.num || name != tt.name {
+			t.Errorf("%q: got %q %q, want %q %q", tt.as, num, name, tt.num, tt.name)
+		}
+	}
+}
+
+func TestResumeRowWriter(t *testing.T) {
+	columns := []string{"name", "subnet", "ip", "asn", "org", "hosting", "ptr", "error_code"}
+
+	tests := []struct {
+		format  string
+		columns []string
+		tmpl    string
+		want    string
+	}{
+		{
+			format:  "table",
+			columns: columns,
+			want: `NAME          SUBNET           IP            ASN      ORG            HOSTING  PTR                              ERROR_CODE
+example.test  -                192.0.2.1     AS64500  Example Cloud  true     a.example.test. b.example.test.  -
+example.test  -                2001:db8::1   AS64501  -              false    -                                rate_limited
+example.test  198.51.100.0/24  -             -        -              false    -                                timeout
+example.test  203.0.113.0/24   198.51.100.1  -        -              false    -                                -
+`,
+		},
+		{
+			format:  "csv",
+			columns: columns,
+			want: `name,subnet,ip,asn,org,hosting,ptr,error_code
+example.test,,192.0.2.1,AS64500,Example	Cloud,true,a.example.test. b.example.test.,
+example.test,,2001:db8::1,AS64501,,false,,rate_limited
+example.test,198.51.100.0/24,,,,false,,timeout
+example.test,203.0.113.0/24,198.51.100.1,,,false,,
+`,
+		},
+		{
+			format:  "tsv",
+			columns: []string{"ip", "org", "cached"},
+			want: "ip\torg\tcached\n" +
+				"192.0.2.1\t\"Example\tCloud\"\tfalse\n" +
+				"2001:db8::1\t\ttrue\n" +
+				"\t\tfalse\n" +
+				"198.51.100.1\t\tfalse\n",
+		},
+		{
+			format: "csv",
+			want: `name,ip,country,asn,org,hosting,proxy
+example.test,192.0.2.1,United States,AS64500,Example	Cloud,true,false
+example.test,2001:db8::1,,AS64501,,false,false
+example.test,,,,,false,false
+example.test,198.51.100.1,,,,false,false
+`,
+		},
+		{
+			format: "template",
+			tmpl:   `{{.IP}} {{.Resume.CountryCode}}{{if .Subnet}} for {{.Subnet}}{{end}}`,
+			want:   "192.0.2.1 US\n2001:db8::1 DE\n  for 198.51.100.0/24\n198.51.100.1 NL for 203.0.113.0/24\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+
+		w, err := NewResumeRowWriter(&out, tt.format, tt.columns, tt.tmpl)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+
+		rows := ResumeRows("example.test", tableInfo())
+		// rows come by parts like streamed names
+		if err := w.Write(rows[:1]...); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(rows[1:]...); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		if out.String() != tt.want {
+			t.Errorf("%s %v: got\n%s\nwant\n%s", tt.format, tt.columns, out.String(), tt.want)
+		}
+	}
+}
+
+func TestResumeRowWriterErrors(t *testing.T) {
+	tests := []struct {
+		format  string
+		columns []string
+		tmpl    string
+	}{
+		{format: "xml"},
+		{format: "csv", columns: []string{"name", "latency"}},
+		{format: "template"},
+		{format: "template", tmpl: "{{.IP"},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewResumeRowWriter(&strings.Builder{}, tt.format, tt.columns, tt.tmpl); err == nil {
+			t.Errorf("%+v is accepted", tt)
+		}
+	}
+
+	// template fails on unknown field when row is written
+	w, _ := NewResumeRowWriter(&strings.Builder{}, "template", nil, "{{.Latency}}")
+	if err := w.Write(ResumeRow{}); err == nil {
+		t.Error("unknown template field is written")
+	}
+}
@@ -4,10 +4,57 @@
 
 package seeip
 
+import "time"
+
 type Configuration struct {
-	Address         []string `arg:"-a,--addr" help:"Search ip address or domain. Can be list or single value."`
-	ResolverService string   `arg:"-r,--reslov" help:"Resolver service name | DNS server address."`
-	IsJson          bool     `arg:"-j,--json" help:"JSON object output."`
-	Pretty          bool     `arg:"-f,--format" help:"JSON formatted object output."`
-	Workers         int      `arg:"-w,--workers" help:"Process worker count."`
+	Address         []string      `arg:"-a,--addr" help:"Search ip address or domain. Can be list or single value."`
+	Input           string        `arg:"-i,--in" help:"File of names to look up, one per line ('-' for stdin, also read from pipe without --addr). Results are streamed as NDJSON."`
+	ResolverService string        `arg:"-r,--reslov" help:"Resolver service name | DNS server address | DoH URL. Comma separated list for composite resolver."`
+	DoHMethod       string        `arg:"--doh-method" help:"Request method of DoH URL resolvers: get | post."`
+	ResolversFile   string        `arg:"--resolvers,env:SEEIP_RESOLVERS" help:"YAML file of named resolvers for --reslov. ~/.config/seeip/resolvers.yaml is used if exists."`
+	ResolverMode    string        `arg:"--reslov-mode" help:"Composite resolver strategy: fallback | race | roundrobin."`
+	IsJson          bool          `arg:"-j,--json" help:"JSON object output."`
+	Pretty          bool          `arg:"-f,--format" help:"JSON formatted object output."`
+	Output          string        `arg:"-O,--output" help:"Output format: yaml | json | table | csv | tsv | template. Same as --json for json."`
+	Columns         []string      `arg:"--columns" help:"Columns of table, csv and tsv outputs: name subnet ip country country_code region city asn as_name org isp hosting proxy mobile cached ptr ns error error_code dnssec axfr. Can be list or comma separated."`
+	Template        string        `arg:"--template" help:"Go text/template of template output, executed for every resumed IP: '{{.Name}} {{.IP}} {{.Resume.City}}'."`
+	Stats           bool          `arg:"-s,--stats" help:"Print aggregate statistics (IPs per country, ASN, org, flags, IPv6 share, names sharing IPs) instead of results."`
+	Top             int           `arg:"--top" help:"Entries of statistics country, ASN and org lists. All if 0."`
+	Workers         int           `arg:"-w,--workers" help:"Process worker count."`
+	Timeout         time.Duration `arg:"-t,--timeout" help:"Whole run timeout. Unlimited if 0."`
+	LookupTimeout   time.Duration `arg:"--lookup-timeout" help:"Single DNS or IP info lookup timeout. Unlimited if 0."`
+	Resumer         string        `arg:"--resumer" help:"IP info resumers in priority order, comma separated: ipapi | ipinfo | ipwho | rdap | mmdb."`
+	ResumerMode     string        `arg:"--resumer-mode" help:"Several resumers asking: sequential | parallel."`
+	ResumerDB       []string      `arg:"--db" help:"MaxMind format (.mmdb) database files for mmdb resumer. Can be list."`
+	IpinfoToken     string        `arg:"--ipinfo-token,env:IPINFO_TOKEN" help:"ipinfo.io API token."`
+	Compare         []string      `arg:"--compare" help:"Compare answers of several resolvers instead of IP info lookup, exit code 11 on disagreement. Can be list or comma separated."`
+	Wordlist        string        `arg:"--wordlist" help:"Enumerate subdomains of --addr domains by file of words, one per line. Wildcard DNS answers are dropped, found names are looked up as usual."`
+	Email           *EmailCommand `arg:"subcommand:email" help:"Check email security of domains instead of IP info lookup: SPF with include lookup count, DMARC, DKIM, MTA-STS and TLS-RPT. Exit code 12 if any domain has fail finding."`
+	Types           []string      `arg:"-T,--types" help:"Additional record types to query: MX TXT CNAME SOA CAA SRV etc. Can be list or comma separated."`
+	Chain           bool          `arg:"-C,--chain" help:"CNAME chain tracing with authoritative nameservers of every step and dangling CNAME check."`
+	Trace           bool          `arg:"--trace" help:"Iterative resolution from root servers with every referral, glue and latency like 'dig +trace'."`
+	PTR             bool          `arg:"-p,--ptr" help:"PTR lookup of resolved IPs with forward-confirmed reverse DNS check."`
+	Owner           bool          `arg:"-o,--owner" help:"RDAP/WHOIS ownership lookup of resolved IPs and domains."`
+	Dnssec          bool          `arg:"--dnssec" help:"Local DNSSEC validation of names: secure | insecure | bogus | indeterminate with reason and resolver AD flag."`
+	TrustAnchor     string        `arg:"--trust-anchor" help:"Zone file of DS or DNSKEY trust anchors for --dnssec instead of root KSK."`
+	AXFR            bool          `arg:"--axfr" help:"Try zone transfer (AXFR) of every domain from each address of its nameservers and report servers allowing it."`
+	AXFRDir         string        `arg:"--axfr-dir" help:"Write zones of allowed transfers to directory in RFC 1035 master file format: <domain>.zone. Implies --axfr."`
+	ECS             []string      `arg:"--ecs" help:"Resolve IPs once more for clients of every subnet with EDNS Client Subnet option: 203.0.113.0/24 (single address is cut to /24 or /56). DNS server, DoT and DoH resolvers only. Can be list or comma separated."`
+	Cache           string        `arg:"-c,--cache" help:"IP info cache backend: sqlite | starskey. Disabled if empty."`
+	CachePath       string        `arg:"--cache-path" help:"IP info cache database file (sqlite) or directory (starskey)."`
+	CacheTTL        time.Duration `arg:"--cache-ttl" help:"IP info cache entries lifetime."`
+	CacheMode       string        `arg:"--cache-mode" help:"IP info cache usage: default | offline | refresh | bypass."`
+	DnsCache        string        `arg:"--dns-cache" help:"DNS answers cache by record TTL: memory | starskey (memory with persistent layer). Disabled if empty."`
+	DnsCachePath    string        `arg:"--dns-cache-path" help:"DNS cache directory of starskey backend."`
+	DnsCacheSize    int           `arg:"--dns-cache-size" help:"Count of DNS answers kept in memory."`
+	Verbose         bool          `arg:"-v,--verbose" help:"Print run statistics to stderr."`
+
+	// Resolvers - named resolvers loaded from ResolversFile
+	Resolvers map[string]ResolverConfig `arg:"-"`
+}
+
+// EmailCommand - email subcommand options, global options go before subcommand name
+type EmailCommand struct {
+	Domains       []string `arg:"positional" help:"Domains to check in addition to --addr and --in names."`
+	DKIMSelectors []string `arg:"--dkim-selectors" help:"DKIM selectors to probe in addition to common ones. Can be list or comma separated."`
 }
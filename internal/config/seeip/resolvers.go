@@ -0,0 +1,150 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package seeip
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Named resolver types of resolvers file
+const (
+	ResolverDoH   = "doh"
+	ResolverDoT   = "dot"
+	ResolverPlain = "dns"
+)
+
+/*
+ResolversFile - named resolvers file
+
+	resolvers:
+	  quad9:
+	    type: doh
+	    url: https://dns.quad9.net/dns-query
+	    bootstrap: [9.9.9.9, 149.112.112.112]
+	    timeout: 3s
+	    retries: 2
+	  office:
+	    type: dns
+	    server: 10.0.0.1:53
+	    edns: {udp_size: 1232, dnssec: true}
+*/
+type ResolversFile struct {
+	Resolvers map[string]ResolverConfig `yaml:"resolvers"`
+}
+
+// ResolverConfig - named resolver settings
+type ResolverConfig struct {
+	// Type - doh | dot | dns
+	Type string `yaml:"type"`
+
+	// URL - DoH endpoint
+	URL string `yaml:"url"`
+	// JSON - DoH JSON API (application/dns-json) instead of RFC 8484 wire format
+	JSON bool `yaml:"json"`
+	// Method - DoH wire format request method: get | post
+	Method string `yaml:"method"`
+	// Bootstrap - DoH host IPs, host isn't resolved with system resolver if set
+	Bootstrap []string `yaml:"bootstrap"`
+	// Proxy - DoH HTTP(S) or SOCKS5 proxy URL
+	Proxy string `yaml:"proxy"`
+
+	// Server - DoT or plain DNS server address: "1.1.1.1", "10.0.0.1:5353"
+	Server string `yaml:"server"`
+	// ServerName - DoT certificate name
+	ServerName string `yaml:"server_name"`
+	// Pins - DoT SPKI pins (base64 SHA-256)
+	Pins []string `yaml:"pins"`
+
+	// Timeout - single query timeout
+	Timeout time.Duration `yaml:"timeout"`
+	// Retries - query repeats on resolver failure
+	Retries int `yaml:"retries"`
+	// EDNS - EDNS0 settings of queries
+	EDNS *EDNSConfig `yaml:"edns"`
+}
+
+type EDNSConfig struct {
+	UDPSize uint16 `yaml:"udp_size"`
+	DNSSEC  bool   `yaml:"dnssec"`
+}
+
+// DefaultResolversFile - "~/.config/seeip/resolvers.yaml"
+func DefaultResolversFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "seeip", "resolvers.yaml")
+}
+
+/*
+LoadResolvers - named resolvers from YAML file
+
+	If path is empty, DefaultResolversFile is used when it exists.
+*/
+func LoadResolvers(path string) (map[string]ResolverConfig, error) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultResolversFile()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return map[string]ResolverConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read resolvers file: %w", err)
+	}
+
+	file := ResolversFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse resolvers file %s: %w", path, err)
+	}
+
+	for name, rc := range file.Resolvers {
+		if err := rc.validate(); err != nil {
+			return nil, fmt.Errorf("resolvers file %s: %s: %w", path, name, err)
+		}
+
+		if strings.Contains(name, ",") {
+			return nil, fmt.Errorf("resolvers file %s: name must not contain comma: %s", path, name)
+		}
+	}
+
+	if file.Resolvers == nil {
+		file.Resolvers = map[string]ResolverConfig{}
+	}
+
+	return file.Resolvers, nil
+}
+
+func (rc ResolverConfig) validate() error {
+	switch rc.Type {
+	case ResolverDoH:
+		if rc.URL == "" {
+			return errors.New("url is required for doh resolver")
+		}
+	case ResolverDoT, ResolverPlain:
+		if rc.Server == "" {
+			return fmt.Errorf("server is required for %s resolver", rc.Type)
+		}
+	default:
+		return fmt.Errorf("unknown resolver type %q: doh | dot | dns expected", rc.Type)
+	}
+
+	if rc.Retries < 0 {
+		return errors.New("retries must not be negative")
+	}
+
+	return nil
+}
@@ -7,6 +7,8 @@ package models
 import (
 	"context"
 	"net"
+	"net/netip"
+	"slices"
 	"time"
 )
 
@@ -21,17 +23,286 @@ const (
 type Resolver interface {
 	ResolveIP(ctx context.Context, s string) ([]net.IP, error)
 	ResolveNS(ctx context.Context, s string) ([]string, error)
+	ResolvePTR(ctx context.Context, ip net.IP) ([]string, error)
+	// ResolveRecords - query records of any type by name: "MX", "TXT", "SOA" etc.
+	ResolveRecords(ctx context.Context, s string, rtype string) ([]DnsRecord, error)
+}
+
+// NamedResolver - resolver with its name: "google", "8.8.8.8:53" etc.
+type NamedResolver struct {
+	Name     string
+	Resolver Resolver
+}
+
+// Tracer - iterative resolution from root servers with every delegation hop
+type Tracer interface {
+	Trace(ctx context.Context, s string, rtype string) (DnsTrace, error)
+}
+
+// SubnetResolver - IP resolving on behalf of clients of subnet: EDNS Client Subnet option (RFC 7871) is sent with queries
+type SubnetResolver interface {
+	ResolveIPSubnet(ctx context.Context, s string, subnet netip.Prefix) (SubnetAnswer, error)
+}
+
+/*
+SubnetAnswer - IPs of name resolved for client subnet
+
+	Scope is prefix length the answer is valid for (RFC 7871 scope prefix-length): 0 - the same answer for any client.
+	It's nil if resolver ignored client subnet.
+*/
+type SubnetAnswer struct {
+	IPs          []net.IP  `json:"ip,omitempty" yaml:"ip,omitempty"`
+	Scope        *uint8    `json:"scope,omitempty" yaml:"scope,omitempty"`
+	ErrorIPs     string    `json:"ip_error,omitempty" yaml:"ip_error,omitempty"`
+	ErrorIPsCode ErrorCode `json:"ip_error_code,omitempty" yaml:"ip_error_code,omitempty"`
+}
+
+// DnssecValidator - local DNSSEC validation of name answer by chain of trust from trust anchors
+type DnssecValidator interface {
+	ValidateDNSSEC(ctx context.Context, s string) (DnssecStatus, error)
+}
+
+// DnssecState - DNSSEC validation state of answer (RFC 4035 4.3)
+type DnssecState string
+
+const (
+	// DnssecSecure - answer (every step of CNAME chain) is signed or its absence is proven, chain of trust is validated
+	DnssecSecure DnssecState = "secure"
+	// DnssecInsecure - answer is in unsigned zone, absence of DS in parent zone is proven
+	DnssecInsecure DnssecState = "insecure"
+	// DnssecBogus - signatures or chain of trust are broken
+	DnssecBogus DnssecState = "bogus"
+	// DnssecIndeterminate - validation failed: resolver errors, no trust anchor etc.
+	DnssecIndeterminate DnssecState = "indeterminate"
+)
+
+/*
+DnssecStatus - DNSSEC validation result of name A answer
+
+	AD is authenticated data flag of resolver answer, it's set only by validating resolvers.
+	Chain lists zones of chain of trust from trust anchor down to zone of name and zones of CNAME targets.
+*/
+type DnssecStatus struct {
+	State  DnssecState  `json:"state" yaml:"state"`
+	Reason string       `json:"reason,omitempty" yaml:"reason,omitempty"`
+	AD     bool         `json:"ad" yaml:"ad"`
+	Chain  []DnssecZone `json:"chain,omitempty" yaml:"chain,omitempty"`
+}
+
+// DnssecZone - zone of chain of trust: key tags of its DS records in parent zone (trust anchors for root) and its DNSKEY records
+type DnssecZone struct {
+	Zone string   `json:"zone" yaml:"zone"`
+	DS   []uint16 `json:"ds,omitempty" yaml:"ds,omitempty"`
+	Keys []uint16 `json:"keys,omitempty" yaml:"keys,omitempty"`
+}
+
+// ReverseDNS - PTR names of IP with forward-confirmed reverse DNS (FCrDNS) check
+type ReverseDNS struct {
+	Names     []string `json:"names,omitempty" yaml:"names,omitempty"`
+	Confirmed []string `json:"confirmed,omitempty" yaml:"confirmed,omitempty"`
+	FCrDNS    bool     `json:"fcrdns" yaml:"fcrdns"`
+	Err       string   `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
 type AboutResolve struct {
-	IPs               []net.IP `json:"ip,omitempty" yaml:"ip,omitempty"`
-	NameServers       []string `json:"ns,omitempty" yaml:"ns,omitempty"`
-	ErrorIPs          string   `json:"ip_error,omitempty" yaml:"ip_error,omitempty"`
-	ErrorNS           string   `json:"ns_error,omitempty" yaml:"ns_error,omitempty"`
-	ResolveDurationMs int64    `json:"resolve_duration_ms" yaml:"resolve_duration_ms"`
+	IPs               []net.IP                `json:"ip,omitempty" yaml:"ip,omitempty"`
+	NameServers       []string                `json:"ns,omitempty" yaml:"ns,omitempty"`
+	ErrorIPs          string                  `json:"ip_error,omitempty" yaml:"ip_error,omitempty"`
+	ErrorIPsCode      ErrorCode               `json:"ip_error_code,omitempty" yaml:"ip_error_code,omitempty"`
+	ErrorNS           string                  `json:"ns_error,omitempty" yaml:"ns_error,omitempty"`
+	ErrorNSCode       ErrorCode               `json:"ns_error_code,omitempty" yaml:"ns_error_code,omitempty"`
+	Owner             *Ownership              `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Records           map[string][]DnsRecord  `json:"records,omitempty" yaml:"records,omitempty"`
+	ErrorRecords      map[string]string       `json:"records_error,omitempty" yaml:"records_error,omitempty"`
+	ErrorRecordsCode  map[string]ErrorCode    `json:"records_error_code,omitempty" yaml:"records_error_code,omitempty"`
+	Chain             *CnameChain             `json:"chain,omitempty" yaml:"chain,omitempty"`
+	Trace             *DnsTrace               `json:"trace,omitempty" yaml:"trace,omitempty"`
+	Subnets           map[string]SubnetAnswer `json:"subnets,omitempty" yaml:"subnets,omitempty"`
+	Dnssec            *DnssecStatus           `json:"dnssec,omitempty" yaml:"dnssec,omitempty"`
+	Transfer          *ZoneTransfer           `json:"axfr,omitempty" yaml:"axfr,omitempty"`
+	ResolveDurationMs int64                   `json:"resolve_duration_ms" yaml:"resolve_duration_ms"`
+}
+
+// AllIPs - unique IPs of name: resolved ones and IPs of every client subnet
+func (r AboutResolve) AllIPs() []net.IP {
+	ips := slices.Clone(r.IPs)
+
+	for _, ans := range r.Subnets {
+		for _, ip := range ans.IPs {
+			if !slices.ContainsFunc(ips, ip.Equal) {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	return ips
+}
+
+// ZoneTransferrer - zone transfer (AXFR) probe of zone against its nameservers
+type ZoneTransferrer interface {
+	TransferZone(ctx context.Context, zone string, nameservers []string) (ZoneTransfer, error)
+}
+
+/*
+ZoneTransfer - AXFR attempts of zone against every address of its nameservers
+
+	Open is set if any server allowed transfer: whole zone is disclosed to anyone.
+	File is master file of zone dumped from first allowed transfer.
+*/
+type ZoneTransfer struct {
+	Open    bool         `json:"open" yaml:"open"`
+	Servers []AXFRServer `json:"servers,omitempty" yaml:"servers,omitempty"`
+	File    string       `json:"file,omitempty" yaml:"file,omitempty"`
+	Err     string       `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// AXFRServer - AXFR attempt against single nameserver address
+type AXFRServer struct {
+	NS         string    `json:"ns" yaml:"ns"`
+	Address    string    `json:"address,omitempty" yaml:"address,omitempty"`
+	Allowed    bool      `json:"allowed" yaml:"allowed"`
+	Records    int       `json:"records,omitempty" yaml:"records,omitempty"`
+	Serial     uint32    `json:"serial,omitempty" yaml:"serial,omitempty"`
+	DurationMs int64     `json:"duration_ms" yaml:"duration_ms"`
+	Err        string    `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrCode    ErrorCode `json:"error_code,omitempty" yaml:"error_code,omitempty"`
+}
+
+/*
+SubdomainEnum - subdomains of base domain found by wordlist
+
+	Wildcard is set if random labels resolve, candidates resolving only to WildcardIPs are dropped as false positives.
+	Failed counts candidates with lookup errors other than NXDOMAIN and NODATA.
+*/
+type SubdomainEnum struct {
+	Base        string   `json:"base" yaml:"base"`
+	Found       []string `json:"found" yaml:"found"`
+	Candidates  int      `json:"candidates" yaml:"candidates"`
+	Wildcard    bool     `json:"wildcard" yaml:"wildcard"`
+	WildcardIPs []net.IP `json:"wildcard_ip,omitempty" yaml:"wildcard_ip,omitempty"`
+	Dropped     int      `json:"dropped" yaml:"dropped"`
+	Failed      int      `json:"failed" yaml:"failed"`
+}
+
+/*
+ResolveComparison - answers of several resolvers for the same name
+
+	Disagreements list every difference of IP sets, address TTLs, NS lists or failures.
+*/
+type ResolveComparison struct {
+	Agree         bool                      `json:"agree" yaml:"agree"`
+	Disagreements []string                  `json:"disagreements,omitempty" yaml:"disagreements,omitempty"`
+	Answers       map[string]ResolverAnswer `json:"answers" yaml:"answers"`
+}
+
+// ResolverAnswer - normalized (sorted) answer of single resolver. TTL is min TTL of address records, 0 if unknown
+type ResolverAnswer struct {
+	IPs          []string  `json:"ip,omitempty" yaml:"ip,omitempty"`
+	TTL          uint32    `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	NameServers  []string  `json:"ns,omitempty" yaml:"ns,omitempty"`
+	ErrorIPs     string    `json:"ip_error,omitempty" yaml:"ip_error,omitempty"`
+	ErrorIPsCode ErrorCode `json:"ip_error_code,omitempty" yaml:"ip_error_code,omitempty"`
+	ErrorNS      string    `json:"ns_error,omitempty" yaml:"ns_error,omitempty"`
+	ErrorNSCode  ErrorCode `json:"ns_error_code,omitempty" yaml:"ns_error_code,omitempty"`
+	DurationMs   int64     `json:"duration_ms" yaml:"duration_ms"`
+}
+
+/*
+CnameChain - CNAME chain of name down to final A/AAAA answers
+
+	Dangling is set if chain has CNAME but its final target doesn't exist (NXDOMAIN,
+	possible subdomain takeover). Other failures of target lookup are reported in Err only.
+*/
+type CnameChain struct {
+	Steps    []ChainStep `json:"steps" yaml:"steps"`
+	Dangling bool        `json:"dangling" yaml:"dangling"`
+	Err      string      `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ChainStep - name of CNAME chain with authoritative zone nameservers of it
+type ChainStep struct {
+	Name      string      `json:"name" yaml:"name"`
+	CNAME     string      `json:"cname,omitempty" yaml:"cname,omitempty"`
+	TTL       uint32      `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Zone      string      `json:"zone,omitempty" yaml:"zone,omitempty"`
+	Authority []string    `json:"authority,omitempty" yaml:"authority,omitempty"`
+	Answers   []DnsRecord `json:"answers,omitempty" yaml:"answers,omitempty"`
+}
+
+// DnsTrace - delegation path of iterative resolution like "dig +trace"
+type DnsTrace struct {
+	Name    string      `json:"name" yaml:"name"`
+	Type    string      `json:"type" yaml:"type"`
+	Hops    []TraceHop  `json:"hops" yaml:"hops"`
+	Answers []DnsRecord `json:"answers,omitempty" yaml:"answers,omitempty"`
+	Err     string      `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+/*
+TraceHop - single query of iterative resolution
+
+	Referral is set when server delegated query to child zone (with its glue records).
+	Lame is set when server of delegated zone has no authoritative answer or referral for it.
+	NSTraces are nested traces of referral nameservers without glue records.
+*/
+type TraceHop struct {
+	Zone          string      `json:"zone" yaml:"zone"`
+	Server        string      `json:"server" yaml:"server"`
+	Addr          string      `json:"addr" yaml:"addr"`
+	LatencyMs     int64       `json:"latency_ms" yaml:"latency_ms"`
+	Rcode         string      `json:"rcode,omitempty" yaml:"rcode,omitempty"`
+	Authoritative bool        `json:"authoritative" yaml:"authoritative"`
+	Referral      string      `json:"referral,omitempty" yaml:"referral,omitempty"`
+	NameServers   []string    `json:"ns,omitempty" yaml:"ns,omitempty"`
+	Glue          []DnsRecord `json:"glue,omitempty" yaml:"glue,omitempty"`
+	Answers       []DnsRecord `json:"answers,omitempty" yaml:"answers,omitempty"`
+	Lame          bool        `json:"lame,omitempty" yaml:"lame,omitempty"`
+	NSTraces      []DnsTrace  `json:"ns_traces,omitempty" yaml:"ns_traces,omitempty"`
+	Err           string      `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
 func (ar *AboutResolve) CalcDuration(start time.Time) {
 	end := time.Now()
 	ar.ResolveDurationMs = end.Sub(start).Milliseconds()
 }
+
+// DnsRecord - typed DNS resource record. Data is RDATA in presentation format
+type DnsRecord struct {
+	Name string     `json:"name" yaml:"name"`
+	Type string     `json:"type" yaml:"type"`
+	TTL  uint32     `json:"ttl" yaml:"ttl"`
+	Data string     `json:"data" yaml:"data"`
+	MX   *MXRecord  `json:"mx,omitempty" yaml:"mx,omitempty"`
+	SOA  *SOARecord `json:"soa,omitempty" yaml:"soa,omitempty"`
+	SRV  *SRVRecord `json:"srv,omitempty" yaml:"srv,omitempty"`
+	CAA  *CAARecord `json:"caa,omitempty" yaml:"caa,omitempty"`
+	TXT  []string   `json:"txt,omitempty" yaml:"txt,omitempty"`
+}
+
+type MXRecord struct {
+	Preference uint16 `json:"preference" yaml:"preference"`
+	Exchange   string `json:"exchange" yaml:"exchange"`
+}
+
+type SOARecord struct {
+	Ns      string `json:"ns" yaml:"ns"`
+	Mbox    string `json:"mbox" yaml:"mbox"`
+	Serial  uint32 `json:"serial" yaml:"serial"`
+	Refresh uint32 `json:"refresh" yaml:"refresh"`
+	Retry   uint32 `json:"retry" yaml:"retry"`
+	Expire  uint32 `json:"expire" yaml:"expire"`
+	MinTTL  uint32 `json:"minttl" yaml:"minttl"`
+}
+
+type SRVRecord struct {
+	Priority uint16 `json:"priority" yaml:"priority"`
+	Weight   uint16 `json:"weight" yaml:"weight"`
+	Port     uint16 `json:"port" yaml:"port"`
+	Target   string `json:"target" yaml:"target"`
+}
+
+type CAARecord struct {
+	Flag  uint8  `json:"flag" yaml:"flag"`
+	Tag   string `json:"tag" yaml:"tag"`
+	Value string `json:"value" yaml:"value"`
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package models
+
+import "context"
+
+// EmailChecker - email security posture of domain: SPF, DMARC, DKIM, MTA-STS and TLS-RPT
+type EmailChecker interface {
+	CheckEmail(ctx context.Context, domain string) EmailPosture
+}
+
+// FindingStatus - result of single email security check
+type FindingStatus string
+
+const (
+	FindingPass FindingStatus = "pass"
+	FindingWarn FindingStatus = "warn"
+	FindingFail FindingStatus = "fail"
+)
+
+// Worse - status with higher severity: fail > warn > pass
+func (s FindingStatus) Worse(other FindingStatus) FindingStatus {
+	rank := map[FindingStatus]int{FindingPass: 0, FindingWarn: 1, FindingFail: 2}
+	if rank[other] > rank[s] {
+		return other
+	}
+	return s
+}
+
+// EmailFinding - result of check with explanation
+type EmailFinding struct {
+	// Check - spf | dmarc | dkim | mta-sts | tls-rpt
+	Check   string        `json:"check" yaml:"check"`
+	Status  FindingStatus `json:"status" yaml:"status"`
+	Message string        `json:"message" yaml:"message"`
+}
+
+/*
+EmailPosture - email security records of domain and findings about them
+
+	Status is the worst status of findings.
+*/
+type EmailPosture struct {
+	Status   FindingStatus  `json:"status" yaml:"status"`
+	MX       []string       `json:"mx,omitempty" yaml:"mx,omitempty"`
+	SPF      *SPFReport     `json:"spf,omitempty" yaml:"spf,omitempty"`
+	DMARC    *DMARCReport   `json:"dmarc,omitempty" yaml:"dmarc,omitempty"`
+	DKIM     []DKIMReport   `json:"dkim,omitempty" yaml:"dkim,omitempty"`
+	MTASTS   *MTASTSReport  `json:"mta_sts,omitempty" yaml:"mta_sts,omitempty"`
+	TLSRPT   *TLSRPTReport  `json:"tls_rpt,omitempty" yaml:"tls_rpt,omitempty"`
+	Findings []EmailFinding `json:"findings" yaml:"findings"`
+}
+
+/*
+SPFReport - SPF record with expanded includes
+
+	Lookups counts DNS querying terms of whole include tree (RFC 7208 limit is 10),
+	VoidLookups counts includes and redirects without answer (limit is 2).
+*/
+type SPFReport struct {
+	Record      string       `json:"record" yaml:"record"`
+	Lookups     int          `json:"lookups" yaml:"lookups"`
+	VoidLookups int          `json:"void_lookups" yaml:"void_lookups"`
+	Includes    []SPFInclude `json:"includes,omitempty" yaml:"includes,omitempty"`
+}
+
+// SPFInclude - included or redirected SPF record
+type SPFInclude struct {
+	Domain   string       `json:"domain" yaml:"domain"`
+	Record   string       `json:"record,omitempty" yaml:"record,omitempty"`
+	Includes []SPFInclude `json:"includes,omitempty" yaml:"includes,omitempty"`
+	Err      string       `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// DMARCReport - DMARC policy of domain. Domain is organizational domain if policy is inherited from it
+type DMARCReport struct {
+	Record          string   `json:"record" yaml:"record"`
+	Domain          string   `json:"domain" yaml:"domain"`
+	Policy          string   `json:"policy" yaml:"policy"`
+	SubdomainPolicy string   `json:"subdomain_policy" yaml:"subdomain_policy"`
+	Percent         int      `json:"pct" yaml:"pct"`
+	RUA             []string `json:"rua,omitempty" yaml:"rua,omitempty"`
+	RUF             []string `json:"ruf,omitempty" yaml:"ruf,omitempty"`
+}
+
+// DKIMReport - DKIM public key found by selector
+type DKIMReport struct {
+	Selector string `json:"selector" yaml:"selector"`
+	KeyType  string `json:"key_type" yaml:"key_type"`
+	Bits     int    `json:"bits,omitempty" yaml:"bits,omitempty"`
+	Revoked  bool   `json:"revoked,omitempty" yaml:"revoked,omitempty"`
+	Testing  bool   `json:"testing,omitempty" yaml:"testing,omitempty"`
+}
+
+// MTASTSReport - MTA-STS record and policy of domain
+type MTASTSReport struct {
+	Record string   `json:"record" yaml:"record"`
+	ID     string   `json:"id,omitempty" yaml:"id,omitempty"`
+	Mode   string   `json:"mode,omitempty" yaml:"mode,omitempty"`
+	MX     []string `json:"mx,omitempty" yaml:"mx,omitempty"`
+	MaxAge int64    `json:"max_age,omitempty" yaml:"max_age,omitempty"`
+	Err    string   `json:"policy_error,omitempty" yaml:"policy_error,omitempty"`
+}
+
+// TLSRPTReport - SMTP TLS reporting record of domain
+type TLSRPTReport struct {
+	Record string   `json:"record" yaml:"record"`
+	RUA    []string `json:"rua,omitempty" yaml:"rua,omitempty"`
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// ErrorCode - stable machine readable class of lookup error
+type ErrorCode string
+
+const (
+	// CodeNXDOMAIN - name does not exist
+	CodeNXDOMAIN ErrorCode = "nxdomain"
+	// CodeNODATA - name exists, but has no records of asked type
+	CodeNODATA ErrorCode = "nodata"
+	// CodeSERVFAIL - resolver failed to process query
+	CodeSERVFAIL ErrorCode = "servfail"
+	// CodeREFUSED - resolver refused to answer
+	CodeREFUSED ErrorCode = "refused"
+	// CodeTimeout - no answer in time
+	CodeTimeout ErrorCode = "timeout"
+	// CodeNetwork - resolver or API is unreachable: connection refused, no route etc.
+	CodeNetwork ErrorCode = "network_unreachable"
+	// CodeRateLimited - request is rejected by rate limit
+	CodeRateLimited ErrorCode = "rate_limited"
+	// CodeMalformed - response can't be parsed
+	CodeMalformed ErrorCode = "malformed_response"
+	// CodeNotCached - IP info is not found in cache in offline mode
+	CodeNotCached ErrorCode = "not_cached"
+	// CodeCanceled - lookup is canceled by interrupt
+	CodeCanceled ErrorCode = "canceled"
+	// CodeUnknown - any other error
+	CodeUnknown ErrorCode = "unknown"
+)
+
+// LookupError - error with known class
+type LookupError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *LookupError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LookupError) Unwrap() error {
+	return e.Err
+}
+
+func (e *LookupError) ErrorCode() ErrorCode {
+	return e.Code
+}
+
+// errorSeverity - rank of error classes: transport and server failures are above unknown errors and negative answers
+var errorSeverity = map[ErrorCode]int{
+	CodeNODATA:      1,
+	CodeNXDOMAIN:    2,
+	CodeUnknown:     3,
+	CodeNotCached:   4,
+	CodeREFUSED:     5,
+	CodeSERVFAIL:    6,
+	CodeMalformed:   7,
+	CodeRateLimited: 8,
+	CodeTimeout:     9,
+	CodeNetwork:     10,
+	CodeCanceled:    11,
+}
+
+// Severity - rank of error class, greater is more severe. Empty class is 0
+func (c ErrorCode) Severity() int {
+	return errorSeverity[c]
+}
+
+/*
+ErrorCodeOf - class of error, empty for nil
+
+	Errors that know their class implement ErrorCode() ErrorCode. Errors of DNS protocol libraries
+	are recognized by DnsRcode() int (response code) and HTTPStatus() int (HTTP status of DoH and APIs) methods.
+	Other errors are classified by standard library types: timeouts, network and JSON errors.
+	Wrapped error is classified by the outermost error of chain with known class.
+	Joined errors (A and AAAA queries etc.) get class of the most severe part: "A timeout + AAAA NODATA" is timeout,
+	negative answer class is returned only if every part is negative answer.
+*/
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+
+	for {
+		if code, ok := ownErrorCode(err); ok {
+			return code
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			return joinedErrorCode(e.Unwrap())
+		case interface{ Unwrap() error }:
+			if err = e.Unwrap(); err != nil {
+				continue
+			}
+		}
+
+		return CodeUnknown
+	}
+}
+
+// joinedErrorCode - class of the most severe error
+func joinedErrorCode(errs []error) ErrorCode {
+	var worst ErrorCode
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		code := ErrorCodeOf(err)
+		if worst == "" || code.Severity() > worst.Severity() {
+			worst = code
+		}
+	}
+
+	if worst == "" {
+		return CodeUnknown
+	}
+	return worst
+}
+
+// ownErrorCode - class of error itself, without wrapped ones
+func ownErrorCode(err error) (ErrorCode, bool) {
+	switch e := err.(type) {
+	case interface{ ErrorCode() ErrorCode }:
+		return e.ErrorCode(), true
+	case interface{ DnsRcode() int }:
+		return RcodeErrorCode(e.DnsRcode()), true
+	case interface{ HTTPStatus() int }:
+		switch code := e.HTTPStatus(); {
+		case code == http.StatusTooManyRequests:
+			return CodeRateLimited, true
+		case code >= http.StatusInternalServerError:
+			return CodeSERVFAIL, true
+		}
+		return CodeUnknown, true
+	case *net.DNSError:
+		return dnsErrorCode(e), true
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return CodeMalformed, true
+	}
+
+	switch {
+	case err == context.Canceled:
+		return CodeCanceled, true
+	case err == context.DeadlineExceeded:
+		return CodeTimeout, true
+	case err == io.ErrUnexpectedEOF:
+		return CodeMalformed, true
+	case err == syscall.ECONNREFUSED, err == syscall.ENETUNREACH, err == syscall.EHOSTUNREACH:
+		return CodeNetwork, true
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return CodeTimeout, true
+	}
+
+	if _, ok := err.(*net.OpError); ok {
+		return CodeNetwork, true
+	}
+
+	return "", false
+}
+
+// RcodeErrorCode - class of DNS response code (RFC 1035)
+func RcodeErrorCode(rcode int) ErrorCode {
+	switch rcode {
+	case 2:
+		return CodeSERVFAIL
+	case 3:
+		return CodeNXDOMAIN
+	case 5:
+		return CodeREFUSED
+	}
+	return CodeUnknown
+}
+
+// dnsErrorCode - class of Go resolver error. It doesn't tell NXDOMAIN from NODATA
+func dnsErrorCode(err *net.DNSError) ErrorCode {
+	switch {
+	case err.IsNotFound:
+		return CodeNXDOMAIN
+	case err.IsTimeout:
+		return CodeTimeout
+	case err.Err == "server misbehaving":
+		return CodeSERVFAIL
+	case err.IsTemporary:
+		return CodeNetwork
+	}
+	return CodeUnknown
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type rcodeErr int
+
+func (e rcodeErr) Error() string { return fmt.Sprintf("rcode %d", int(e)) }
+func (e rcodeErr) DnsRcode() int { return int(e) }
+
+type statusErr int
+
+func (e statusErr) Error() string   { return fmt.Sprintf("status %d", int(e)) }
+func (e statusErr) HTTPStatus() int { return int(e) }
+
+func TestErrorCodeOf(t *testing.T) {
+	var (
+		nodata   = &LookupError{Code: CodeNODATA, Err: errors.New("no AAAA records")}
+		nxdomain = &LookupError{Code: CodeNXDOMAIN, Err: errors.New("NXDOMAIN")}
+		refused  = &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+		syntax   error
+	)
+	syntax = json.Unmarshal([]byte("{"), &struct{}{})
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"nil", nil, ""},
+		{"plain", errors.New("boom"), CodeUnknown},
+		{"coded", nodata, CodeNODATA},
+		{"wrapped coded", fmt.Errorf("lookup: %w", nxdomain), CodeNXDOMAIN},
+		{"rcode", rcodeErr(2), CodeSERVFAIL},
+		{"http 429", statusErr(429), CodeRateLimited},
+		{"http 503", statusErr(503), CodeSERVFAIL},
+		{"http 404", statusErr(404), CodeUnknown},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), CodeCanceled},
+		{"deadline", context.DeadlineExceeded, CodeTimeout},
+		{"go resolver timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, CodeTimeout},
+		{"connection refused", refused, CodeNetwork},
+		{"url error", &url.Error{Op: "Get", URL: "https://dns.test", Err: refused}, CodeNetwork},
+		{"json", fmt.Errorf("decode: %w", syntax), CodeMalformed},
+
+		// joined errors: failures win, negative only if every part is negative
+		{"timeout and nodata", errors.Join(context.DeadlineExceeded, nodata), CodeTimeout},
+		{"nodata and servfail", errors.Join(nodata, rcodeErr(2)), CodeSERVFAIL},
+		{"nodata and unknown", errors.Join(nodata, errors.New("boom")), CodeUnknown},
+		{"both negative", errors.Join(nodata, nxdomain), CodeNXDOMAIN},
+		{"both nodata", errors.Join(nodata, nodata), CodeNODATA},
+		{"wrapped joined", fmt.Errorf("resolve: %w", errors.Join(nxdomain, refused)), CodeNetwork},
+		{"coded joined", &LookupError{Code: CodeRateLimited, Err: errors.Join(nodata, refused)}, CodeRateLimited},
+		{"nested joined", errors.Join(nodata, errors.Join(nxdomain, statusErr(429))), CodeRateLimited},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorCodeOf(tt.err); got != tt.want {
+			t.Errorf("%s: ErrorCodeOf(%v) = %q, want %q", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestErrorCodeSeverity(t *testing.T) {
+	// exit code of the most severe error is reported: order isn't the order of exit codes
+	order := []ErrorCode{"", CodeNODATA, CodeNXDOMAIN, CodeUnknown, CodeNotCached, CodeREFUSED, CodeSERVFAIL,
+		CodeMalformed, CodeRateLimited, CodeTimeout, CodeNetwork, CodeCanceled}
+
+	for i := 1; i < len(order); i++ {
+		if order[i].Severity() <= order[i-1].Severity() {
+			t.Errorf("%q is not more severe than %q", order[i], order[i-1])
+		}
+	}
+}
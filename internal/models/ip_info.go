@@ -5,6 +5,7 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"time"
@@ -19,7 +20,11 @@ AboutIPobject - response ip info object
 type ResumeAboutIP struct {
 	RequestIP net.IP        `json:"request_ip" yaml:"request_ip"`
 	Resume    AboutIPobject `json:"resume,omitempty" yaml:"resume,omitempty"`
+	Cached    bool          `json:"cached" yaml:"cached"`
+	Owner     *Ownership    `json:"owner,omitempty" yaml:"owner,omitempty"`
+	PTR       *ReverseDNS   `json:"ptr,omitempty" yaml:"ptr,omitempty"`
 	Err       string        `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrCode   ErrorCode     `json:"error_code,omitempty" yaml:"error_code,omitempty"`
 }
 
 type AboutIPobject struct {
@@ -47,6 +52,11 @@ type AboutIPobject struct {
 	Proxy         bool      `json:"proxy" yaml:"proxy"`
 	Hosting       bool      `json:"hosting" yaml:"hosting"`
 	RequestTime   time.Time `json:"-" yaml:"-"`
+
+	// Sources - provider name per field (by JSON name) when object is merged from several providers
+	Sources map[string]string `json:"sources,omitempty" yaml:"sources,omitempty"`
+	// Conflicts - other provider values of field that differ from merged one: "provider: value"
+	Conflicts map[string][]string `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
 }
 
 func (ip AboutIPobject) MapLinks() map[string]string {
@@ -58,5 +68,42 @@ func (ip AboutIPobject) MapLinks() map[string]string {
 }
 
 type ResumerIP interface {
-	ResumeIP(ip net.IP) (AboutIPobject, error)
+	ResumeIP(ctx context.Context, ip net.IP) (AboutIPobject, error)
+}
+
+// BatchResumerIP - ResumerIP that can resume many IPs per request
+type BatchResumerIP interface {
+	ResumerIP
+	// ResumeIPs - results and errors have the same length and order as ips
+	ResumeIPs(ctx context.Context, ips []net.IP) ([]AboutIPobject, []error)
+}
+
+// BatchStats - aggregate report over lookup batch. IPs are counted once, whatever names share them
+type BatchStats struct {
+	Names       int         `json:"names" yaml:"names"`
+	FailedNames int         `json:"failed_names" yaml:"failed_names"`
+	IPs         int         `json:"ips" yaml:"ips"`
+	IPv6        int         `json:"ipv6" yaml:"ipv6"`
+	IPv6Share   float64     `json:"ipv6_share" yaml:"ipv6_share"`
+	Unresumed   int         `json:"unresumed" yaml:"unresumed"`
+	Hosting     int         `json:"hosting" yaml:"hosting"`
+	Proxy       int         `json:"proxy" yaml:"proxy"`
+	Mobile      int         `json:"mobile" yaml:"mobile"`
+	Countries   []StatCount `json:"countries" yaml:"countries"`
+	ASNs        []StatCount `json:"asns" yaml:"asns"`
+	Orgs        []StatCount `json:"orgs" yaml:"orgs"`
+	ErrorCodes  []StatCount `json:"error_codes,omitempty" yaml:"error_codes,omitempty"`
+	SharedIPs   []SharedIP  `json:"shared_ips,omitempty" yaml:"shared_ips,omitempty"`
+}
+
+// StatCount - IPs count of single value
+type StatCount struct {
+	Value string `json:"value" yaml:"value"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+// SharedIP - IP resolved by several names
+type SharedIP struct {
+	IP    string   `json:"ip" yaml:"ip"`
+	Names []string `json:"names" yaml:"names"`
 }
@@ -0,0 +1,36 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package models
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Ownership - RDAP/WHOIS registration data of IP network or domain
+type Ownership struct {
+	Source       string    `json:"source" yaml:"source"`
+	Server       string    `json:"server" yaml:"server"`
+	Handle       string    `json:"handle,omitempty" yaml:"handle,omitempty"`
+	Name         string    `json:"name,omitempty" yaml:"name,omitempty"`
+	Org          string    `json:"org,omitempty" yaml:"org,omitempty"`
+	Country      string    `json:"country,omitempty" yaml:"country,omitempty"`
+	StartAddress string    `json:"start_address,omitempty" yaml:"start_address,omitempty"`
+	EndAddress   string    `json:"end_address,omitempty" yaml:"end_address,omitempty"`
+	CIDR         []string  `json:"cidr,omitempty" yaml:"cidr,omitempty"`
+	AbuseEmail   string    `json:"abuse_email,omitempty" yaml:"abuse_email,omitempty"`
+	AbusePhone   string    `json:"abuse_phone,omitempty" yaml:"abuse_phone,omitempty"`
+	Registrar    string    `json:"registrar,omitempty" yaml:"registrar,omitempty"`
+	Registered   time.Time `json:"registered,omitzero" yaml:"registered,omitempty"`
+	LastChanged  time.Time `json:"last_changed,omitzero" yaml:"last_changed,omitempty"`
+	Expires      time.Time `json:"expires,omitzero" yaml:"expires,omitempty"`
+	Err          string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type OwnerLookup interface {
+	LookupIP(ctx context.Context, ip net.IP) (Ownership, error)
+	LookupDomain(ctx context.Context, domain string) (Ownership, error)
+}
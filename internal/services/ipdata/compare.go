@@ -0,0 +1,249 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	microutils "github.com/eterline/micro-utils"
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// compareTTLRatio - cached answers count TTL down, so TTLs disagree only if one is ratio times less than the largest
+const compareTTLRatio = 2
+
+/*
+ResolverCompareService - resolves the same names through several resolvers and reports differences
+
+	Helps to find split-horizon setups and DNS hijacking.
+*/
+type ResolverCompareService struct {
+	resolvers  []models.NamedResolver
+	lookupTime time.Duration
+	maxWorkers int
+}
+
+// NewResolverCompareService - init compare service over at least two resolvers
+func NewResolverCompareService(workers int, resolvers ...models.NamedResolver) (*ResolverCompareService, error) {
+	if len(resolvers) < 2 {
+		return nil, errors.New("at least two resolvers are required for comparison")
+	}
+
+	return &ResolverCompareService{
+		resolvers:  resolvers,
+		maxWorkers: microutils.InitWorkersCountCurrently(workers),
+	}, nil
+}
+
+// SetLookupTimeout - set deadline of every single DNS lookup. Disabled if d <= 0
+func (cs *ResolverCompareService) SetLookupTimeout(d time.Duration) {
+	cs.lookupTime = d
+}
+
+/*
+Compare - resolve names with every resolver at once and compare answers
+
+	IP addresses are skipped. Name disagrees if any resolver has other IP set, NS list,
+	much lower address TTL or fails while others not.
+*/
+func (cs *ResolverCompareService) Compare(ctx context.Context, names []string) (map[string]models.ResolveComparison, error) {
+	if len(names) < 1 {
+		return map[string]models.ResolveComparison{}, errors.New("resolving name pool is empty")
+	}
+
+	var (
+		result = map[string]models.ResolveComparison{}
+		mu     = sync.Mutex{}
+		wg     = &sync.WaitGroup{}
+		tp     = microutils.NewTicketPool(cs.maxWorkers)
+	)
+	defer tp.ClosePool()
+
+	for _, name := range names {
+		if isIP(name) {
+			continue
+		}
+
+		wg.Go(func() {
+			tp.CatchTicket()
+			defer tp.PutTicket()
+
+			cmp := cs.compareName(ctx, name)
+
+			mu.Lock()
+			result[name] = cmp
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+	return result, nil
+}
+
+func (cs *ResolverCompareService) compareName(ctx context.Context, name string) models.ResolveComparison {
+	var (
+		answers = make(map[string]models.ResolverAnswer, len(cs.resolvers))
+		mu      = sync.Mutex{}
+		wg      = &sync.WaitGroup{}
+	)
+
+	for _, nr := range cs.resolvers {
+		wg.Go(func() {
+			ans := cs.answer(ctx, nr.Resolver, name)
+
+			mu.Lock()
+			answers[nr.Name] = ans
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+
+	cmp := models.ResolveComparison{
+		Answers:       answers,
+		Disagreements: cs.disagreements(answers),
+	}
+	cmp.Agree = len(cmp.Disagreements) == 0
+
+	return cmp
+}
+
+// answer - A, AAAA and NS records of name with single resolver
+func (cs *ResolverCompareService) answer(ctx context.Context, rv models.Resolver, name string) models.ResolverAnswer {
+	var (
+		ans   = models.ResolverAnswer{}
+		start = time.Now()
+		errs  []error
+	)
+
+	for _, rtype := range []string{"A", "AAAA"} {
+		lookupCtx, cancel := withLookupTimeout(ctx, cs.lookupTime)
+		records, err := rv.ResolveRecords(lookupCtx, name, rtype)
+		cancel()
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, rec := range records {
+			ans.IPs = append(ans.IPs, rec.Data)
+			if rec.TTL > 0 && (ans.TTL == 0 || rec.TTL < ans.TTL) {
+				ans.TTL = rec.TTL
+			}
+		}
+	}
+
+	if len(ans.IPs) == 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		ans.ErrorIPs = strings.Join(msgs, "; ")
+		ans.ErrorIPsCode = models.ErrorCodeOf(errors.Join(errs...))
+	}
+
+	lookupCtx, cancel := withLookupTimeout(ctx, cs.lookupTime)
+	ns, err := rv.ResolveNS(lookupCtx, name)
+	cancel()
+
+	if err != nil {
+		ans.ErrorNS, ans.ErrorNSCode = err.Error(), models.ErrorCodeOf(err)
+	}
+
+	for _, n := range ns {
+		ans.NameServers = append(ans.NameServers, strings.ToLower(strings.TrimSuffix(n, ".")))
+	}
+
+	ans.IPs = sortedUnique(ans.IPs)
+	ans.NameServers = sortedUnique(ans.NameServers)
+	ans.DurationMs = time.Since(start).Milliseconds()
+
+	return ans
+}
+
+// disagreements - differences of resolver answers, resolvers are grouped by equal values
+func (cs *ResolverCompareService) disagreements(answers map[string]models.ResolverAnswer) []string {
+	var diff []string
+
+	ipKey := func(a models.ResolverAnswer) string {
+		if len(a.IPs) == 0 {
+			return "failed"
+		}
+		return "[" + strings.Join(a.IPs, " ") + "]"
+	}
+
+	nsKey := func(a models.ResolverAnswer) string {
+		if len(a.NameServers) == 0 {
+			return "failed"
+		}
+		return "[" + strings.Join(a.NameServers, " ") + "]"
+	}
+
+	if d := cs.groupBy(answers, ipKey); d != "" {
+		diff = append(diff, "ip: "+d)
+	}
+
+	if d := cs.groupBy(answers, nsKey); d != "" {
+		diff = append(diff, "ns: "+d)
+	}
+
+	var maxTTL uint32
+	for _, a := range answers {
+		maxTTL = max(maxTTL, a.TTL)
+	}
+
+	var low []string
+	for _, nr := range cs.resolvers {
+		// 0 - unknown TTL (system resolver) or failed resolve
+		if ttl := answers[nr.Name].TTL; ttl > 0 && ttl*compareTTLRatio < maxTTL {
+			low = append(low, fmt.Sprintf("%s %d", nr.Name, ttl))
+		}
+	}
+
+	if len(low) > 0 {
+		diff = append(diff, fmt.Sprintf("ttl: max %d, %s", maxTTL, strings.Join(low, ", ")))
+	}
+
+	return diff
+}
+
+// groupBy - "google, cloudflare: [1.1.1.1]; local: [10.0.0.1]" or empty string if all values are equal
+func (cs *ResolverCompareService) groupBy(answers map[string]models.ResolverAnswer, key func(models.ResolverAnswer) string) string {
+	var (
+		keys   []string
+		groups = map[string][]string{}
+	)
+
+	for _, nr := range cs.resolvers {
+		k := key(answers[nr.Name])
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], nr.Name)
+	}
+
+	if len(keys) < 2 {
+		return ""
+	}
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = strings.Join(groups[k], ", ") + ": " + k
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func sortedUnique(values []string) []string {
+	slices.Sort(values)
+	return slices.Compact(values)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// nsResolver - fakeResolver with NS names of "name NS" records
+type nsResolver struct {
+	*fakeResolver
+}
+
+func (r nsResolver) ResolveNS(ctx context.Context, s string) ([]string, error) {
+	records, err := r.ResolveRecords(ctx, s, "NS")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(records))
+	for i, rec := range records {
+		names[i] = rec.Data
+	}
+	return names, nil
+}
+
+// zone - A records with TTL and NS records of example.test
+func zone(ttl uint32, ns []string, ips ...string) map[string][]models.DnsRecord {
+	records := map[string][]models.DnsRecord{}
+	for _, ip := range ips {
+		records["example.test A"] = append(records["example.test A"], models.DnsRecord{Type: "A", Data: ip, TTL: ttl})
+	}
+	for _, n := range ns {
+		records["example.test NS"] = append(records["example.test NS"], models.DnsRecord{Type: "NS", Data: n})
+	}
+	return records
+}
+
+func TestCompareDisagreements(t *testing.T) {
+	var (
+		ns    = []string{"ns1.example.test.", "ns2.example.test."}
+		nsAlt = []string{"NS2.Example.Test", "ns1.example.test"}
+	)
+
+	tests := []struct {
+		name      string
+		resolvers []map[string][]models.DnsRecord
+		want      []string
+	}{
+		{
+			name: "same answers in other order and case",
+			resolvers: []map[string][]models.DnsRecord{
+				zone(300, ns, "192.0.2.1", "192.0.2.2"),
+				zone(300, nsAlt, "192.0.2.2", "192.0.2.1", "192.0.2.1"),
+			},
+		},
+		{
+			name: "cached TTL counted down",
+			resolvers: []map[string][]models.DnsRecord{
+				zone(300, ns, "192.0.2.1"),
+				zone(150, ns, "192.0.2.1"),
+				// unknown TTL of system resolver
+				zone(0, ns, "192.0.2.1"),
+			},
+		},
+		{
+			name: "other IPs",
+			resolvers: []map[string][]models.DnsRecord{
+				zone(300, ns, "192.0.2.1"),
+				zone(300, ns, "10.0.0.1"),
+				zone(300, ns, "192.0.2.1"),
+			},
+			want: []string{"ip: r0, r2: [192.0.2.1]; r1: [10.0.0.1]"},
+		},
+		{
+			name: "failed resolver",
+			resolvers: []map[string][]models.DnsRecord{
+				zone(300, ns, "192.0.2.1"),
+				{},
+			},
+			want: []string{"ip: r0: [192.0.2.1]; r1: failed", "ns: r0: [ns1.example.test ns2.example.test]; r1: failed"},
+		},
+		{
+			name: "other nameservers",
+			resolvers: []map[string][]models.DnsRecord{
+				zone(300, ns, "192.0.2.1"),
+				zone(300, []string{"ns.hijack.test"}, "192.0.2.1"),
+			},
+			want: []string{"ns: r0: [ns1.example.test ns2.example.test]; r1: [ns.hijack.test]"},
+		},
+		{
+			name: "low TTL",
+			resolvers: []map[string][]models.DnsRecord{
+				zone(3600, ns, "192.0.2.1"),
+				zone(60, ns, "192.0.2.1"),
+				zone(1800, ns, "192.0.2.1"),
+			},
+			want: []string{"ttl: max 3600, r1 60"},
+		},
+		{
+			name: "every resolver failed",
+			resolvers: []map[string][]models.DnsRecord{
+				{},
+				{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resolvers []models.NamedResolver
+			for i, records := range tt.resolvers {
+				resolvers = append(resolvers, models.NamedResolver{
+					Name:     fmt.Sprintf("r%d", i),
+					Resolver: nsResolver{&fakeResolver{records: records}},
+				})
+			}
+
+			cs, err := NewResolverCompareService(2, resolvers...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compared, err := cs.Compare(context.Background(), []string{"example.test", "192.0.2.1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(compared) != 1 {
+				t.Fatalf("got %d names, want only example.test without IP", len(compared))
+			}
+
+			cmp := compared["example.test"]
+			if !reflect.DeepEqual(cmp.Disagreements, tt.want) || cmp.Agree != (len(tt.want) == 0) {
+				t.Errorf("got agree %v with %q, want %q", cmp.Agree, cmp.Disagreements, tt.want)
+			}
+			if len(cmp.Answers) != len(tt.resolvers) {
+				t.Errorf("got %d answers, want %d", len(cmp.Answers), len(tt.resolvers))
+			}
+		})
+	}
+}
+
+func TestCompareAnswer(t *testing.T) {
+	cs, _ := NewResolverCompareService(1,
+		models.NamedResolver{Name: "a", Resolver: nsResolver{&fakeResolver{records: zone(300, []string{"NS1.example.test."}, "192.0.2.2", "192.0.2.1")}}},
+		models.NamedResolver{Name: "b", Resolver: nsResolver{&fakeResolver{}}},
+	)
+
+	compared, _ := cs.Compare(context.Background(), []string{"example.test"})
+	answers := compared["example.test"].Answers
+
+	if a := answers["a"]; !reflect.DeepEqual(a.IPs, []string{"192.0.2.1", "192.0.2.2"}) || a.TTL != 300 ||
+		!reflect.DeepEqual(a.NameServers, []string{"ns1.example.test"}) || a.ErrorIPs != "" {
+		t.Errorf("got %+v, want sorted IPs and normalized nameservers", a)
+	}
+	if b := answers["b"]; b.ErrorIPsCode != models.CodeNXDOMAIN || b.ErrorNSCode != models.CodeNXDOMAIN || b.ErrorIPs == "" {
+		t.Errorf("got %+v, want nxdomain of IPs and nameservers", b)
+	}
+
+	if _, err := NewResolverCompareService(1, models.NamedResolver{Name: "a", Resolver: &fakeResolver{}}); err == nil {
+		t.Error("single resolver is accepted")
+	}
+	if _, err := cs.Compare(context.Background(), nil); err == nil {
+		t.Error("empty names are accepted")
+	}
+}
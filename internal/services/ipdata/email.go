@@ -0,0 +1,64 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	microutils "github.com/eterline/micro-utils"
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// EmailCheckService - email security posture checks of domain pool
+type EmailCheckService struct {
+	checker    models.EmailChecker
+	maxWorkers int
+}
+
+func NewEmailCheckService(workers int, checker models.EmailChecker) *EmailCheckService {
+	return &EmailCheckService{
+		checker:    checker,
+		maxWorkers: microutils.InitWorkersCountCurrently(workers),
+	}
+}
+
+// Check - check domains at once. IP addresses are skipped, domains are keyed in lowercase
+func (es *EmailCheckService) Check(ctx context.Context, domains []string) (map[string]models.EmailPosture, error) {
+	if len(domains) < 1 {
+		return map[string]models.EmailPosture{}, errors.New("checking domain pool is empty")
+	}
+
+	var (
+		result = map[string]models.EmailPosture{}
+		mu     = sync.Mutex{}
+		wg     = &sync.WaitGroup{}
+		tp     = microutils.NewTicketPool(es.maxWorkers)
+	)
+	defer tp.ClosePool()
+
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if domain == "" || isIP(domain) {
+			continue
+		}
+
+		wg.Go(func() {
+			tp.CatchTicket()
+			defer tp.PutTicket()
+
+			posture := es.checker.CheckEmail(ctx, domain)
+
+			mu.Lock()
+			result[domain] = posture
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+	return result, nil
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	microutils "github.com/eterline/micro-utils"
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// wildcardProbes - count of random labels resolved to detect wildcard DNS. Wildcards of CDNs can answer with rotating IPs
+const wildcardProbes = 3
+
+/*
+SubdomainEnumService - subdomain enumeration of base domains by wordlist
+
+	Candidates "word.base" are resolved concurrently, max workers count is shared by all candidates.
+*/
+type SubdomainEnumService struct {
+	rv         models.Resolver
+	lookupTime time.Duration
+	maxWorkers int
+}
+
+func NewSubdomainEnumService(workers int, rv models.Resolver) *SubdomainEnumService {
+	return &SubdomainEnumService{
+		rv:         rv,
+		maxWorkers: microutils.InitWorkersCountCurrently(workers),
+	}
+}
+
+// SetLookupTimeout - set deadline of every single DNS lookup. Disabled if d <= 0
+func (es *SubdomainEnumService) SetLookupTimeout(d time.Duration) {
+	es.lookupTime = d
+}
+
+/*
+Enumerate - resolve every word as subdomain of base
+
+	Words are lowercased, blank and repeated ones are skipped. Wildcard DNS is detected by random labels before
+	enumeration, candidates which resolve only to wildcard IPs are dropped. Found names are sorted.
+*/
+func (es *SubdomainEnumService) Enumerate(ctx context.Context, base string, words []string) (models.SubdomainEnum, error) {
+	base = strings.ToLower(strings.Trim(strings.TrimSpace(base), "."))
+	if base == "" || isIP(base) {
+		return models.SubdomainEnum{}, errors.New("base domain is required for enumeration")
+	}
+
+	if len(words) < 1 {
+		return models.SubdomainEnum{}, errors.New("enumeration wordlist is empty")
+	}
+
+	enum := models.SubdomainEnum{
+		Base:  base,
+		Found: []string{},
+	}
+
+	wildcard, err := es.wildcardIPs(ctx, base)
+	if err != nil {
+		return models.SubdomainEnum{}, fmt.Errorf("wildcard detection of %s failed: %w", base, err)
+	}
+	enum.Wildcard, enum.WildcardIPs = len(wildcard) > 0, wildcard
+
+	var (
+		mu   = sync.Mutex{}
+		wg   = &sync.WaitGroup{}
+		tp   = microutils.NewTicketPool(es.maxWorkers)
+		seen = map[string]struct{}{}
+	)
+	defer tp.ClosePool()
+
+	for _, word := range words {
+		word = strings.ToLower(strings.Trim(strings.TrimSpace(word), "."))
+		if _, ok := seen[word]; ok || word == "" {
+			continue
+		}
+		seen[word] = struct{}{}
+
+		name := word + "." + base
+		enum.Candidates++
+
+		wg.Go(func() {
+			tp.CatchTicket()
+			defer tp.PutTicket()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			ips, err := es.resolve(ctx, name)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err != nil:
+				enum.Failed++
+			case len(ips) == 0:
+			case enum.Wildcard && onlyWildcard(ips, wildcard):
+				enum.Dropped++
+			default:
+				enum.Found = append(enum.Found, name)
+			}
+		})
+	}
+
+	wg.Wait()
+	slices.Sort(enum.Found)
+
+	return enum, ctx.Err()
+}
+
+// resolve - IPs of name, nil without error if name does not exist or has no addresses
+func (es *SubdomainEnumService) resolve(ctx context.Context, name string) ([]net.IP, error) {
+	lookupCtx, cancel := withLookupTimeout(ctx, es.lookupTime)
+	defer cancel()
+
+	ips, err := es.rv.ResolveIP(lookupCtx, name)
+	if err != nil {
+		switch models.ErrorCodeOf(err) {
+		case models.CodeNXDOMAIN, models.CodeNODATA:
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return ips, nil
+}
+
+/*
+wildcardIPs - IPs of random labels under base, empty if base has no wildcard records
+
+	Failed probe is no wildcard evidence: error is returned only if every probe fails.
+*/
+func (es *SubdomainEnumService) wildcardIPs(ctx context.Context, base string) ([]net.IP, error) {
+	var (
+		wildcard []net.IP
+		errs     []error
+	)
+
+	for range wildcardProbes {
+		label := make([]byte, 8)
+		if _, err := rand.Read(label); err != nil {
+			return nil, fmt.Errorf("failed to make random label: %w", err)
+		}
+
+		ips, err := es.resolve(ctx, hex.EncodeToString(label)+"."+base)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, ip := range ips {
+			if !slices.ContainsFunc(wildcard, ip.Equal) {
+				wildcard = append(wildcard, ip)
+			}
+		}
+	}
+
+	if len(errs) == wildcardProbes {
+		return nil, errors.Join(errs...)
+	}
+	return wildcard, nil
+}
+
+// onlyWildcard - every IP of candidate is wildcard answer
+func onlyWildcard(ips, wildcard []net.IP) bool {
+	for _, ip := range ips {
+		if !slices.ContainsFunc(wildcard, ip.Equal) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+/*
+wildcardResolver - fake resolver with wildcard records of base: names without records
+get next of wildcard answers in turn (CDN rotation). First failNames lookups of such names time out.
+*/
+type wildcardResolver struct {
+	*fakeResolver
+	base      string
+	wildcard  [][]string
+	failNames int
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (w *wildcardResolver) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
+	// wildcard doesn't answer for existing names
+	_, exists := w.errs[s+" A"]
+	for key := range w.records {
+		exists = exists || strings.HasPrefix(key, s+" ")
+	}
+	if exists || !strings.HasSuffix(s, "."+w.base) {
+		return w.fakeResolver.ResolveIP(ctx, s)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.calls++
+	if w.calls <= w.failNames {
+		return nil, &models.LookupError{Code: models.CodeTimeout, Err: context.DeadlineExceeded}
+	}
+	if len(w.wildcard) == 0 {
+		return w.fakeResolver.ResolveIP(ctx, s)
+	}
+
+	var ips []net.IP
+	for _, ip := range w.wildcard[(w.calls-1)%len(w.wildcard)] {
+		ips = append(ips, net.ParseIP(ip))
+	}
+	return ips, nil
+}
+
+func addrs(ips ...string) []models.DnsRecord {
+	var records []models.DnsRecord
+	for _, ip := range ips {
+		records = append(records, models.DnsRecord{Type: "A", Data: ip})
+	}
+	return records
+}
+
+func TestEnumerate(t *testing.T) {
+	records := map[string][]models.DnsRecord{
+		"www.example.test A": addrs("198.51.100.1"),
+		// real record mixed with wildcard IP is kept
+		"api.example.test A": addrs("192.0.2.100", "198.51.100.2"),
+		// record of wildcard IPs only is dropped
+		"cdn.example.test A": addrs("192.0.2.101"),
+		// name with AAAA only has no A records, wildcard doesn't answer for it
+		"v6.example.test AAAA": {{Type: "AAAA", Data: "2001:db8::1"}},
+	}
+	errs := map[string]error{
+		"broken.example.test A": &models.LookupError{Code: models.CodeSERVFAIL, Err: context.DeadlineExceeded},
+	}
+	words := []string{"www", " WWW. ", "", "api", "cdn", "broken", "v6", "nothere", "api"}
+
+	tests := []struct {
+		name      string
+		wildcard  [][]string
+		failNames int
+		want      models.SubdomainEnum
+	}{
+		{
+			name: "no wildcard",
+			want: models.SubdomainEnum{
+				Found: []string{"api.example.test", "cdn.example.test", "www.example.test"}, Candidates: 6, Failed: 1,
+			},
+		},
+		{
+			name:      "failed probes",
+			failNames: wildcardProbes - 1,
+			want: models.SubdomainEnum{
+				Found: []string{"api.example.test", "cdn.example.test", "www.example.test"}, Candidates: 6, Failed: 1,
+			},
+		},
+		{
+			name:     "rotating wildcard",
+			wildcard: [][]string{{"192.0.2.100"}, {"192.0.2.101", "192.0.2.100"}},
+			want: models.SubdomainEnum{
+				Found: []string{"api.example.test", "www.example.test"}, Candidates: 6, Failed: 1,
+				Wildcard: true, WildcardIPs: []net.IP{net.ParseIP("192.0.2.100"), net.ParseIP("192.0.2.101")},
+				// cdn and nothere
+				Dropped: 2,
+			},
+		},
+		{
+			name:      "wildcard with failed probe",
+			wildcard:  [][]string{{"192.0.2.100", "192.0.2.101"}},
+			failNames: 1,
+			want: models.SubdomainEnum{
+				Found: []string{"api.example.test", "www.example.test"}, Candidates: 6, Failed: 1,
+				Wildcard: true, WildcardIPs: []net.IP{net.ParseIP("192.0.2.100"), net.ParseIP("192.0.2.101")},
+				Dropped: 2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rv := &wildcardResolver{
+				fakeResolver: &fakeResolver{records: records, errs: errs},
+				base:         "example.test",
+				wildcard:     tt.wildcard,
+				failNames:    tt.failNames,
+			}
+
+			enum, err := NewSubdomainEnumService(4, rv).Enumerate(context.Background(), " Example.TEST. ", words)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.want.Base = "example.test"
+			if !reflect.DeepEqual(enum, tt.want) {
+				t.Errorf("got %+v, want %+v", enum, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnumerateErrors(t *testing.T) {
+	rv := &wildcardResolver{
+		fakeResolver: &fakeResolver{},
+		base:         "example.test",
+		failNames:    wildcardProbes,
+	}
+	es := NewSubdomainEnumService(4, rv)
+
+	// every probe failed: wildcard answers can't be told from real ones
+	if _, err := es.Enumerate(context.Background(), "example.test", []string{"www"}); models.ErrorCodeOf(err) != models.CodeTimeout {
+		t.Errorf("got %v, want timeout of wildcard detection", err)
+	}
+
+	for _, base := range []string{"", " . ", "192.0.2.1"} {
+		if _, err := es.Enumerate(context.Background(), base, []string{"www"}); err == nil {
+			t.Errorf("base %q is accepted", base)
+		}
+	}
+	if _, err := es.Enumerate(context.Background(), "example.test", nil); err == nil {
+		t.Error("empty wordlist is accepted")
+	}
+}
@@ -7,8 +7,11 @@ package ipdata
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"net/netip"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -32,10 +35,54 @@ type IPstorage interface {
 	Save(ctx context.Context, ip net.IP, obj models.AboutIPobject) error
 }
 
+// CacheMode - IPstorage usage policy of IP resumes
+type CacheMode string
+
+const (
+	// CacheDefault - read from cache, resume and save on miss
+	CacheDefault CacheMode = "default"
+	// CacheOffline - read from cache only, never resume with ResumerIP
+	CacheOffline CacheMode = "offline"
+	// CacheRefresh - don't read from cache, resume and overwrite
+	CacheRefresh CacheMode = "refresh"
+	// CacheBypass - don't touch cache at all
+	CacheBypass CacheMode = "bypass"
+)
+
+// ParseCacheMode - parse cache mode name. Empty name is CacheDefault
+func ParseCacheMode(s string) (CacheMode, error) {
+	switch m := CacheMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return CacheDefault, nil
+	case CacheDefault, CacheOffline, CacheRefresh, CacheBypass:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown cache mode: %s", s)
+}
+
+func (m CacheMode) readable() bool {
+	return m == CacheDefault || m == CacheOffline
+}
+
+func (m CacheMode) writable() bool {
+	return m == CacheDefault || m == CacheRefresh
+}
+
 type NetworkScrapeService struct {
 	resolv     models.Resolver
 	resumer    models.ResumerIP
 	storage    IPstorage
+	owner      models.OwnerLookup
+	reverse    bool
+	rtypes     []string
+	chain      bool
+	tracer     models.Tracer
+	dnssec     models.DnssecValidator
+	transfer   models.ZoneTransferrer
+	subnetRv   models.SubnetResolver
+	subnets    []netip.Prefix
+	cacheMode  CacheMode
+	lookupTime time.Duration
 	maxWorkers int
 }
 
@@ -51,10 +98,75 @@ func NewNetworkScrapeService(
 		resolv:     rv,
 		resumer:    ru,
 		storage:    st,
+		cacheMode:  CacheDefault,
 		maxWorkers: microutils.InitWorkersCountCurrently(workers),
 	}
 }
 
+// SetCacheMode - set IPstorage usage policy. Default is CacheDefault
+func (rs *NetworkScrapeService) SetCacheMode(m CacheMode) {
+	rs.cacheMode = m
+}
+
+// SetLookupTimeout - set deadline of every single DNS or IP info lookup. Disabled if d <= 0
+func (rs *NetworkScrapeService) SetLookupTimeout(d time.Duration) {
+	rs.lookupTime = d
+}
+
+// SetOwnerLookup - enable RDAP/WHOIS ownership lookup of domains and IPs. Disabled if nil
+func (rs *NetworkScrapeService) SetOwnerLookup(ol models.OwnerLookup) {
+	rs.owner = ol
+}
+
+// SetReverseLookup - enable PTR lookup with FCrDNS check of resumed IPs
+func (rs *NetworkScrapeService) SetReverseLookup(enable bool) {
+	rs.reverse = enable
+}
+
+// SetRecordTypes - additional record types to query for every domain: "MX", "TXT", "SOA" etc.
+func (rs *NetworkScrapeService) SetRecordTypes(types []string) {
+	rs.rtypes = types
+}
+
+// SetChainTrace - enable CNAME chain tracing with authoritative nameservers of every step
+func (rs *NetworkScrapeService) SetChainTrace(enable bool) {
+	rs.chain = enable
+}
+
+// SetTracer - enable iterative resolution trace (delegation path from root servers) of every domain
+func (rs *NetworkScrapeService) SetTracer(t models.Tracer) {
+	rs.tracer = t
+}
+
+// SetDnssecValidator - enable DNSSEC validation of every domain. Disabled if nil
+func (rs *NetworkScrapeService) SetDnssecValidator(v models.DnssecValidator) {
+	rs.dnssec = v
+}
+
+// SetZoneTransfer - try zone transfer of every domain from its nameservers. Disabled if nil
+func (rs *NetworkScrapeService) SetZoneTransfer(zt models.ZoneTransferrer) {
+	rs.transfer = zt
+}
+
+// SetClientSubnets - resolve IPs of every domain for clients of each subnet with EDNS Client Subnet option
+func (rs *NetworkScrapeService) SetClientSubnets(rv models.SubnetResolver, subnets []netip.Prefix) {
+	rs.subnetRv = rv
+	rs.subnets = subnets
+}
+
+// lookupContext - ctx with lookup deadline
+func (rs *NetworkScrapeService) lookupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withLookupTimeout(ctx, rs.lookupTime)
+}
+
+// withLookupTimeout - ctx with deadline d. Only cancelable if d <= 0
+func withLookupTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
 func isIP(s string) bool {
 	return ipRegex.MatchString(strings.TrimSpace(s))
 }
@@ -82,57 +194,317 @@ func (rs *NetworkScrapeService) ResolveDNS(ctx context.Context, names []string)
 			tp.CatchTicket()
 			defer tp.PutTicket()
 
-			startTime := time.Now()
+			res := rs.resolveName(ctx, name)
+
+			mu.Lock()
+			resolvPool[name] = res
+			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+	return resolvPool, nil
+}
+
+// ScrapeResult - resolve and IP resumes of single name
+type ScrapeResult struct {
+	Name    string
+	Resolve models.AboutResolve
+	Resumes []models.ResumeAboutIP
+}
+
+const (
+	// streamBatchSize - unique IPs of finished streamed names that are resumed at once
+	streamBatchSize = 100
+	// streamFlushInterval - max wait of finished name for IPs of other names to batch with
+	streamFlushInterval = 200 * time.Millisecond
+)
+
+/*
+StreamScrape - resolve and resume every name of channel as soon as it comes
 
-			if isIP(name) {
-				res := models.AboutResolve{
-					IPs:         []net.IP{net.ParseIP(name)},
-					NameServers: []string{},
+	Names are resolved by maxWorkers workers. IPs of finished names are collected into batches
+	(streamBatchSize unique IPs or streamFlushInterval wait), every batch is resumed by single FetchAboutIP call.
+	Names are emitted one at a time when their IPs are resumed, names without IPs - at once.
+	Only pending batch is kept, so names count is not limited by memory.
+	Returns when names channel is closed and drained or ctx is done.
+*/
+func (rs *NetworkScrapeService) StreamScrape(ctx context.Context, names <-chan string, emit func(ScrapeResult)) {
+	var (
+		resolved = make(chan ScrapeResult, rs.maxWorkers)
+		wg       = &sync.WaitGroup{}
+	)
+
+	for range rs.maxWorkers {
+		wg.Go(func() {
+			for {
+				var (
+					name string
+					ok   bool
+				)
+
+				select {
+				case <-ctx.Done():
+					return
+				case name, ok = <-names:
+					if !ok {
+						return
+					}
+				}
+
+				resolved <- ScrapeResult{
+					Name:    name,
+					Resolve: rs.resolveName(ctx, name),
 				}
-				res.CalcDuration(startTime)
-				mu.Lock()
-				resolvPool[name] = res
-				mu.Unlock()
+			}
+		})
+	}
+
+	go func() {
+		wg.Wait()
+		close(resolved)
+	}()
+
+	rs.resumeStream(ctx, resolved, emit)
+}
+
+// resumeStream - resume IPs of resolved names by batches, emit names when their IPs are resumed
+func (rs *NetworkScrapeService) resumeStream(ctx context.Context, resolved <-chan ScrapeResult, emit func(ScrapeResult)) {
+	var (
+		pending []ScrapeResult
+		ips     []net.IP
+		seen    = map[string]struct{}{}
+		timer   = time.NewTimer(streamFlushInterval)
+		flushC  <-chan time.Time
+	)
+	defer timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		flushC = nil
+
+		if len(pending) == 0 {
+			return
+		}
+
+		resumes, _ := rs.FetchAboutIP(ctx, ips)
+
+		byIP := make(map[string]models.ResumeAboutIP, len(resumes))
+		for _, r := range resumes {
+			byIP[r.RequestIP.String()] = r
+		}
+
+		for _, res := range pending {
+			for _, ip := range res.Resolve.AllIPs() {
+				res.Resumes = append(res.Resumes, byIP[ip.String()])
+			}
+			emit(res)
+		}
+
+		pending, ips, seen = nil, nil, map[string]struct{}{}
+	}
+
+	for {
+		select {
+		case res, ok := <-resolved:
+			if !ok {
+				flush()
 				return
 			}
 
-			var (
-				res      models.AboutResolve
-				wgWorker sync.WaitGroup
-			)
+			addrs := res.Resolve.AllIPs()
+			if len(addrs) == 0 {
+				emit(res)
+				continue
+			}
 
-			wgWorker.Go(func() {
-				ips, err := rs.resolv.ResolveIP(ctx, name)
-				if err != nil {
-					res.ErrorIPs = err.Error()
-					return
+			pending = append(pending, res)
+			for _, ip := range addrs {
+				if _, ok := seen[ip.String()]; !ok {
+					seen[ip.String()] = struct{}{}
+					ips = append(ips, ip)
 				}
-				res.IPs = ips
-			})
+			}
+
+			if len(ips) >= streamBatchSize {
+				flush()
+				continue
+			}
+
+			if flushC == nil {
+				timer.Reset(streamFlushInterval)
+				flushC = timer.C
+			}
+
+		case <-flushC:
+			flush()
+		}
+	}
+}
+
+// resolveName - resolve single name with every enabled lookup. IP addresses are passed as is
+func (rs *NetworkScrapeService) resolveName(ctx context.Context, name string) models.AboutResolve {
+	startTime := time.Now()
+
+	if isIP(name) {
+		res := models.AboutResolve{
+			IPs:         []net.IP{net.ParseIP(name)},
+			NameServers: []string{},
+		}
+		res.CalcDuration(startTime)
+		return res
+	}
+
+	var (
+		res      models.AboutResolve
+		wgWorker sync.WaitGroup
+	)
+
+	if err := ctx.Err(); err != nil {
+		res.ErrorIPs, res.ErrorIPsCode = err.Error(), models.ErrorCodeOf(err)
+		res.ErrorNS, res.ErrorNSCode = err.Error(), models.ErrorCodeOf(err)
+		res.CalcDuration(startTime)
+		return res
+	}
+
+	wgWorker.Go(func() {
+		ctx, cancel := rs.lookupContext(ctx)
+		defer cancel()
+
+		ips, err := rs.resolv.ResolveIP(ctx, name)
+		if err != nil {
+			res.ErrorIPs, res.ErrorIPsCode = err.Error(), models.ErrorCodeOf(err)
+			return
+		}
+		res.IPs = ips
+	})
+
+	wgWorker.Go(func() {
+		ctx, cancel := rs.lookupContext(ctx)
+		defer cancel()
 
+		ns, err := rs.resolv.ResolveNS(ctx, name)
+		if err != nil {
+			res.ErrorNS, res.ErrorNSCode = err.Error(), models.ErrorCodeOf(err)
+			return
+		}
+		res.NameServers = ns
+	})
+
+	var recMu sync.Mutex
+
+	for _, rtype := range rs.rtypes {
+		wgWorker.Go(func() {
+			ctx, cancel := rs.lookupContext(ctx)
+			defer cancel()
+
+			records, err := rs.resolv.ResolveRecords(ctx, name, rtype)
+
+			recMu.Lock()
+			defer recMu.Unlock()
+
+			if err != nil {
+				if res.ErrorRecords == nil {
+					res.ErrorRecords = map[string]string{}
+					res.ErrorRecordsCode = map[string]models.ErrorCode{}
+				}
+				res.ErrorRecords[rtype] = err.Error()
+				res.ErrorRecordsCode[rtype] = models.ErrorCodeOf(err)
+				return
+			}
+
+			if res.Records == nil {
+				res.Records = map[string][]models.DnsRecord{}
+			}
+			res.Records[rtype] = records
+		})
+	}
+
+	if rs.chain {
+		wgWorker.Go(func() {
+			chain := rs.traceChain(ctx, name)
+			res.Chain = &chain
+		})
+	}
+
+	if rs.tracer != nil {
+		wgWorker.Go(func() {
+			ctx, cancel := rs.lookupContext(ctx)
+			defer cancel()
+
+			// error is kept in trace object itself
+			trace, _ := rs.tracer.Trace(ctx, name, "A")
+			res.Trace = &trace
+		})
+	}
+
+	if rs.dnssec != nil {
+		wgWorker.Go(func() {
+			ctx, cancel := rs.lookupContext(ctx)
+			defer cancel()
+
+			// error is kept in status reason
+			status, _ := rs.dnssec.ValidateDNSSEC(ctx, name)
+			res.Dnssec = &status
+		})
+	}
+
+	if rs.subnetRv != nil && len(rs.subnets) > 0 {
+		var subnetMu sync.Mutex
+		res.Subnets = make(map[string]models.SubnetAnswer, len(rs.subnets))
+
+		for _, subnet := range rs.subnets {
 			wgWorker.Go(func() {
-				ns, err := rs.resolv.ResolveNS(ctx, name)
+				ctx, cancel := rs.lookupContext(ctx)
+				defer cancel()
+
+				ans, err := rs.subnetRv.ResolveIPSubnet(ctx, name, subnet)
 				if err != nil {
-					res.ErrorNS = err.Error()
-					return
+					ans.ErrorIPs, ans.ErrorIPsCode = err.Error(), models.ErrorCodeOf(err)
 				}
-				res.NameServers = ns
+
+				subnetMu.Lock()
+				res.Subnets[subnet.String()] = ans
+				subnetMu.Unlock()
 			})
+		}
+	}
 
-			wgWorker.Wait()
-			res.CalcDuration(startTime)
+	if rs.owner != nil {
+		wgWorker.Go(func() {
+			ctx, cancel := rs.lookupContext(ctx)
+			defer cancel()
 
-			mu.Lock()
-			resolvPool[name] = res
-			mu.Unlock()
+			owner, err := rs.owner.LookupDomain(ctx, name)
+			if err != nil {
+				owner.Err = err.Error()
+			}
+			res.Owner = &owner
 		})
 	}
 
-	wg.Wait()
-	return resolvPool, nil
+	wgWorker.Wait()
+
+	// nameservers are known only after NS lookup, transfer of big zone isn't limited by lookup timeout
+	if rs.transfer != nil && len(res.NameServers) > 0 {
+		transfer, err := rs.transfer.TransferZone(ctx, name, res.NameServers)
+		if err != nil {
+			transfer.Err = err.Error()
+		}
+		res.Transfer = &transfer
+	}
+
+	res.CalcDuration(startTime)
+	return res
 }
 
-func (rs *NetworkScrapeService) FetchAboutIP(ipPool []net.IP) ([]models.ResumeAboutIP, error) {
+/*
+FetchAboutIP - resume IP pool with respect of cache mode
+
+	If resumer implements models.BatchResumerIP, cache misses are resumed by one batch call.
+	When ctx is done, returns partial result: unfinished resumes contain ctx error.
+*/
+func (rs *NetworkScrapeService) FetchAboutIP(ctx context.Context, ipPool []net.IP) ([]models.ResumeAboutIP, error) {
 	if ipPool == nil {
 		return []models.ResumeAboutIP{}, errors.New("ip pool is nil")
 	}
@@ -141,45 +513,348 @@ func (rs *NetworkScrapeService) FetchAboutIP(ipPool []net.IP) ([]models.ResumeAb
 		return []models.ResumeAboutIP{}, errors.New("ip pool is empty")
 	}
 
+	resumes := make([]models.ResumeAboutIP, len(ipPool))
+	for i, ip := range ipPool {
+		resumes[i].RequestIP = ip
+	}
+
+	missed := rs.fromStorage(ctx, resumes)
+
+	switch batch, ok := rs.resumer.(models.BatchResumerIP); {
+	case rs.cacheMode == CacheOffline:
+		for _, i := range missed {
+			resumes[i].Err = "ip resume not found in cache (offline mode)"
+			resumes[i].ErrCode = models.CodeNotCached
+		}
+	case ok:
+		rs.resumeBatch(ctx, batch, resumes, missed)
+	default:
+		rs.resumeEach(ctx, resumes, missed)
+	}
+
+	// already resumed objects are saved even after cancel
+	rs.toStorage(context.WithoutCancel(ctx), resumes, missed)
+
+	rs.fetchOwners(ctx, resumes)
+	rs.fetchReverse(ctx, resumes)
+
+	return resumes, nil
+}
+
+// fetchOwners - fill ownership of every resumed IP if owner lookup is enabled
+func (rs *NetworkScrapeService) fetchOwners(ctx context.Context, resumes []models.ResumeAboutIP) {
+	if rs.owner == nil {
+		return
+	}
+
 	var (
-		resumes = make([]models.ResumeAboutIP, len(ipPool))
-		mu      = sync.Mutex{}
-		wg      = &sync.WaitGroup{}
-		tp      = microutils.NewTicketPool(rs.maxWorkers)
+		wg = &sync.WaitGroup{}
+		tp = microutils.NewTicketPool(rs.maxWorkers)
 	)
 	defer tp.ClosePool()
 
-	for i, ip := range ipPool {
+	for i := range resumes {
 		wg.Go(func() {
 			tp.CatchTicket()
 			defer tp.PutTicket()
 
-			about := models.ResumeAboutIP{RequestIP: ip}
+			ctx, cancel := rs.lookupContext(ctx)
+			defer cancel()
 
-			if rs.storage != nil {
-				obj, err := rs.storage.Get(context.Background(), ip)
-				if obj != nil && err != nil {
-					about.Resume = *obj
-				}
+			owner, err := rs.owner.LookupIP(ctx, resumes[i].RequestIP)
+			if err != nil {
+				owner.Err = err.Error()
 			}
+			resumes[i].Owner = &owner
+		})
+	}
 
-			obj, err := rs.resumer.ResumeIP(ip)
-			if err != nil {
-				about.Err = err.Error()
-			} else {
-				about.Resume = obj
+	wg.Wait()
+}
+
+// fromStorage - fill resumes from cache. Returns indexes of missed resumes
+func (rs *NetworkScrapeService) fromStorage(ctx context.Context, resumes []models.ResumeAboutIP) []int {
+	var (
+		missed = make([]int, 0, len(resumes))
+		mu     = sync.Mutex{}
+		wg     = &sync.WaitGroup{}
+		tp     = microutils.NewTicketPool(rs.maxWorkers)
+	)
+	defer tp.ClosePool()
+
+	if rs.storage == nil || !rs.cacheMode.readable() {
+		for i := range resumes {
+			missed = append(missed, i)
+		}
+		return missed
+	}
+
+	for i := range resumes {
+		wg.Go(func() {
+			tp.CatchTicket()
+			defer tp.PutTicket()
+
+			ctx, cancel := rs.lookupContext(ctx)
+			defer cancel()
+
+			obj, err := rs.storage.Get(ctx, resumes[i].RequestIP)
+			if err == nil && obj != nil {
+				resumes[i].Resume = *obj
+				resumes[i].Cached = true
+				return
 			}
 
 			mu.Lock()
-			resumes[i] = about
+			missed = append(missed, i)
 			mu.Unlock()
+		})
+	}
+
+	wg.Wait()
+	slices.Sort(missed)
+	return missed
+}
+
+// resumeBatch - batch resume. Lookup deadline is resumer's own per request timeout
+func (rs *NetworkScrapeService) resumeBatch(ctx context.Context, batch models.BatchResumerIP, resumes []models.ResumeAboutIP, idx []int) {
+	if len(idx) < 1 {
+		return
+	}
 
-			if rs.storage != nil {
-				rs.storage.Save(context.Background(), ip, obj)
+	ips := make([]net.IP, len(idx))
+	for n, i := range idx {
+		ips[n] = resumes[i].RequestIP
+	}
+
+	objs, errs := batch.ResumeIPs(ctx, ips)
+
+	for n, i := range idx {
+		if errs[n] != nil {
+			resumes[i].Err, resumes[i].ErrCode = errs[n].Error(), models.ErrorCodeOf(errs[n])
+			continue
+		}
+		resumes[i].Resume = objs[n]
+	}
+}
+
+func (rs *NetworkScrapeService) resumeEach(ctx context.Context, resumes []models.ResumeAboutIP, idx []int) {
+	var (
+		wg = &sync.WaitGroup{}
+		tp = microutils.NewTicketPool(rs.maxWorkers)
+	)
+	defer tp.ClosePool()
+
+	for _, i := range idx {
+		wg.Go(func() {
+			tp.CatchTicket()
+			defer tp.PutTicket()
+
+			ctx, cancel := rs.lookupContext(ctx)
+			defer cancel()
+
+			obj, err := rs.resumer.ResumeIP(ctx, resumes[i].RequestIP)
+			if err != nil {
+				resumes[i].Err, resumes[i].ErrCode = err.Error(), models.ErrorCodeOf(err)
+				return
 			}
+			resumes[i].Resume = obj
 		})
 	}
 
 	wg.Wait()
-	return resumes, nil
+}
+
+// toStorage - save resumed objects into cache
+func (rs *NetworkScrapeService) toStorage(ctx context.Context, resumes []models.ResumeAboutIP, idx []int) {
+	if rs.storage == nil || !rs.cacheMode.writable() {
+		return
+	}
+
+	for _, i := range idx {
+		about := &resumes[i]
+
+		// failed resumes ("status": "fail") must not shadow next requests
+		if about.Err != "" || about.Resume.Status != "success" {
+			continue
+		}
+
+		if err := rs.storage.Save(ctx, about.RequestIP, about.Resume); err != nil {
+			about.Err = fmt.Sprintf("failed to save resume into cache: %v", err)
+			about.ErrCode = models.ErrorCodeOf(err)
+		}
+	}
+}
+
+// fetchReverse - fill PTR names and FCrDNS check of every resumed IP if reverse lookup is enabled
+func (rs *NetworkScrapeService) fetchReverse(ctx context.Context, resumes []models.ResumeAboutIP) {
+	if !rs.reverse {
+		return
+	}
+
+	var (
+		wg = &sync.WaitGroup{}
+		tp = microutils.NewTicketPool(rs.maxWorkers)
+	)
+	defer tp.ClosePool()
+
+	for i := range resumes {
+		wg.Go(func() {
+			tp.CatchTicket()
+			defer tp.PutTicket()
+
+			ptr := rs.reverseIP(ctx, resumes[i].RequestIP)
+			resumes[i].PTR = &ptr
+		})
+	}
+
+	wg.Wait()
+}
+
+/*
+reverseIP - PTR lookup with forward-confirmed reverse DNS check
+
+	FCrDNS passes if any PTR name resolves back (A/AAAA) to the same IP.
+*/
+func (rs *NetworkScrapeService) reverseIP(ctx context.Context, ip net.IP) models.ReverseDNS {
+	rev := models.ReverseDNS{}
+
+	lookupCtx, cancel := rs.lookupContext(ctx)
+	names, err := rs.resolv.ResolvePTR(lookupCtx, ip)
+	cancel()
+
+	if err != nil {
+		rev.Err = err.Error()
+		return rev
+	}
+	rev.Names = names
+
+	for _, name := range names {
+		lookupCtx, cancel := rs.lookupContext(ctx)
+		ips, err := rs.resolv.ResolveIP(lookupCtx, name)
+		cancel()
+
+		if err != nil {
+			continue
+		}
+
+		if slices.ContainsFunc(ips, ip.Equal) {
+			rev.Confirmed = append(rev.Confirmed, name)
+		}
+	}
+
+	rev.FCrDNS = len(rev.Confirmed) > 0
+	return rev
+}
+
+// chainMaxSteps - max followed CNAME records, protects from loops
+const chainMaxSteps = 16
+
+/*
+traceChain - follow CNAME records of name one by one down to A/AAAA answers
+
+	Every step gets authoritative zone: closest parent of name (or name itself) with NS records.
+*/
+func (rs *NetworkScrapeService) traceChain(ctx context.Context, name string) models.CnameChain {
+	var (
+		chain = models.CnameChain{}
+		seen  = map[string]struct{}{}
+		zones = map[string][]string{}
+		next  = fqdn(name)
+	)
+
+	for {
+		if _, ok := seen[next]; ok {
+			chain.Err = fmt.Sprintf("CNAME loop at %s", next)
+			return chain
+		}
+		seen[next] = struct{}{}
+
+		if len(chain.Steps) >= chainMaxSteps {
+			chain.Err = fmt.Sprintf("CNAME chain is longer than %d steps", chainMaxSteps)
+			return chain
+		}
+
+		step := models.ChainStep{Name: next}
+		step.Zone, step.Authority = rs.zoneOf(ctx, next, zones)
+
+		lookupCtx, cancel := rs.lookupContext(ctx)
+		cnames, err := rs.resolv.ResolveRecords(lookupCtx, next, "CNAME")
+		cancel()
+
+		if err == nil && len(cnames) > 0 && cnames[0].Data != "" {
+			step.CNAME = fqdn(cnames[0].Data)
+			step.TTL = cnames[0].TTL
+			chain.Steps = append(chain.Steps, step)
+			next = step.CNAME
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			chain.Steps = append(chain.Steps, step)
+			chain.Err = err.Error()
+			return chain
+		}
+
+		var (
+			errs     []string
+			nxdomain = true
+		)
+
+		for _, rtype := range []string{"A", "AAAA"} {
+			lookupCtx, cancel := rs.lookupContext(ctx)
+			records, err := rs.resolv.ResolveRecords(lookupCtx, next, rtype)
+			cancel()
+
+			if err != nil {
+				errs = append(errs, err.Error())
+				nxdomain = nxdomain && models.ErrorCodeOf(err) == models.CodeNXDOMAIN
+				continue
+			}
+			nxdomain = false
+			step.Answers = append(step.Answers, records...)
+		}
+
+		chain.Steps = append(chain.Steps, step)
+
+		if len(step.Answers) == 0 {
+			chain.Err = strings.Join(errs, "; ")
+			// target without addresses, timeouts and server failures are not takeover evidence
+			chain.Dangling = len(chain.Steps) > 1 && nxdomain
+		}
+
+		return chain
+	}
+}
+
+// zoneOf - closest zone of name with its nameservers. Found zones are kept in cache
+func (rs *NetworkScrapeService) zoneOf(ctx context.Context, name string, cache map[string][]string) (string, []string) {
+	for zone := name; zone != "" && zone != "."; {
+		ns, ok := cache[zone]
+		if !ok {
+			lookupCtx, cancel := rs.lookupContext(ctx)
+			records, err := rs.resolv.ResolveRecords(lookupCtx, zone, "NS")
+			cancel()
+
+			if err == nil {
+				for _, rec := range records {
+					ns = append(ns, rec.Data)
+				}
+			}
+			cache[zone] = ns
+		}
+
+		if len(ns) > 0 {
+			return zone, ns
+		}
+
+		_, parent, _ := strings.Cut(zone, ".")
+		zone = parent
+	}
+
+	return "", nil
+}
+
+// fqdn - lower case fully qualified name: "WWW.Example.com" -> "www.example.com."
+func fqdn(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
 }
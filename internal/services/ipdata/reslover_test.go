@@ -0,0 +1,409 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eterline/micro-utils/internal/models"
+)
+
+// fakeResolver - records by "name type", missing names are NXDOMAIN, missing types of known names are NODATA
+type fakeResolver struct {
+	records map[string][]models.DnsRecord
+	errs    map[string]error
+}
+
+func (f *fakeResolver) ResolveIP(ctx context.Context, s string) ([]net.IP, error) {
+	records, err := f.ResolveRecords(ctx, s, "A")
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, len(records))
+	for i, rec := range records {
+		ips[i] = net.ParseIP(rec.Data)
+	}
+	return ips, nil
+}
+
+func (f *fakeResolver) ResolveNS(ctx context.Context, s string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeResolver) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeResolver) ResolveRecords(ctx context.Context, s, rtype string) ([]models.DnsRecord, error) {
+	name := strings.TrimSuffix(s, ".")
+
+	if err, ok := f.errs[name+" "+rtype]; ok {
+		return nil, err
+	}
+
+	if rec, ok := f.records[name+" "+rtype]; ok {
+		return rec, nil
+	}
+
+	for key := range f.records {
+		if strings.HasPrefix(key, name+" ") {
+			return nil, &models.LookupError{Code: models.CodeNODATA, Err: fmt.Errorf("%s %s: no records", name, rtype)}
+		}
+	}
+
+	return nil, &models.LookupError{Code: models.CodeNXDOMAIN, Err: fmt.Errorf("%s: no such host", name)}
+}
+
+func cname(target string) []models.DnsRecord {
+	return []models.DnsRecord{{Type: "CNAME", Data: target + ".", TTL: 300}}
+}
+
+func TestTraceChain(t *testing.T) {
+	rv := &fakeResolver{
+		records: map[string][]models.DnsRecord{
+			"example.test NS":        {{Type: "NS", Data: "ns1.example.test."}},
+			"www.example.test CNAME": cname("app.example.test"),
+			"app.example.test A":     {{Type: "A", Data: "192.0.2.1"}},
+
+			"gone.example.test CNAME":   cname("deleted.cloud.test"),
+			"broken.example.test CNAME": cname("flaky.example.test"),
+			"empty.example.test CNAME":  cname("txtonly.example.test"),
+			"txtonly.example.test TXT":  {{Type: "TXT", Data: "v=none"}},
+
+			"loop1.example.test CNAME": cname("loop2.example.test"),
+			"loop2.example.test CNAME": cname("loop1.example.test"),
+		},
+		errs: map[string]error{
+			"flaky.example.test A":    &models.LookupError{Code: models.CodeSERVFAIL, Err: errors.New("SERVFAIL")},
+			"flaky.example.test AAAA": &models.LookupError{Code: models.CodeTimeout, Err: errors.New("timeout")},
+		},
+	}
+
+	// long chain: c0 -> c1 -> ... -> c20
+	for i := range 20 {
+		rv.records[fmt.Sprintf("c%d.example.test CNAME", i)] = cname(fmt.Sprintf("c%d.example.test", i+1))
+	}
+
+	rs := NewNetworkScrapeService(1, rv, nil, nil)
+
+	tests := []struct {
+		name     string
+		steps    int
+		dangling bool
+		err      string
+	}{
+		{name: "www.example.test", steps: 2},
+		{name: "gone.example.test", steps: 2, dangling: true, err: "no such host"},
+		{name: "broken.example.test", steps: 2, err: "SERVFAIL"},
+		{name: "empty.example.test", steps: 2, err: "no records"},
+		{name: "loop1.example.test", steps: 2, err: "CNAME loop"},
+		{name: "c0.example.test", steps: chainMaxSteps, err: "longer than"},
+	}
+
+	for _, tt := range tests {
+		chain := rs.traceChain(context.Background(), tt.name)
+
+		if len(chain.Steps) != tt.steps {
+			t.Errorf("%s: %d steps, want %d", tt.name, len(chain.Steps), tt.steps)
+		}
+		if chain.Dangling != tt.dangling {
+			t.Errorf("%s: dangling %v, want %v", tt.name, chain.Dangling, tt.dangling)
+		}
+		if tt.err == "" && chain.Err != "" || !strings.Contains(chain.Err, tt.err) {
+			t.Errorf("%s: error %q, want %q", tt.name, chain.Err, tt.err)
+		}
+	}
+
+	chain := rs.traceChain(context.Background(), "www.example.test")
+	if chain.Steps[0].Zone != "example.test." || chain.Steps[1].Answers[0].Data != "192.0.2.1" {
+		t.Errorf("www.example.test: unexpected steps %+v", chain.Steps)
+	}
+}
+
+// batchResumer - batch IP resumer recording batches
+type batchResumer struct {
+	mu      sync.Mutex
+	batches [][]net.IP
+}
+
+func (b *batchResumer) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	return models.AboutIPobject{Status: "success", As: "AS64500 " + ip.String()}, nil
+}
+
+func (b *batchResumer) ResumeIPs(ctx context.Context, ips []net.IP) ([]models.AboutIPobject, []error) {
+	b.mu.Lock()
+	b.batches = append(b.batches, ips)
+	b.mu.Unlock()
+
+	objs := make([]models.AboutIPobject, len(ips))
+	for i, ip := range ips {
+		objs[i], _ = b.ResumeIP(ctx, ip)
+	}
+	return objs, make([]error, len(ips))
+}
+
+func TestStreamScrapeBatches(t *testing.T) {
+	rv := &fakeResolver{records: map[string][]models.DnsRecord{}}
+
+	const count = 250
+	for i := range count {
+		// 50 unique IPs shared by names
+		rv.records[fmt.Sprintf("n%d.example.test A", i)] = []models.DnsRecord{{Type: "A", Data: fmt.Sprintf("192.0.2.%d", i%50+1)}}
+	}
+
+	var (
+		resumer = &batchResumer{}
+		rs      = NewNetworkScrapeService(8, rv, resumer, nil)
+		names   = make(chan string)
+		emitted = map[string]ScrapeResult{}
+	)
+
+	go func() {
+		defer close(names)
+		for i := range count {
+			names <- fmt.Sprintf("n%d.example.test", i)
+		}
+		names <- "missing.example.test"
+	}()
+
+	rs.StreamScrape(context.Background(), names, func(res ScrapeResult) {
+		if _, ok := emitted[res.Name]; ok {
+			t.Errorf("%s is emitted twice", res.Name)
+		}
+		emitted[res.Name] = res
+	})
+
+	if len(emitted) != count+1 {
+		t.Fatalf("%d names emitted, want %d", len(emitted), count+1)
+	}
+
+	for name, res := range emitted {
+		ips := res.Resolve.AllIPs()
+		if len(res.Resumes) != len(ips) {
+			t.Fatalf("%s: %d resumes of %d IPs", name, len(res.Resumes), len(ips))
+		}
+		for i, ip := range ips {
+			if !res.Resumes[i].RequestIP.Equal(ip) || res.Resumes[i].Resume.As != "AS64500 "+ip.String() {
+				t.Fatalf("%s: resume %+v doesn't belong to %s", name, res.Resumes[i], ip)
+			}
+		}
+	}
+
+	if len(resumer.batches) > count/10 {
+		t.Errorf("%d batch requests for %d names", len(resumer.batches), count)
+	}
+
+	for _, batch := range resumer.batches {
+		seen := map[string]bool{}
+		for _, ip := range batch {
+			if seen[ip.String()] {
+				t.Fatalf("IP %s is repeated in batch", ip)
+			}
+			seen[ip.String()] = true
+		}
+	}
+}
+
+func TestStreamScrapeCancel(t *testing.T) {
+	rv := &fakeResolver{records: map[string][]models.DnsRecord{
+		"a.example.test A": {{Type: "A", Data: "192.0.2.1"}},
+	}}
+
+	var (
+		rs          = NewNetworkScrapeService(2, rv, &batchResumer{}, nil)
+		names       = make(chan string, 1)
+		ctx, cancel = context.WithCancel(context.Background())
+		done        = make(chan struct{})
+		emitted     []string
+	)
+
+	// names channel is never closed like blocked stdin
+	names <- "a.example.test"
+
+	go func() {
+		defer close(done)
+		rs.StreamScrape(ctx, names, func(res ScrapeResult) {
+			emitted = append(emitted, res.Name)
+		})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("StreamScrape doesn't return after cancel")
+	}
+
+	if len(emitted) != 1 {
+		t.Errorf("pending names %v, want a.example.test emitted", emitted)
+	}
+}
+
+// memStorage - IP info cache in memory with access counters
+type memStorage struct {
+	mu    sync.Mutex
+	objs  map[string]models.AboutIPobject
+	gets  int
+	saves []string
+}
+
+func (m *memStorage) Get(ctx context.Context, ip net.IP) (*models.AboutIPobject, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gets++
+	obj, ok := m.objs[ip.String()]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &obj, nil
+}
+
+func (m *memStorage) Save(ctx context.Context, ip net.IP, obj models.AboutIPobject) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.saves = append(m.saves, ip.String())
+	m.objs[ip.String()] = obj
+	return nil
+}
+
+// countingResumer - single IP resumer, IPs of failIP get "fail" status
+type countingResumer struct {
+	failIP string
+	mu     sync.Mutex
+	calls  int
+}
+
+func (c *countingResumer) ResumeIP(ctx context.Context, ip net.IP) (models.AboutIPobject, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+
+	if ip.String() == c.failIP {
+		return models.AboutIPobject{Status: "fail"}, nil
+	}
+	return models.AboutIPobject{Status: "success", As: "AS64500 resumed"}, nil
+}
+
+func TestFetchAboutIPCacheModes(t *testing.T) {
+	tests := []struct {
+		mode    CacheMode
+		cached  []bool
+		errCode []models.ErrorCode
+		calls   int
+		gets    int
+		saves   []string
+	}{
+		{mode: CacheDefault, cached: []bool{true, false, false}, errCode: []models.ErrorCode{"", "", ""}, calls: 2, gets: 3, saves: []string{"192.0.2.2"}},
+		{mode: CacheOffline, cached: []bool{true, false, false}, errCode: []models.ErrorCode{"", models.CodeNotCached, models.CodeNotCached}, gets: 3},
+		{mode: CacheRefresh, cached: []bool{false, false, false}, errCode: []models.ErrorCode{"", "", ""}, calls: 3, saves: []string{"192.0.2.1", "192.0.2.2"}},
+		{mode: CacheBypass, cached: []bool{false, false, false}, errCode: []models.ErrorCode{"", "", ""}, calls: 3},
+	}
+
+	ips := []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2"), net.ParseIP("192.0.2.3")}
+
+	for _, tt := range tests {
+		var (
+			st = &memStorage{objs: map[string]models.AboutIPobject{
+				"192.0.2.1": {Status: "success", As: "AS64500 cached"},
+			}}
+			// failed resume must not shadow next requests
+			ru = &countingResumer{failIP: "192.0.2.3"}
+			rs = NewNetworkScrapeService(1, &fakeResolver{}, ru, st)
+		)
+		rs.SetCacheMode(tt.mode)
+
+		resumes, err := rs.FetchAboutIP(context.Background(), ips)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.mode, err)
+		}
+
+		for i, r := range resumes {
+			if r.Cached != tt.cached[i] || r.ErrCode != tt.errCode[i] {
+				t.Errorf("%s: %s got cached %v with code %q, want %v with %q", tt.mode, r.RequestIP, r.Cached, r.ErrCode, tt.cached[i], tt.errCode[i])
+			}
+		}
+		if resumes[0].Cached && resumes[0].Resume.As != "AS64500 cached" {
+			t.Errorf("%s: cached resume is %+v", tt.mode, resumes[0].Resume)
+		}
+
+		slices.Sort(st.saves)
+		if ru.calls != tt.calls || st.gets != tt.gets || !slices.Equal(st.saves, tt.saves) {
+			t.Errorf("%s: got %d resumes, %d cache reads, saves %v, want %d, %d, %v", tt.mode, ru.calls, st.gets, st.saves, tt.calls, tt.gets, tt.saves)
+		}
+	}
+}
+
+func TestParseCacheMode(t *testing.T) {
+	for in, want := range map[string]CacheMode{"": CacheDefault, " Offline ": CacheOffline, "refresh": CacheRefresh, "BYPASS": CacheBypass} {
+		if got, err := ParseCacheMode(in); err != nil || got != want {
+			t.Errorf("%q: got %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseCacheMode("readonly"); err == nil {
+		t.Error("unknown mode is accepted")
+	}
+}
+
+// ptrResolver - fake resolver with PTR names by IP
+type ptrResolver struct {
+	*fakeResolver
+	ptr map[string][]string
+}
+
+func (p *ptrResolver) ResolvePTR(ctx context.Context, ip net.IP) ([]string, error) {
+	names, ok := p.ptr[ip.String()]
+	if !ok {
+		return nil, errors.New("nxdomain")
+	}
+	return names, nil
+}
+
+func TestReverseIP(t *testing.T) {
+	rv := &ptrResolver{
+		fakeResolver: &fakeResolver{records: map[string][]models.DnsRecord{
+			"mail.example.test A":  {{Type: "A", Data: "192.0.2.1"}},
+			"other.example.test A": {{Type: "A", Data: "192.0.2.9"}},
+			"spoof.example.test A": {{Type: "A", Data: "192.0.2.9"}},
+		}},
+		ptr: map[string][]string{
+			"192.0.2.1": {"mail.example.test.", "other.example.test.", "gone.example.test."},
+			"192.0.2.2": {"spoof.example.test."},
+		},
+	}
+	rs := NewNetworkScrapeService(1, rv, nil, nil)
+
+	tests := []struct {
+		ip        string
+		names     int
+		confirmed []string
+		fcrdns    bool
+		err       bool
+	}{
+		{ip: "192.0.2.1", names: 3, confirmed: []string{"mail.example.test."}, fcrdns: true},
+		{ip: "192.0.2.2", names: 1},
+		{ip: "192.0.2.3", err: true},
+	}
+
+	for _, tt := range tests {
+		rev := rs.reverseIP(context.Background(), net.ParseIP(tt.ip))
+
+		if len(rev.Names) != tt.names || !slices.Equal(rev.Confirmed, tt.confirmed) || rev.FCrDNS != tt.fcrdns || (rev.Err != "") != tt.err {
+			t.Errorf("%s: got %+v", tt.ip, rev)
+		}
+	}
+}
@@ -7,9 +7,11 @@ package doh
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"time"
 )
@@ -19,6 +21,12 @@ type DnsDoHProvider struct {
 	serviceName string
 	upstream    string
 	httpClient  *http.Client
+
+	// RFC 8484 wire format (application/dns-message) request method. JSON API is used if empty
+	wireMethod string
+	// EDNS0 settings: UDP payload size (wire format only) and DNSSEC OK bit
+	ednsSize uint16
+	dnssec   bool
 }
 
 // Service - get DoH provider name
@@ -28,14 +36,55 @@ func (c *DnsDoHProvider) Service() string {
 
 // Query - make DNS request to service
 func (c *DnsDoHProvider) Query(ctx context.Context, d Domain, t Record) (DnsResponse, error) {
+	return c.query(ctx, d, t, netip.Prefix{})
+}
+
+/*
+QuerySubnet - make DNS request on behalf of clients of subnet: EDNS Client Subnet option (RFC 7871)
+
+	JSON API sends subnet as edns_client_subnet parameter. Response ClientSubnet is empty if service ignored it.
+*/
+func (c *DnsDoHProvider) QuerySubnet(ctx context.Context, d Domain, t Record, subnet netip.Prefix) (DnsResponse, error) {
+	if !subnet.IsValid() {
+		return DnsResponse{}, fmt.Errorf("invalid client subnet: %s", subnet)
+	}
+	return c.query(ctx, d, t, subnet.Masked())
+}
+
+func (c *DnsDoHProvider) query(ctx context.Context, d Domain, t Record, subnet netip.Prefix) (DnsResponse, error) {
 	name, err := d.Punycode()
 	if err != nil {
 		return DnsResponse{}, err
 	}
 
+	if c.wireMethod != "" {
+		return c.queryWire(ctx, name, t, subnet)
+	}
+
 	param := url.Values{}
 	param.Add("name", name)
 	param.Add("type", t.String())
+	if c.dnssec {
+		param.Add("do", "true")
+	}
+	if subnet.IsValid() {
+		param.Add("edns_client_subnet", subnet.String())
+	}
+
+	res, err := c.queryJSON(ctx, param)
+	if err != nil {
+		return DnsResponse{}, err
+	}
+
+	if res.Success() {
+		return res, nil
+	}
+
+	return res, res.Status
+}
+
+// queryJSON - JSON API request with query parameters. Unsuccessful DNS status isn't an error here
+func (c *DnsDoHProvider) queryJSON(ctx context.Context, param url.Values) (DnsResponse, error) {
 	dnsURL := fmt.Sprintf(c.upstream, param.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dnsURL, nil)
@@ -53,31 +102,89 @@ func (c *DnsDoHProvider) Query(ctx context.Context, d Domain, t Record) (DnsResp
 	res := DnsResponse{}
 
 	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		u := *req.URL
+		u.RawQuery = ""
+		return DnsResponse{}, &HTTPStatusError{URL: u.String(), Code: r.StatusCode, Status: r.Status}
+	}
 	if err := json.
 		NewDecoder(r.Body).
 		Decode(&res); err != nil {
 		return DnsResponse{}, err
 	}
 
-	if res.Success() {
-		return res, nil
-	}
-
-	return res, res.Status
+	return res, nil
 }
 
 func setupHttpClient() *http.Client {
-	return &http.Client{
-		Timeout: 5 * time.Second,
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   3 * time.Second,
-				KeepAlive: 60 * time.Second,
-			}).DialContext,
-			TLSHandshakeTimeout: 3 * time.Second,
-			DisableKeepAlives:   false,
-			MaxIdleConns:        256,
-			MaxIdleConnsPerHost: 256,
-		},
+	client, _ := setupHttpClientWith(0, nil, "")
+	return client
+}
+
+/*
+setupHttpClientWith - DoH HTTP client
+
+	timeout - whole request timeout, 5s if <= 0.
+	bootstrap - IPs of DoH server, URL host is not resolved with system resolver if set.
+	proxy - HTTP(S) or SOCKS5 proxy URL, environment proxy settings are used if empty.
+*/
+func setupHttpClientWith(timeout time.Duration, bootstrap []string, proxy string) (*http.Client, error) {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   3 * time.Second,
+		KeepAlive: 60 * time.Second,
 	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 3 * time.Second,
+		DisableKeepAlives:   false,
+		MaxIdleConns:        256,
+		MaxIdleConnsPerHost: 256,
+		ForceAttemptHTTP2:   true,
+	}
+
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	if len(bootstrap) > 0 {
+		for _, ip := range bootstrap {
+			if net.ParseIP(ip) == nil {
+				return nil, fmt.Errorf("invalid bootstrap IP: %s", ip)
+			}
+		}
+
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+
+			var errs []error
+			for _, ip := range bootstrap {
+				conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+				if err == nil {
+					return conn, nil
+				}
+				errs = append(errs, err)
+			}
+
+			return nil, errors.Join(errs...)
+		}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}, nil
 }
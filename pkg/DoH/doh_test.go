@@ -0,0 +1,247 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package doh
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	dns "github.com/miekg/dns"
+)
+
+// wireHandler - RFC 8484 server answering A queries of example.test, request message is passed to check
+func wireHandler(t *testing.T, check func(r *http.Request, msg *dns.Msg)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			packed []byte
+			err    error
+		)
+
+		switch r.Method {
+		case http.MethodGet:
+			param := r.URL.Query().Get("dns")
+			if strings.Contains(param, "=") {
+				t.Errorf("dns parameter %q is padded", param)
+			}
+			packed, err = base64.RawURLEncoding.DecodeString(param)
+		case http.MethodPost:
+			packed, err = io.ReadAll(r.Body)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		msg := new(dns.Msg)
+		if err := msg.Unpack(packed); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		check(r, msg)
+
+		resp := new(dns.Msg)
+		resp.SetReply(msg)
+		if msg.Question[0].Name == "example.test." {
+			rr, _ := dns.NewRR("example.test. 300 IN A 192.0.2.1")
+			resp.Answer = append(resp.Answer, rr)
+		} else {
+			resp.Rcode = dns.RcodeNameError
+		}
+		if ecs := ClientSubnetOf(msg); ecs != nil {
+			ecs.SourceScope = ecs.SourceNetmask
+			resp.SetEdns0(dns.DefaultMsgSize, false)
+			resp.IsEdns0().Option = append(resp.IsEdns0().Option, ecs)
+		}
+
+		packed, _ = resp.Pack()
+		w.Header().Set("Content-Type", wireContentType)
+		w.Write(packed)
+	}
+}
+
+// testProvider - provider of TLS test server URL path
+func testProvider(t *testing.T, srv *httptest.Server, cfg ProviderConfig) *DnsDoHProvider {
+	t.Helper()
+
+	cfg.URL = srv.URL + cfg.URL
+	p, err := InitDnsProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.httpClient = srv.Client()
+	return p
+}
+
+func TestQueryWire(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			var requests int
+
+			srv := httptest.NewTLSServer(wireHandler(t, func(r *http.Request, msg *dns.Msg) {
+				requests++
+
+				if r.Method != method || r.URL.Path != "/dns-query" {
+					t.Errorf("got %s %s, want %s /dns-query", r.Method, r.URL.Path, method)
+				}
+				if r.Header.Get("Accept") != wireContentType {
+					t.Errorf("got Accept %q", r.Header.Get("Accept"))
+				}
+				if method == http.MethodPost && r.Header.Get("Content-Type") != wireContentType {
+					t.Errorf("got Content-Type %q", r.Header.Get("Content-Type"))
+				}
+				if method == http.MethodPost && r.URL.RawQuery != "" {
+					t.Errorf("POST has query %q", r.URL.RawQuery)
+				}
+				if msg.Id != 0 {
+					t.Errorf("got message ID %d, want 0", msg.Id)
+				}
+			}))
+			defer srv.Close()
+
+			p := testProvider(t, srv, ProviderConfig{URL: "/dns-query", Method: strings.ToLower(method)})
+
+			res, err := p.Query(context.Background(), "Example.test", TypeA)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Answer) != 1 || res.Answer[0].Data != "192.0.2.1" || res.Answer[0].TTL != 300 {
+				t.Fatalf("got %+v", res.Answer)
+			}
+
+			if _, err := p.Query(context.Background(), "missing.test", TypeA); !errors.Is(err, NXDOMAIN) {
+				t.Fatalf("got %v, want NXDOMAIN", err)
+			}
+
+			// message ID of request is restored in response
+			msg := new(dns.Msg)
+			msg.SetQuestion("example.test.", dns.TypeA)
+			msg.Id = 4242
+			answer, err := p.Exchange(context.Background(), msg)
+			if err != nil || answer.Id != 4242 || len(answer.Answer) != 1 {
+				t.Fatalf("got %v, %v", answer, err)
+			}
+
+			if requests != 3 {
+				t.Fatalf("got %d requests, want 3", requests)
+			}
+		})
+	}
+}
+
+func TestQueryWireSubnet(t *testing.T) {
+	srv := httptest.NewTLSServer(wireHandler(t, func(r *http.Request, msg *dns.Msg) {
+		opt := msg.IsEdns0()
+		if opt == nil || !opt.Do() || opt.UDPSize() != 1232 {
+			t.Errorf("got EDNS0 %v, want DO bit and 1232 size", opt)
+		}
+		ecs := ClientSubnetOf(msg)
+		if ecs == nil || ecs.Family != 1 || ecs.SourceNetmask != 24 || ecs.Address.String() != "198.51.100.0" {
+			t.Errorf("got client subnet %v, want 198.51.100.0/24", ecs)
+		}
+	}))
+	defer srv.Close()
+
+	p := testProvider(t, srv, ProviderConfig{URL: "/dns-query", EDNSSize: 1232, DNSSEC: true})
+
+	res, err := p.QuerySubnet(context.Background(), "example.test", TypeA, netip.MustParsePrefix("198.51.100.77/24"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ClientSubnet != "198.51.100.0/24" {
+		t.Fatalf("got client subnet %q", res.ClientSubnet)
+	}
+}
+
+func TestQueryJSON(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		if r.Method != http.MethodGet || r.URL.Path != "/resolve" || q.Get("key") != "abc" {
+			t.Errorf("got %s %s", r.Method, r.URL)
+		}
+		if r.Header.Get("Accept") != "application/dns-json" {
+			t.Errorf("got Accept %q", r.Header.Get("Accept"))
+		}
+		if q.Get("do") != "true" {
+			t.Errorf("no do parameter: %s", r.URL.RawQuery)
+		}
+
+		res := DnsResponse{
+			Question: []Question{{Name: q.Get("name"), Type: 1}},
+		}
+
+		switch {
+		case r.URL.Path == "/resolve" && q.Get("name") == "example.test" && q.Get("type") == "A":
+			res.Answer = []Answer{{Name: "example.test.", Type: 1, TTL: 300, Data: "192.0.2.1"}}
+			res.ClientSubnet = q.Get("edns_client_subnet")
+		case q.Get("name") == "example.test." && q.Get("type") == "1":
+			// Exchange sends numeric type and FQDN
+			res.Answer = []Answer{{Name: "example.test.", Type: 1, TTL: 300, Data: "192.0.2.1"}}
+			res.AD = q.Get("cd") == "true"
+		case q.Get("name") == "broken.test":
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		default:
+			res.Status = NXDOMAIN
+		}
+
+		w.Header().Set("Content-Type", "application/dns-json")
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+
+	p := testProvider(t, srv, ProviderConfig{URL: "/resolve?key=abc", JSON: true, DNSSEC: true})
+	ctx := context.Background()
+
+	res, err := p.QuerySubnet(ctx, "example.test", TypeA, netip.MustParsePrefix("2001:db8::1/56"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Answer) != 1 || res.Answer[0].Data != "192.0.2.1" || res.ClientSubnet != "2001:db8::/56" {
+		t.Fatalf("got %+v", res)
+	}
+
+	if _, err := p.Query(ctx, "missing.test", TypeA); !errors.Is(err, NXDOMAIN) {
+		t.Fatalf("got %v, want NXDOMAIN", err)
+	}
+
+	// HTTP status error URL is without query parameters
+	var httpErr *HTTPStatusError
+	if _, err := p.Query(ctx, "broken.test", TypeA); !errors.As(err, &httpErr) || httpErr.HTTPStatus() != http.StatusServiceUnavailable || strings.Contains(httpErr.URL, "?") {
+		t.Fatalf("got %v, want 503 status error without query", err)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.test.", dns.TypeA)
+	msg.CheckingDisabled = true
+	answer, err := p.Exchange(ctx, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Id != msg.Id || !answer.AuthenticatedData || len(answer.Answer) != 1 || answer.Answer[0].(*dns.A).A.String() != "192.0.2.1" {
+		t.Fatalf("got %v", answer)
+	}
+}
+
+func TestInitDnsProvider(t *testing.T) {
+	for _, cfg := range []ProviderConfig{
+		{URL: "http://dns.example.test/dns-query"},
+		{URL: "https:///dns-query"},
+		{URL: "https://dns.example.test/dns-query", Method: "PUT"},
+		{URL: "https://dns.example.test/dns-query", Bootstrap: []string{"not-ip"}},
+	} {
+		if _, err := InitDnsProvider(cfg); err == nil {
+			t.Errorf("%+v is accepted", cfg)
+		}
+	}
+}
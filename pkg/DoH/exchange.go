@@ -0,0 +1,105 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package doh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	dns "github.com/miekg/dns"
+)
+
+/*
+Exchange - send DNS message and get response message like plain DNS client
+
+	Wire format providers send message as is (ID is 0 in request, it's restored in response).
+	JSON API providers send only first question with DO and CD bits,
+	answer and authority records are parsed back from presentation format.
+*/
+func (c *DnsDoHProvider) Exchange(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	if len(msg.Question) == 0 {
+		return nil, errors.New("DNS message without question")
+	}
+
+	if c.wireMethod != "" {
+		req := msg.Copy()
+		// RFC 8484 4.1: ID 0 makes responses cache friendly
+		req.Id = 0
+
+		res, err := c.exchangeWire(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		res.Id = msg.Id
+		return res, nil
+	}
+
+	q := msg.Question[0]
+
+	param := url.Values{}
+	param.Add("name", q.Name)
+	param.Add("type", strconv.Itoa(int(q.Qtype)))
+	if opt := msg.IsEdns0(); (opt != nil && opt.Do()) || c.dnssec {
+		param.Add("do", "true")
+	}
+	if msg.CheckingDisabled {
+		param.Add("cd", "true")
+	}
+
+	res, err := c.queryJSON(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+
+	return msgFromResponse(msg, res)
+}
+
+// msgFromResponse - JSON API response as DNS message of request
+func msgFromResponse(req *dns.Msg, res DnsResponse) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetReply(req)
+
+	m.Rcode = int(res.Status)
+	m.Truncated = res.TC
+	m.RecursionAvailable = res.RA
+	m.AuthenticatedData = res.AD
+	m.CheckingDisabled = res.CD
+
+	var err error
+
+	if m.Answer, err = rrFromAnswers(res.Answer); err != nil {
+		return nil, err
+	}
+	if m.Ns, err = rrFromAnswers(res.Authority); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// rrFromAnswers - resource records of JSON API answers
+func rrFromAnswers(answers []Answer) ([]dns.RR, error) {
+	var rrs []dns.RR
+
+	for _, ans := range answers {
+		t, ok := dns.TypeToString[uint16(ans.Type)]
+		if !ok {
+			t = fmt.Sprintf("TYPE%d", ans.Type)
+		}
+
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(ans.Name), ans.TTL, t, ans.Data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid DoH answer record %s %s: %w", ans.Name, t, err)
+		}
+		if rr != nil {
+			rrs = append(rrs, rr)
+		}
+	}
+
+	return rrs, nil
+}
@@ -4,6 +4,80 @@
 
 package doh
 
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProviderConfig - DoH provider settings
+type ProviderConfig struct {
+	// URL - DoH endpoint: "https://dns.quad9.net/dns-query"
+	URL string
+	// JSON - use JSON API (application/dns-json) instead of RFC 8484 wire format
+	JSON bool
+	// Method - wire format request method: GET (default) or POST
+	Method string
+	// Timeout - whole HTTP request timeout, 5s if <= 0
+	Timeout time.Duration
+	// Bootstrap - IPs of URL host, it's not resolved with system resolver if set
+	Bootstrap []string
+	// Proxy - HTTP(S) or SOCKS5 proxy URL
+	Proxy string
+	// EDNSSize - EDNS0 UDP payload size of wire format queries, EDNS0 is disabled if 0 and DNSSEC is false
+	EDNSSize uint16
+	// DNSSEC - set DNSSEC OK bit
+	DNSSEC bool
+}
+
+// InitDnsProvider - DoH provider of any URL by settings
+func InitDnsProvider(cfg ProviderConfig) (*DnsDoHProvider, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DoH URL: %w", err)
+	}
+
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid DoH URL, https://host/path expected: %s", cfg.URL)
+	}
+
+	client, err := setupHttpClientWith(cfg.Timeout, cfg.Bootstrap, cfg.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &DnsDoHProvider{
+		serviceName: u.Host,
+		httpClient:  client,
+		ednsSize:    cfg.EDNSSize,
+		dnssec:      cfg.DNSSEC,
+	}
+
+	if cfg.JSON {
+		// JSON API upstream is format string of query parameters
+		sep := "?"
+		if u.RawQuery != "" {
+			sep = "&"
+		}
+		provider.upstream = strings.ReplaceAll(u.String(), "%", "%%") + sep + "%s"
+		return provider, nil
+	}
+
+	switch method := strings.ToUpper(cfg.Method); method {
+	case "":
+		provider.wireMethod = http.MethodGet
+	case http.MethodGet, http.MethodPost:
+		provider.wireMethod = method
+	default:
+		return nil, fmt.Errorf("unsupported DoH request method: %s", cfg.Method)
+	}
+
+	provider.upstream = u.String()
+	return provider, nil
+}
+
 func InitDnsCloudflareProvider() *DnsDoHProvider {
 	return &DnsDoHProvider{
 		serviceName: "cloudflare",
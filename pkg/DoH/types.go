@@ -5,6 +5,7 @@
 package doh
 
 import (
+	"fmt"
 	"strings"
 
 	"golang.org/x/net/idna"
@@ -66,6 +67,16 @@ const (
 	// RFC 1035: https://www.rfc-editor.org/rfc/rfc1035
 	TypePTR = Record("PTR")
 
+	// TypeSRV — Service locator record.
+	// Specifies host and port of servers for specified services.
+	// RFC 2782: https://www.rfc-editor.org/rfc/rfc2782
+	TypeSRV = Record("SRV")
+
+	// TypeCAA — Certification Authority Authorization record.
+	// Specifies which certificate authorities may issue certificates for the domain.
+	// RFC 8659: https://www.rfc-editor.org/rfc/rfc8659
+	TypeCAA = Record("CAA")
+
 	// TypeANY — Special query type.
 	// Requests all available record types for a domain (discouraged in practice).
 	// RFC 1035: https://www.rfc-editor.org/rfc/rfc1035
@@ -88,9 +99,32 @@ func (c DoHStatusCode) Error() string {
 		return "DoH query error - the requested domain name does not exist"
 	case REFUSED:
 		return "DoH query error - the DNS server refused to answer the query"
+	case NOERROR:
+		return "DoH query completed successfully"
 	}
 
-	return "DoH query completed successfully"
+	return fmt.Sprintf("DoH query error - DNS status code %d", int(c))
+}
+
+// DnsRcode - DNS response code of status
+func (c DoHStatusCode) DnsRcode() int {
+	return int(c)
+}
+
+// HTTPStatusError - unsuccessful HTTP status of DoH request
+type HTTPStatusError struct {
+	URL    string
+	Code   int
+	Status string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("DoH request %s failed: %s", e.URL, e.Status)
+}
+
+// HTTPStatus - HTTP status code of response
+func (e *HTTPStatusError) HTTPStatus() int {
+	return e.Code
 }
 
 const (
@@ -118,14 +152,17 @@ type Answer struct {
 
 // Response - dns query response from DoH providers
 type DnsResponse struct {
-	Status   DoHStatusCode `json:"Status"`
-	TC       bool          `json:"TC"`
-	RD       bool          `json:"RD"`
-	RA       bool          `json:"RA"`
-	AD       bool          `json:"AD"`
-	CD       bool          `json:"CD"`
-	Question []Question    `json:"Question"`
-	Answer   []Answer      `json:"Answer"`
+	Status    DoHStatusCode `json:"Status"`
+	TC        bool          `json:"TC"`
+	RD        bool          `json:"RD"`
+	RA        bool          `json:"RA"`
+	AD        bool          `json:"AD"`
+	CD        bool          `json:"CD"`
+	Question  []Question    `json:"Question"`
+	Answer    []Answer      `json:"Answer"`
+	Authority []Answer      `json:"Authority"`
+	// ClientSubnet - EDNS Client Subnet of response: "address/scope prefix length". Empty if subnet is not sent or ignored
+	ClientSubnet string `json:"edns_client_subnet,omitempty"`
 }
 
 func (dr DnsResponse) Success() bool {
@@ -0,0 +1,185 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package doh
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	dns "github.com/miekg/dns"
+)
+
+const (
+	// wireContentType - RFC 8484 DNS message media type
+	wireContentType = "application/dns-message"
+	// wireMaxResponse - max DNS message size
+	wireMaxResponse = dns.MaxMsgSize
+)
+
+// queryWire - RFC 8484 query, client subnet option is added if subnet is valid. Response is converted to JSON API form
+func (c *DnsDoHProvider) queryWire(ctx context.Context, name string, t Record, subnet netip.Prefix) (DnsResponse, error) {
+	qtype, ok := dns.StringToType[strings.ToUpper(t.String())]
+	if !ok {
+		return DnsResponse{}, fmt.Errorf("unknown DNS record type: %s", t)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	// RFC 8484 4.1: ID 0 makes responses cache friendly
+	msg.Id = 0
+
+	if c.ednsSize > 0 || c.dnssec || subnet.IsValid() {
+		msg.SetEdns0(max(c.ednsSize, dns.MinMsgSize), c.dnssec)
+	}
+
+	if subnet.IsValid() {
+		opt := msg.IsEdns0()
+		opt.Option = append(opt.Option, ClientSubnetOption(subnet))
+	}
+
+	answer, err := c.exchangeWire(ctx, msg)
+	if err != nil {
+		return DnsResponse{}, err
+	}
+
+	res := responseFromMsg(answer)
+	if res.Success() {
+		return res, nil
+	}
+
+	return res, res.Status
+}
+
+// exchangeWire - send DNS message as RFC 8484 request
+func (c *DnsDoHProvider) exchangeWire(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var req *http.Request
+
+	if c.wireMethod == http.MethodPost {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, c.upstream, bytes.NewReader(packed))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", wireContentType)
+	} else {
+		u, _ := url.Parse(c.upstream)
+		q := u.Query()
+		q.Set("dns", base64.RawURLEncoding.EncodeToString(packed))
+		u.RawQuery = q.Encode()
+
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req.Header.Set("Accept", wireContentType)
+
+	r, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{URL: c.upstream, Code: r.StatusCode, Status: r.Status}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, wireMaxResponse))
+	if err != nil {
+		return nil, err
+	}
+
+	answer := new(dns.Msg)
+	if err := answer.Unpack(body); err != nil {
+		return nil, fmt.Errorf("invalid DoH response of %s: %w", c.upstream, err)
+	}
+
+	return answer, nil
+}
+
+// responseFromMsg - DNS message in JSON API form, answer data is RDATA in presentation format
+func responseFromMsg(m *dns.Msg) DnsResponse {
+	res := DnsResponse{
+		Status: DoHStatusCode(m.Rcode),
+		TC:     m.Truncated,
+		RD:     m.RecursionDesired,
+		RA:     m.RecursionAvailable,
+		AD:     m.AuthenticatedData,
+		CD:     m.CheckingDisabled,
+	}
+
+	for _, q := range m.Question {
+		res.Question = append(res.Question, Question{Name: q.Name, Type: int(q.Qtype)})
+	}
+
+	res.Answer = answersFromRR(m.Answer)
+	res.Authority = answersFromRR(m.Ns)
+
+	if ecs := ClientSubnetOf(m); ecs != nil {
+		res.ClientSubnet = fmt.Sprintf("%s/%d", ecs.Address, ecs.SourceScope)
+	}
+
+	return res
+}
+
+// ClientSubnetOption - EDNS Client Subnet option (RFC 7871) of subnet, source prefix length is subnet length
+func ClientSubnetOption(subnet netip.Prefix) *dns.EDNS0_SUBNET {
+	subnet = subnet.Masked()
+
+	ecs := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		SourceNetmask: uint8(subnet.Bits()),
+		Address:       subnet.Addr().AsSlice(),
+	}
+
+	if subnet.Addr().Is4() {
+		ecs.Family = 1
+	} else {
+		ecs.Family = 2
+	}
+
+	return ecs
+}
+
+// ClientSubnetOf - EDNS Client Subnet option of message, nil if there is none
+func ClientSubnetOf(m *dns.Msg) *dns.EDNS0_SUBNET {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+
+	for _, o := range opt.Option {
+		if ecs, ok := o.(*dns.EDNS0_SUBNET); ok {
+			return ecs
+		}
+	}
+	return nil
+}
+
+func answersFromRR(rrs []dns.RR) []Answer {
+	var answers []Answer
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		answers = append(answers, Answer{
+			Name: hdr.Name,
+			Type: int(hdr.Rrtype),
+			TTL:  int(hdr.Ttl),
+			Data: strings.TrimSpace(strings.TrimPrefix(rr.String(), hdr.String())),
+		})
+	}
+	return answers
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DKIMSelectors - selectors of popular mail services and MTAs. Selectors can't be listed, only guessed
+var DKIMSelectors = []string{
+	"default", "dkim", "mail", "smtp", "s1", "s2", "k1", "k2", "k3",
+	"selector1", "selector2", "google", "key1", "key2", "fm1", "fm2", "fm3",
+	"protonmail", "protonmail2", "protonmail3", "mxvault", "zoho", "mandrill", "everlytickey1",
+}
+
+// DKIMKey - parsed DKIM public key record (RFC 6376 3.6.1)
+type DKIMKey struct {
+	// KeyType - k tag: rsa (default) | ed25519
+	KeyType string
+	// Bits - key length
+	Bits int
+	// Revoked - empty p tag
+	Revoked bool
+	// Testing - t=y flag, verifiers treat signatures as unsigned
+	Testing bool
+}
+
+// DKIMName - DNS name of DKIM key: "selector._domainkey.example.com"
+func DKIMName(selector, domain string) string {
+	return selector + "._domainkey." + domain
+}
+
+// ParseDKIM - parse DKIM key record. Version tag is optional, but it must be first if it's set
+func ParseDKIM(txt string) (DKIMKey, error) {
+	tags, err := parseTags(txt)
+	if err != nil {
+		return DKIMKey{}, err
+	}
+
+	if len(tags) > 0 && tags[0].Name == "v" {
+		if err := checkVersion(tags, "DKIM1"); err != nil {
+			return DKIMKey{}, err
+		}
+	}
+
+	var (
+		key    = DKIMKey{KeyType: "rsa"}
+		public *string
+	)
+
+	for _, t := range tags {
+		switch t.Name {
+		case "k":
+			key.KeyType = strings.ToLower(t.Value)
+		case "t":
+			key.Testing = slices.Contains(strings.Split(t.Value, ":"), "y")
+		case "p":
+			public = &t.Value
+		}
+	}
+
+	if public == nil {
+		return DKIMKey{}, errors.New("p tag is required")
+	}
+
+	data := strings.Join(strings.Fields(*public), "")
+	if data == "" {
+		key.Revoked = true
+		return key, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return DKIMKey{}, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	switch key.KeyType {
+	case "rsa":
+		key.Bits, err = rsaBits(raw)
+		if err != nil {
+			return DKIMKey{}, err
+		}
+	case "ed25519":
+		if len(raw) != 32 {
+			return DKIMKey{}, fmt.Errorf("invalid ed25519 public key length %d", len(raw))
+		}
+		key.Bits = 256
+	default:
+		return DKIMKey{}, fmt.Errorf("unknown key type %q", key.KeyType)
+	}
+
+	return key, nil
+}
+
+// rsaBits - length of RSA key in SubjectPublicKeyInfo or PKCS#1 form
+func rsaBits(raw []byte) (int, error) {
+	if pub, err := x509.ParsePKIXPublicKey(raw); err == nil {
+		rsaPub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return 0, errors.New("public key is not RSA key")
+		}
+		return rsaPub.N.BitLen(), nil
+	}
+
+	pub, err := x509.ParsePKCS1PublicKey(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid RSA public key: %w", err)
+	}
+	return pub.N.BitLen(), nil
+}
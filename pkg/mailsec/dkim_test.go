@@ -0,0 +1,79 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestParseDKIM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		spki  = base64.StdEncoding.EncodeToString(pkix)
+		pkcs1 = base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+		ed    = base64.StdEncoding.EncodeToString(edKey)
+	)
+
+	tests := []struct {
+		name    string
+		txt     string
+		want    DKIMKey
+		wantErr bool
+	}{
+		{name: "rsa subject public key info", txt: "v=DKIM1; k=rsa; p=" + spki, want: DKIMKey{KeyType: "rsa", Bits: 1024}},
+		{name: "rsa pkcs1 without version", txt: "p=" + pkcs1, want: DKIMKey{KeyType: "rsa", Bits: 1024}},
+		// key is split into several TXT strings with whitespace
+		{name: "key with spaces", txt: "v=DKIM1; p=" + spki[:40] + " " + spki[40:], want: DKIMKey{KeyType: "rsa", Bits: 1024}},
+		{name: "ed25519", txt: "v=DKIM1; k=ed25519; p=" + ed, want: DKIMKey{KeyType: "ed25519", Bits: 256}},
+		{name: "revoked", txt: "v=DKIM1; p=", want: DKIMKey{KeyType: "rsa", Revoked: true}},
+		{name: "testing", txt: "v=DKIM1; t=s:y; p=" + spki, want: DKIMKey{KeyType: "rsa", Bits: 1024, Testing: true}},
+		{name: "no public key", txt: "v=DKIM1; k=rsa", wantErr: true},
+		{name: "other version", txt: "v=DKIM2; p=" + spki, wantErr: true},
+		{name: "unknown key type", txt: "v=DKIM1; k=dsa; p=" + spki, wantErr: true},
+		{name: "short ed25519", txt: "v=DKIM1; k=ed25519; p=" + ed[:20], wantErr: true},
+		{name: "not base64", txt: "v=DKIM1; p=not*base64", wantErr: true},
+		{name: "not rsa key", txt: "v=DKIM1; p=" + ed, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDKIM(tt.txt)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if name := DKIMName("selector1", "example.com"); !strings.HasPrefix(name, "selector1._domainkey.") {
+		t.Fatalf("got %s", name)
+	}
+}
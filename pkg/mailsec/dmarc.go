@@ -0,0 +1,93 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DMARCRecord - parsed DMARC policy record (RFC 7489 6.3)
+type DMARCRecord struct {
+	// Policy - p tag: none | quarantine | reject
+	Policy string
+	// SubdomainPolicy - sp tag, Policy if it's not set
+	SubdomainPolicy string
+	// Percent - pct tag, 100 by default
+	Percent int
+	// AggregateURIs - rua tag
+	AggregateURIs []string
+	// ForensicURIs - ruf tag
+	ForensicURIs []string
+	// DKIMAlignment, SPFAlignment - adkim, aspf tags: r (relaxed, default) | s (strict)
+	DKIMAlignment string
+	SPFAlignment  string
+}
+
+// IsDMARC - TXT record is DMARC record
+func IsDMARC(txt string) bool {
+	return hasVersion(txt, "DMARC1")
+}
+
+// ParseDMARC - parse DMARC record, p tag is required
+func ParseDMARC(txt string) (DMARCRecord, error) {
+	tags, err := parseTags(txt)
+	if err != nil {
+		return DMARCRecord{}, err
+	}
+
+	if err := checkVersion(tags, "DMARC1"); err != nil {
+		return DMARCRecord{}, err
+	}
+
+	rec := DMARCRecord{
+		Percent:       100,
+		DKIMAlignment: "r",
+		SPFAlignment:  "r",
+	}
+
+	for _, t := range tags[1:] {
+		switch t.Name {
+		case "p":
+			rec.Policy = strings.ToLower(t.Value)
+		case "sp":
+			rec.SubdomainPolicy = strings.ToLower(t.Value)
+		case "pct":
+			pct, err := strconv.Atoi(t.Value)
+			if err != nil || pct < 0 || pct > 100 {
+				return DMARCRecord{}, fmt.Errorf("invalid pct %q", t.Value)
+			}
+			rec.Percent = pct
+		case "rua":
+			rec.AggregateURIs = splitURIs(t.Value)
+		case "ruf":
+			rec.ForensicURIs = splitURIs(t.Value)
+		case "adkim":
+			rec.DKIMAlignment = strings.ToLower(t.Value)
+		case "aspf":
+			rec.SPFAlignment = strings.ToLower(t.Value)
+		}
+	}
+
+	if rec.Policy == "" {
+		return DMARCRecord{}, errors.New("p tag is required")
+	}
+
+	for _, p := range []string{rec.Policy, rec.SubdomainPolicy} {
+		switch p {
+		case "", "none", "quarantine", "reject":
+		default:
+			return DMARCRecord{}, fmt.Errorf("unknown policy %q", p)
+		}
+	}
+
+	if rec.SubdomainPolicy == "" {
+		rec.SubdomainPolicy = rec.Policy
+	}
+
+	return rec, nil
+}
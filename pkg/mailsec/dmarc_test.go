@@ -0,0 +1,59 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDMARC(t *testing.T) {
+	tests := []struct {
+		name    string
+		txt     string
+		want    DMARCRecord
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			txt:  "v=DMARC1; p=reject",
+			want: DMARCRecord{Policy: "reject", SubdomainPolicy: "reject", Percent: 100, DKIMAlignment: "r", SPFAlignment: "r"},
+		},
+		{
+			name: "all tags",
+			txt:  "v=DMARC1;p=Quarantine; sp=none; pct=25; rua=mailto:a@example.com, mailto:b@example.com!10m; ruf=mailto:f@example.com; adkim=s; aspf=S;",
+			want: DMARCRecord{
+				Policy: "quarantine", SubdomainPolicy: "none", Percent: 25,
+				AggregateURIs: []string{"mailto:a@example.com", "mailto:b@example.com!10m"},
+				ForensicURIs:  []string{"mailto:f@example.com"},
+				DKIMAlignment: "s", SPFAlignment: "s",
+			},
+		},
+		{name: "no policy", txt: "v=DMARC1; rua=mailto:a@example.com", wantErr: true},
+		{name: "unknown policy", txt: "v=DMARC1; p=block", wantErr: true},
+		{name: "unknown subdomain policy", txt: "v=DMARC1; p=none; sp=drop", wantErr: true},
+		{name: "version is not first", txt: "p=reject; v=DMARC1", wantErr: true},
+		{name: "invalid pct", txt: "v=DMARC1; p=reject; pct=101", wantErr: true},
+		{name: "invalid tag", txt: "v=DMARC1; p=reject; rua", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDMARC(tt.txt)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// mtastsMaxPolicy - max size of MTA-STS policy file (RFC 8461 3.3 recommends 64 KiB)
+const mtastsMaxPolicy = 64 << 10
+
+// MTASTSRecord - parsed "_mta-sts" TXT record (RFC 8461 3.1)
+type MTASTSRecord struct {
+	// ID - policy version, changes with every policy update
+	ID string
+}
+
+// IsMTASTS - TXT record is MTA-STS record
+func IsMTASTS(txt string) bool {
+	return hasVersion(txt, "STSv1")
+}
+
+// ParseMTASTSRecord - parse MTA-STS TXT record, id tag is required
+func ParseMTASTSRecord(txt string) (MTASTSRecord, error) {
+	tags, err := parseTags(txt)
+	if err != nil {
+		return MTASTSRecord{}, err
+	}
+
+	if err := checkVersion(tags, "STSv1"); err != nil {
+		return MTASTSRecord{}, err
+	}
+
+	for _, t := range tags[1:] {
+		if t.Name == "id" {
+			if !validPolicyID(t.Value) {
+				return MTASTSRecord{}, fmt.Errorf("invalid id %q: 1-32 letters and digits expected", t.Value)
+			}
+			return MTASTSRecord{ID: t.Value}, nil
+		}
+	}
+
+	return MTASTSRecord{}, errors.New("id tag is required")
+}
+
+func validPolicyID(id string) bool {
+	if len(id) < 1 || len(id) > 32 {
+		return false
+	}
+	for _, r := range id {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// MTASTSPolicy - parsed MTA-STS policy file (RFC 8461 3.2)
+type MTASTSPolicy struct {
+	// Mode - enforce | testing | none
+	Mode string
+	// MX - allowed MX host patterns: "mail.example.com", "*.example.net"
+	MX []string
+	// MaxAge - policy lifetime in seconds
+	MaxAge int64
+}
+
+// MatchMX - MX host matches one of policy patterns, wildcard matches single leftmost label
+func (p MTASTSPolicy) MatchMX(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, pattern := range p.MX {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && rest == suffix {
+				return true
+			}
+			continue
+		}
+
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMTASTSPolicy - parse policy file: "version: STSv1", "mode", "max_age" and "mx" lines
+func ParseMTASTSPolicy(body string) (MTASTSPolicy, error) {
+	var (
+		policy  = MTASTSPolicy{MaxAge: -1}
+		version string
+		sc      = bufio.NewScanner(strings.NewReader(body))
+	)
+
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return MTASTSPolicy{}, fmt.Errorf("invalid policy line %q", line)
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "version":
+			version = value
+		case "mode":
+			policy.Mode = strings.ToLower(value)
+		case "mx":
+			policy.MX = append(policy.MX, value)
+		case "max_age":
+			age, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || age < 0 {
+				return MTASTSPolicy{}, fmt.Errorf("invalid max_age %q", value)
+			}
+			policy.MaxAge = age
+		}
+	}
+
+	switch {
+	case version != "STSv1":
+		return MTASTSPolicy{}, errors.New("policy version must be STSv1")
+	case policy.Mode != "enforce" && policy.Mode != "testing" && policy.Mode != "none":
+		return MTASTSPolicy{}, fmt.Errorf("invalid policy mode %q", policy.Mode)
+	case policy.MaxAge < 0:
+		return MTASTSPolicy{}, errors.New("max_age is required")
+	case policy.Mode != "none" && len(policy.MX) == 0:
+		return MTASTSPolicy{}, errors.New("mx is required for enforce and testing modes")
+	}
+
+	return policy, nil
+}
+
+// PolicyURL - MTA-STS policy URL of domain
+func PolicyURL(domain string) string {
+	return "https://mta-sts." + strings.TrimSuffix(domain, ".") + "/.well-known/mta-sts.txt"
+}
+
+/*
+FetchMTASTSPolicy - download and parse policy of domain
+
+	Redirects are not followed (RFC 8461 3.3), policy must be served as text/plain with 200 status.
+	Client must verify server certificate, it's done by default HTTP client transport.
+*/
+func FetchMTASTSPolicy(ctx context.Context, client *http.Client, domain string) (MTASTSPolicy, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, PolicyURL(domain), nil)
+	if err != nil {
+		return MTASTSPolicy{}, err
+	}
+
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	r, err := noRedirect.Do(req)
+	if err != nil {
+		return MTASTSPolicy{}, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return MTASTSPolicy{}, fmt.Errorf("policy request %s failed: %s", req.URL, r.Status)
+	}
+
+	if media, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); media != "text/plain" {
+		return MTASTSPolicy{}, fmt.Errorf("policy media type is %q, text/plain expected", media)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, mtastsMaxPolicy))
+	if err != nil {
+		return MTASTSPolicy{}, err
+	}
+
+	return ParseMTASTSPolicy(string(body))
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// SPFLookupLimit - max count of DNS querying terms of SPF evaluation (RFC 7208 4.6.4)
+const SPFLookupLimit = 10
+
+// SPFVoidLookupLimit - max count of DNS lookups without answer (RFC 7208 4.6.4)
+const SPFVoidLookupLimit = 2
+
+// spfMechanisms - mechanism names of RFC 7208 5
+var spfMechanisms = map[string]bool{
+	"all": true, "include": true, "a": true, "mx": true, "ptr": true, "ip4": true, "ip6": true, "exists": true,
+}
+
+// SPFTerm - mechanism ("-all", "include:_spf.example.com") or modifier ("redirect=_spf.example.com") of SPF record
+type SPFTerm struct {
+	// Qualifier - "+", "-", "~" or "?" of mechanism, empty for modifier
+	Qualifier string
+	// Name - lowercase mechanism or modifier name
+	Name string
+	// Value - domain-spec, IP network or modifier value. CIDR suffix of a and mx is kept: "/24"
+	Value    string
+	Modifier bool
+}
+
+// Lookup - term needs DNS query and counts against SPFLookupLimit
+func (t SPFTerm) Lookup() bool {
+	if t.Modifier {
+		return t.Name == "redirect"
+	}
+
+	switch t.Name {
+	case "include", "a", "mx", "ptr", "exists":
+		return true
+	}
+	return false
+}
+
+// Target - domain of include, exists and redirect terms
+func (t SPFTerm) Target() string {
+	switch t.Name {
+	case "include", "exists", "redirect":
+		return t.Value
+	}
+	return ""
+}
+
+func (t SPFTerm) String() string {
+	switch {
+	case t.Modifier:
+		return t.Name + "=" + t.Value
+	case t.Value == "":
+		return t.Qualifier + t.Name
+	case strings.HasPrefix(t.Value, "/"):
+		return t.Qualifier + t.Name + t.Value
+	}
+	return t.Qualifier + t.Name + ":" + t.Value
+}
+
+// SPFRecord - parsed SPF record
+type SPFRecord struct {
+	Terms []SPFTerm
+}
+
+// All - "all" mechanism of record
+func (r SPFRecord) All() (SPFTerm, bool) {
+	for _, t := range r.Terms {
+		if !t.Modifier && t.Name == "all" {
+			return t, true
+		}
+	}
+	return SPFTerm{}, false
+}
+
+// Redirect - domain of redirect modifier, empty if there is none
+func (r SPFRecord) Redirect() string {
+	for _, t := range r.Terms {
+		if t.Modifier && t.Name == "redirect" {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// IsSPF - TXT record is SPF record: "v=spf1" followed by space or end
+func IsSPF(txt string) bool {
+	txt = strings.TrimSpace(txt)
+	if len(txt) < 6 || !strings.EqualFold(txt[:6], "v=spf1") {
+		return false
+	}
+	return len(txt) == 6 || txt[6] == ' '
+}
+
+// ParseSPF - parse SPF record. Unknown modifiers are kept, unknown mechanisms are errors
+func ParseSPF(txt string) (SPFRecord, error) {
+	if !IsSPF(txt) {
+		return SPFRecord{}, errors.New("record must start with v=spf1")
+	}
+
+	var (
+		rec    SPFRecord
+		fields = strings.Fields(txt)[1:]
+		seen   = map[string]bool{}
+	)
+
+	for _, field := range fields {
+		term, err := parseSPFTerm(field)
+		if err != nil {
+			return SPFRecord{}, err
+		}
+
+		if term.Modifier {
+			// RFC 7208 6: redirect and exp can appear only once
+			if (term.Name == "redirect" || term.Name == "exp") && seen[term.Name] {
+				return SPFRecord{}, fmt.Errorf("several %s modifiers", term.Name)
+			}
+			seen[term.Name] = true
+		}
+
+		rec.Terms = append(rec.Terms, term)
+	}
+
+	return rec, nil
+}
+
+func parseSPFTerm(field string) (SPFTerm, error) {
+	// modifier: name=value, name starts with letter
+	if i := strings.IndexAny(field, "=:/"); i > 0 && field[i] == '=' {
+		return SPFTerm{
+			Name:     strings.ToLower(field[:i]),
+			Value:    field[i+1:],
+			Modifier: true,
+		}, nil
+	}
+
+	term := SPFTerm{Qualifier: "+"}
+	if strings.ContainsRune("+-~?", rune(field[0])) {
+		term.Qualifier, field = field[:1], field[1:]
+	}
+
+	name, value := field, ""
+	if i := strings.IndexAny(field, ":/"); i >= 0 {
+		name, value = field[:i], strings.TrimPrefix(field[i:], ":")
+	}
+	term.Name, term.Value = strings.ToLower(name), value
+
+	if !spfMechanisms[term.Name] {
+		return SPFTerm{}, fmt.Errorf("unknown mechanism %q", field)
+	}
+
+	switch term.Name {
+	case "all":
+		if value != "" {
+			return SPFTerm{}, fmt.Errorf("invalid mechanism %q", field)
+		}
+	case "include", "exists":
+		if value == "" {
+			return SPFTerm{}, fmt.Errorf("mechanism %q requires domain", field)
+		}
+	case "ip4", "ip6":
+		if err := checkSPFNetwork(term.Name, value); err != nil {
+			return SPFTerm{}, fmt.Errorf("invalid mechanism %q: %w", field, err)
+		}
+	}
+
+	return term, nil
+}
+
+func checkSPFNetwork(mech, value string) error {
+	var (
+		addr netip.Addr
+		err  error
+	)
+
+	if strings.Contains(value, "/") {
+		var prefix netip.Prefix
+		prefix, err = netip.ParsePrefix(value)
+		addr = prefix.Addr()
+	} else {
+		addr, err = netip.ParseAddr(value)
+	}
+
+	switch {
+	case err != nil:
+		return err
+	case mech == "ip4" && !addr.Is4(), mech == "ip6" && !addr.Is6():
+		return fmt.Errorf("%s address expected", mech)
+	}
+	return nil
+}
+
+// HasMacro - domain-spec contains macros ("%{i}._spf.example.com"), it can't be resolved without message data
+func HasMacro(domain string) bool {
+	return strings.Contains(domain, "%{")
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSPF(t *testing.T) {
+	tests := []struct {
+		name    string
+		txt     string
+		terms   []string
+		lookups int
+		wantErr bool
+	}{
+		{
+			name:    "qualifiers",
+			txt:     "v=spf1 a +mx -include:_spf.example.com ~exists:%{i}.example.com ?ptr -all",
+			terms:   []string{"+a", "+mx", "-include:_spf.example.com", "~exists:%{i}.example.com", "?ptr", "-all"},
+			lookups: 5,
+		},
+		{
+			name:  "networks with CIDR",
+			txt:   "v=spf1 ip4:192.0.2.1 ip4:198.51.100.0/24 ip6:2001:db8::1 ip6:2001:db8::/32 a/24 mx:mail.example.com/26 ~all",
+			terms: []string{"+ip4:192.0.2.1", "+ip4:198.51.100.0/24", "+ip6:2001:db8::1", "+ip6:2001:db8::/32", "+a/24", "+mx:mail.example.com/26", "~all"},
+			// a and mx only
+			lookups: 2,
+		},
+		{
+			name:    "modifiers",
+			txt:     "V=SPF1 Include:_spf.example.com exp=explain.example.com Redirect=_spf.example.net unknown=kept",
+			terms:   []string{"+include:_spf.example.com", "exp=explain.example.com", "redirect=_spf.example.net", "unknown=kept"},
+			lookups: 2,
+		},
+		{name: "version only", txt: "v=spf1", terms: nil},
+		{name: "duplicate redirect", txt: "v=spf1 redirect=a.example.com redirect=b.example.com", wantErr: true},
+		{name: "duplicate exp", txt: "v=spf1 exp=a.example.com exp=b.example.com -all", wantErr: true},
+		{name: "no version", txt: "spf1 -all", wantErr: true},
+		{name: "other version", txt: "v=spf10 -all", wantErr: true},
+		{name: "unknown mechanism", txt: "v=spf1 foo:bar -all", wantErr: true},
+		{name: "include without domain", txt: "v=spf1 include -all", wantErr: true},
+		{name: "all with value", txt: "v=spf1 all:example.com", wantErr: true},
+		{name: "ip6 address in ip4", txt: "v=spf1 ip4:2001:db8::1 -all", wantErr: true},
+		{name: "ip4 address in ip6", txt: "v=spf1 ip6:192.0.2.1 -all", wantErr: true},
+		{name: "invalid prefix", txt: "v=spf1 ip4:192.0.2.0/33 -all", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := ParseSPF(tt.txt)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want error", rec.Terms)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var (
+				terms   []string
+				lookups int
+			)
+			for _, term := range rec.Terms {
+				terms = append(terms, term.String())
+				if term.Lookup() {
+					lookups++
+				}
+			}
+
+			if !slices.Equal(terms, tt.terms) || lookups != tt.lookups {
+				t.Fatalf("got %v with %d lookups, want %v with %d", terms, lookups, tt.terms, tt.lookups)
+			}
+		})
+	}
+}
+
+func TestSPFRecordPolicy(t *testing.T) {
+	rec, err := ParseSPF("v=spf1 include:_spf.example.com ~all redirect=_spf.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if all, ok := rec.All(); !ok || all.Qualifier != "~" {
+		t.Fatalf("got all %v %v, want ~all", all, ok)
+	}
+	if got := rec.Redirect(); got != "_spf.example.net" {
+		t.Fatalf("got redirect %q", got)
+	}
+
+	if got := rec.Terms[0].Target(); got != "_spf.example.com" {
+		t.Fatalf("got include target %q", got)
+	}
+	if !HasMacro("%{i}._spf.example.com") || HasMacro("_spf.example.com") {
+		t.Fatal("macro detection mismatch")
+	}
+}
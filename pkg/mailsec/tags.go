@@ -0,0 +1,85 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+/*
+Package mailsec - parsers of email security DNS records and policies
+
+	SPF (RFC 7208), DMARC (RFC 7489), DKIM keys (RFC 6376), MTA-STS (RFC 8461) and TLS-RPT (RFC 8460).
+	DNS lookups are left to caller, only MTA-STS policy is fetched over HTTPS.
+*/
+package mailsec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tag - "name=value" pair of tag-list record
+type tag struct {
+	Name  string
+	Value string
+}
+
+/*
+parseTags - tag-list of DMARC, DKIM, MTA-STS and TLS-RPT records: "v=DMARC1; p=reject; rua=mailto:a@example.com"
+
+	Tag names are lowercase, values are trimmed. Empty tags (trailing ";") are skipped.
+*/
+func parseTags(txt string) ([]tag, error) {
+	var tags []tag
+
+	for part := range strings.SplitSeq(txt, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tag %q", part)
+		}
+
+		tags = append(tags, tag{
+			Name:  strings.ToLower(strings.TrimSpace(name)),
+			Value: strings.TrimSpace(value),
+		})
+	}
+
+	return tags, nil
+}
+
+// checkVersion - first tag must be version tag "v" with value (case-insensitive)
+func checkVersion(tags []tag, version string) error {
+	if len(tags) == 0 || tags[0].Name != "v" || !strings.EqualFold(tags[0].Value, version) {
+		return fmt.Errorf("record must start with v=%s", version)
+	}
+	return nil
+}
+
+// hasVersion - TXT record starts with version tag: "v=spf1 ...", "v=DMARC1; ..."
+func hasVersion(txt, version string) bool {
+	name, rest, ok := strings.Cut(strings.TrimSpace(txt), "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(name), "v") {
+		return false
+	}
+
+	value := strings.TrimSpace(rest)
+	if len(value) < len(version) || !strings.EqualFold(value[:len(version)], version) {
+		return false
+	}
+
+	next := value[len(version):]
+	return next == "" || next[0] == ';' || next[0] == ' '
+}
+
+// splitURIs - comma separated report URIs: "mailto:a@example.com,mailto:b@example.com!10m"
+func splitURIs(value string) []string {
+	var uris []string
+	for uri := range strings.SplitSeq(value, ",") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		txt     string
+		want    []tag
+		wantErr bool
+	}{
+		{
+			name: "trimmed lowercase names",
+			txt:  " V = DMARC1 ;P=reject;  rua = mailto:a@example.com ",
+			want: []tag{{"v", "DMARC1"}, {"p", "reject"}, {"rua", "mailto:a@example.com"}},
+		},
+		{name: "empty tags are skipped", txt: "v=STSv1;; id=20250101;", want: []tag{{"v", "STSv1"}, {"id", "20250101"}}},
+		{name: "empty value", txt: "v=DKIM1; p=", want: []tag{{"v", "DKIM1"}, {"p", ""}}},
+		{name: "value with equal sign", txt: "p=YWJj==", want: []tag{{"p", "YWJj=="}}},
+		{name: "empty", txt: "", want: nil},
+		{name: "tag without value", txt: "v=DMARC1; reject", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTags(tt.txt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasVersion(t *testing.T) {
+	tests := []struct {
+		txt     string
+		version string
+		want    bool
+	}{
+		{"v=DMARC1; p=reject", "DMARC1", true},
+		{"  V = dmarc1;p=none", "DMARC1", true},
+		{"v=DMARC1", "DMARC1", true},
+		{"v=spf1 -all", "spf1", true},
+		{"v=DMARC10; p=reject", "DMARC1", false},
+		{"v=DMARC", "DMARC1", false},
+		{"p=reject; v=DMARC1", "DMARC1", false},
+		{"DMARC1", "DMARC1", false},
+		{"v=TLSRPTv1; rua=mailto:a@example.com", "TLSRPTv1", true},
+		{"v=STSv1;id=1", "STSv1", true},
+	}
+
+	for _, tt := range tests {
+		if got := hasVersion(tt.txt, tt.version); got != tt.want {
+			t.Errorf("hasVersion(%q, %q) = %v, want %v", tt.txt, tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package mailsec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TLSRPTRecord - parsed "_smtp._tls" TXT record (RFC 8460 3)
+type TLSRPTRecord struct {
+	// URIs - report destinations: mailto: and https: URIs
+	URIs []string
+}
+
+// IsTLSRPT - TXT record is TLS-RPT record
+func IsTLSRPT(txt string) bool {
+	return hasVersion(txt, "TLSRPTv1")
+}
+
+// ParseTLSRPT - parse TLS-RPT record, rua tag with mailto: or https: URIs is required
+func ParseTLSRPT(txt string) (TLSRPTRecord, error) {
+	tags, err := parseTags(txt)
+	if err != nil {
+		return TLSRPTRecord{}, err
+	}
+
+	if err := checkVersion(tags, "TLSRPTv1"); err != nil {
+		return TLSRPTRecord{}, err
+	}
+
+	for _, t := range tags[1:] {
+		if t.Name != "rua" {
+			continue
+		}
+
+		rec := TLSRPTRecord{URIs: splitURIs(t.Value)}
+		if len(rec.URIs) == 0 {
+			return TLSRPTRecord{}, errors.New("rua tag is empty")
+		}
+
+		for _, uri := range rec.URIs {
+			lower := strings.ToLower(uri)
+			if !strings.HasPrefix(lower, "mailto:") && !strings.HasPrefix(lower, "https:") {
+				return TLSRPTRecord{}, fmt.Errorf("invalid rua URI %q: mailto: or https: expected", uri)
+			}
+		}
+
+		return rec, nil
+	}
+
+	return TLSRPTRecord{}, errors.New("rua tag is required")
+}
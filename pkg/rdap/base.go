@@ -0,0 +1,403 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package rdap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eterline/micro-utils/pkg/netipuse"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	ianaBootstrap = "https://data.iana.org/rdap"
+	ianaWhois     = "whois.iana.org:43"
+	// networkCacheSize - max cached IP network records, the oldest one is dropped
+	networkCacheSize = 4096
+)
+
+/*
+Client - RDAP client with IANA bootstrap servers discovery
+
+	If RDAP server is unknown or failed, port 43 WHOIS is used as fallback.
+	IP network records are cached by their CIDR: IPs of the same network are looked up once.
+*/
+type Client struct {
+	httpClient  *http.Client
+	bootstrap   string
+	whoisServer string
+
+	mu         sync.Mutex
+	registries map[string]bootstrapFile
+
+	netMu    sync.Mutex
+	networks []cachedNetwork
+}
+
+// cachedNetwork - IP network record by one of its prefixes
+type cachedNetwork struct {
+	prefix netip.Prefix
+	rec    Record
+}
+
+// NewClient - client over IANA bootstrap registry and whois.iana.org
+func NewClient() *Client {
+	return NewClientWith(ianaBootstrap, ianaWhois, setupHttpClient())
+}
+
+// NewClientWith - client over custom bootstrap registry base URL and WHOIS root server
+func NewClientWith(bootstrap, whoisServer string, httpClient *http.Client) *Client {
+	return &Client{
+		httpClient:  httpClient,
+		bootstrap:   strings.TrimRight(bootstrap, "/"),
+		whoisServer: whoisServer,
+		registries:  map[string]bootstrapFile{},
+	}
+}
+
+// QueryIP - IP network registration data, cached by network CIDR
+func (c *Client) QueryIP(ctx context.Context, ip net.IP) (Record, error) {
+	addr, ok := netipuse.FromStdIP(ip)
+	if !ok {
+		return Record{}, fmt.Errorf("invalid ip: %v", ip)
+	}
+
+	if rec, ok := c.cachedNetwork(addr); ok {
+		return rec, nil
+	}
+
+	rec, err := c.queryIP(ctx, addr)
+	if err == nil {
+		c.cacheNetwork(rec)
+	}
+
+	return rec, err
+}
+
+func (c *Client) queryIP(ctx context.Context, addr netip.Addr) (Record, error) {
+
+	registry := registryIPv6
+	if addr.Is4() {
+		registry = registryIPv4
+	}
+
+	rec, err := c.queryRDAP(ctx, registry, addr.String(), "ip/"+addr.String())
+	if err == nil || ctx.Err() != nil || errors.Is(err, ErrNotFound) {
+		return rec, err
+	}
+
+	rec, whoisErr := c.queryWHOIS(ctx, addr.String())
+	if whoisErr != nil {
+		return Record{}, errors.Join(err, whoisErr)
+	}
+
+	return rec, nil
+}
+
+// cachedNetwork - the most specific cached network record containing addr
+func (c *Client) cachedNetwork(addr netip.Addr) (Record, bool) {
+	c.netMu.Lock()
+	defer c.netMu.Unlock()
+
+	var (
+		best    Record
+		bestLen = -1
+	)
+
+	for _, n := range c.networks {
+		if n.prefix.Bits() > bestLen && n.prefix.Contains(addr) {
+			best, bestLen = n.rec, n.prefix.Bits()
+		}
+	}
+
+	return best, bestLen >= 0
+}
+
+// cacheNetwork - remember record by every its CIDR. Default routes (root WHOIS answers) are not cached
+func (c *Client) cacheNetwork(rec Record) {
+	c.netMu.Lock()
+	defer c.netMu.Unlock()
+
+	for _, cidr := range rec.CIDR {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || prefix.Bits() == 0 {
+			continue
+		}
+
+		if len(c.networks) >= networkCacheSize {
+			c.networks = c.networks[1:]
+		}
+		c.networks = append(c.networks, cachedNetwork{prefix: prefix.Masked(), rec: rec})
+	}
+}
+
+// QueryDomain - registered domain (eTLD+1) registration data. "www.google.com" is looked up as "google.com"
+func (c *Client) QueryDomain(ctx context.Context, domain string) (Record, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+
+	registered, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return Record{}, err
+	}
+
+	rec, err := c.queryRDAP(ctx, registryDomain, registered, "domain/"+registered)
+	if err == nil || ctx.Err() != nil || errors.Is(err, ErrNotFound) {
+		return rec, err
+	}
+
+	rec, whoisErr := c.queryWHOIS(ctx, registered)
+	if whoisErr != nil {
+		return Record{}, errors.Join(err, whoisErr)
+	}
+
+	return rec, nil
+}
+
+func (c *Client) queryRDAP(ctx context.Context, registry, key, path string) (Record, error) {
+	base, err := c.server(ctx, registry, key)
+	if err != nil {
+		return Record{}, err
+	}
+
+	api := strings.TrimRight(base, "/") + "/" + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return Record{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Record{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Record{}, fmt.Errorf("rdap %s: %w", key, ErrNotFound)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Record{}, fmt.Errorf("rdap request %s failed: %s", api, resp.Status)
+	}
+
+	obj := rdapObject{}
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return Record{}, err
+	}
+
+	if obj.ErrorCode != 0 {
+		return Record{}, fmt.Errorf("rdap request %s failed: %d %s", api, obj.ErrorCode, obj.Title)
+	}
+
+	rec := obj.record()
+	rec.Server = resp.Request.URL.Host
+
+	return rec, nil
+}
+
+func (obj rdapObject) record() Record {
+	rec := Record{
+		Source:       SourceRDAP,
+		Handle:       obj.Handle,
+		Name:         obj.Name,
+		Country:      obj.Country,
+		StartAddress: obj.StartAddress,
+		EndAddress:   obj.EndAddress,
+	}
+
+	if obj.LdhName != "" {
+		rec.Name = strings.ToLower(obj.LdhName)
+	}
+
+	for _, ev := range obj.Events {
+		switch ev.Action {
+		case "registration":
+			rec.Registered = ev.Date
+		case "last changed":
+			rec.LastChanged = ev.Date
+		case "expiration":
+			rec.Expires = ev.Date
+		}
+	}
+
+	for _, c := range obj.Cidr0 {
+		prefix := c.V4Prefix
+		if prefix == "" {
+			prefix = c.V6Prefix
+		}
+		rec.CIDR = append(rec.CIDR, fmt.Sprintf("%s/%d", prefix, c.Length))
+	}
+
+	if len(rec.CIDR) == 0 {
+		rec.CIDR = rangeCIDR(obj.StartAddress, obj.EndAddress)
+	}
+
+	rec.fillEntities(obj.Entities)
+
+	return rec
+}
+
+// fillEntities - walks nested entities for registrant, registrar and abuse contacts
+func (rec *Record) fillEntities(entities []rdapEntity) {
+	for _, e := range entities {
+		if e.hasRole("registrant") && rec.Org == "" {
+			rec.Org = e.vcard("fn")
+		}
+
+		if e.hasRole("registrar") && rec.Registrar == "" {
+			rec.Registrar = e.vcard("fn")
+		}
+
+		if e.hasRole("abuse") {
+			if rec.AbuseEmail == "" {
+				rec.AbuseEmail = e.vcard("email")
+			}
+			if rec.AbusePhone == "" {
+				rec.AbusePhone = e.vcard("tel")
+			}
+		}
+
+		rec.fillEntities(e.Entities)
+	}
+}
+
+func rangeCIDR(start, end string) []string {
+	from, errFrom := netip.ParseAddr(start)
+	to, errTo := netip.ParseAddr(end)
+	if errFrom != nil || errTo != nil {
+		return nil
+	}
+
+	prefixes := netipuse.PoolRangeFrom(from, to).Prefixes()
+
+	cidr := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		cidr[i] = p.String()
+	}
+
+	return cidr
+}
+
+// server - RDAP base URL for query key from bootstrap registry
+func (c *Client) server(ctx context.Context, registry, key string) (string, error) {
+	file, err := c.registry(ctx, registry)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		best    string
+		bestLen = -1
+	)
+
+	for _, service := range file.Services {
+		if len(service) < 2 || len(service[1]) == 0 {
+			continue
+		}
+
+		for _, entry := range service[0] {
+			n, ok := matchEntry(registry, entry, key)
+			if ok && n > bestLen {
+				best, bestLen = preferHTTPS(service[1]), n
+			}
+		}
+	}
+
+	if bestLen < 0 {
+		return "", fmt.Errorf("%s: %w", key, ErrNoServer)
+	}
+
+	return best, nil
+}
+
+// matchEntry - returns match specificity of bootstrap entry for key
+func matchEntry(registry, entry, key string) (int, bool) {
+	if registry == registryDomain {
+		entry = strings.ToLower(entry)
+		if key == entry || strings.HasSuffix(key, "."+entry) {
+			return len(entry), true
+		}
+		return 0, false
+	}
+
+	prefix, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return 0, false
+	}
+
+	addr, err := netip.ParseAddr(key)
+	if err != nil || !prefix.Contains(addr) {
+		return 0, false
+	}
+
+	return prefix.Bits(), true
+}
+
+func preferHTTPS(urls []string) string {
+	for _, u := range urls {
+		if strings.HasPrefix(u, "https://") {
+			return u
+		}
+	}
+	return urls[0]
+}
+
+// registry - cached bootstrap registry file
+func (c *Client) registry(ctx context.Context, name string) (bootstrapFile, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if file, ok := c.registries[name]; ok {
+		return file, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.bootstrap+"/"+name, nil)
+	if err != nil {
+		return bootstrapFile{}, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return bootstrapFile{}, fmt.Errorf("failed to get rdap bootstrap %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return bootstrapFile{}, fmt.Errorf("failed to get rdap bootstrap %s: %s", name, resp.Status)
+	}
+
+	file := bootstrapFile{}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return bootstrapFile{}, fmt.Errorf("failed to parse rdap bootstrap %s: %w", name, err)
+	}
+
+	c.registries[name] = file
+	return file, nil
+}
+
+func setupHttpClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 60 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        64,
+			MaxIdleConnsPerHost: 16,
+		},
+	}
+}
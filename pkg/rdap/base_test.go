@@ -0,0 +1,257 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package rdap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// whoisStandIn - port 43 like server on loopback, answer is built by query
+func whoisStandIn(t *testing.T, answer func(query string) string) (string, *atomic.Int32) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		hits = &atomic.Int32{}
+		wg   = &sync.WaitGroup{}
+	)
+
+	wg.Go(func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			hits.Add(1)
+			wg.Go(func() {
+				defer conn.Close()
+
+				query, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				io.WriteString(conn, answer(strings.TrimSpace(query)))
+			})
+		}
+	})
+
+	t.Cleanup(func() {
+		ln.Close()
+		wg.Wait()
+	})
+
+	return ln.Addr().String(), hits
+}
+
+// rdapStandIn - RDAP server answering IP network objects by handler, counts requests
+func rdapStandIn(t *testing.T, h func(w http.ResponseWriter, path string)) (*httptest.Server, *atomic.Int32) {
+	hits := &atomic.Int32{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/rdap+json")
+		h(w, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, hits
+}
+
+func network(handle, prefix string, length int, org string) map[string]any {
+	return map[string]any{
+		"objectClassName": "ip network",
+		"handle":          handle,
+		"name":            handle,
+		"country":         "US",
+		"cidr0_cidrs":     []map[string]any{{"v4prefix": prefix, "length": length}},
+		"entities": []map[string]any{{
+			"roles":      []string{"registrant"},
+			"vcardArray": []any{"vcard", []any{[]any{"fn", map[string]any{}, "text", org}}},
+		}},
+	}
+}
+
+// bootstrapStandIn - bootstrap registry with IPv4 and DNS services, counts requests per file
+func bootstrapStandIn(t *testing.T, ipv4, dns [][][]string) (*httptest.Server, *sync.Map) {
+	hits := &sync.Map{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := hits.LoadOrStore(r.URL.Path, &atomic.Int32{})
+		n.(*atomic.Int32).Add(1)
+
+		switch r.URL.Path {
+		case "/" + registryIPv4:
+			json.NewEncoder(w).Encode(bootstrapFile{Services: ipv4})
+		case "/" + registryDomain:
+			json.NewEncoder(w).Encode(bootstrapFile{Services: dns})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, hits
+}
+
+func TestClientBootstrap(t *testing.T) {
+	wide, wideHits := rdapStandIn(t, func(w http.ResponseWriter, path string) {
+		switch path {
+		case "/ip/192.0.2.5":
+			json.NewEncoder(w).Encode(network("WIDE", "192.0.2.0", 24, "Wide Org"))
+		case "/domain/example.test":
+			json.NewEncoder(w).Encode(map[string]any{"objectClassName": "domain", "ldhName": "EXAMPLE.TEST"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	narrow, narrowHits := rdapStandIn(t, func(w http.ResponseWriter, path string) {
+		json.NewEncoder(w).Encode(network("NARROW", "192.0.2.128", 25, "Narrow Org"))
+	})
+
+	boot, bootHits := bootstrapStandIn(t,
+		[][][]string{
+			{{"192.0.2.0/24"}, {wide.URL + "/"}},
+			{{"192.0.2.128/25"}, {narrow.URL + "/"}},
+		},
+		[][][]string{{{"test"}, {wide.URL}}},
+	)
+
+	whois, whoisHits := whoisStandIn(t, func(string) string { return "" })
+
+	cl := NewClientWith(boot.URL, whois, http.DefaultClient)
+	ctx := context.Background()
+
+	// the most specific bootstrap entry wins
+	rec, err := cl.QueryIP(ctx, net.ParseIP("192.0.2.200"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Handle != "NARROW" || rec.Org != "Narrow Org" || rec.Source != SourceRDAP {
+		t.Errorf("192.0.2.200: got %+v, want NARROW network", rec)
+	}
+
+	rec, err = cl.QueryIP(ctx, net.ParseIP("192.0.2.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Handle != "WIDE" || fmt.Sprint(rec.CIDR) != "[192.0.2.0/24]" {
+		t.Errorf("192.0.2.5: got %+v, want WIDE network", rec)
+	}
+
+	// eTLD+1 lookup
+	rec, err = cl.QueryDomain(ctx, "www.Example.test.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Name != "example.test" {
+		t.Errorf("domain name %q, want example.test", rec.Name)
+	}
+
+	// RDAP "not found" is final, no WHOIS fallback
+	if _, err := cl.QueryDomain(ctx, "missing.test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+	if whoisHits.Load() != 0 {
+		t.Errorf("WHOIS asked %d times after RDAP answers", whoisHits.Load())
+	}
+
+	// bootstrap files are fetched once
+	bootHits.Range(func(path, n any) bool {
+		if n.(*atomic.Int32).Load() != 1 {
+			t.Errorf("bootstrap %s fetched %d times", path, n.(*atomic.Int32).Load())
+		}
+		return true
+	})
+
+	// IPs of cached networks are not asked again
+	for _, ip := range []string{"192.0.2.129", "192.0.2.255", "192.0.2.6"} {
+		if _, err := cl.QueryIP(ctx, net.ParseIP(ip)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if narrowHits.Load() != 1 || wideHits.Load() != 3 {
+		t.Errorf("RDAP requests: narrow %d, wide %d; want 1 and 3", narrowHits.Load(), wideHits.Load())
+	}
+}
+
+func TestClientWhoisFallback(t *testing.T) {
+	failing, _ := rdapStandIn(t, func(w http.ResponseWriter, path string) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	boot, _ := bootstrapStandIn(t, [][][]string{{{"198.51.100.0/24"}, {failing.URL}}}, nil)
+
+	registry, registryHits := whoisStandIn(t, func(query string) string {
+		if !strings.HasPrefix(query, "198.51.100.") {
+			return "% no entries found\r\n"
+		}
+		return strings.Join([]string{
+			"% registry answer for " + query,
+			"NetRange:       198.51.100.0 - 198.51.100.255",
+			"NetName:        EXAMPLE-NET",
+			"OrgName:        Example Org",
+			"Country:        US",
+			"OrgAbuseEmail:  abuse@example.test",
+			"RegDate:        2001-02-03",
+			"",
+		}, "\r\n")
+	})
+
+	root, rootHits := whoisStandIn(t, func(query string) string {
+		return "% IANA WHOIS server\r\nrefer:        " + registry + "\r\ninetnum:      0.0.0.0 - 255.255.255.255\r\n"
+	})
+
+	cl := NewClientWith(boot.URL, root, http.DefaultClient)
+	ctx := context.Background()
+
+	for _, ip := range []string{"198.51.100.7", "198.51.100.8"} {
+		rec, err := cl.QueryIP(ctx, net.ParseIP(ip))
+		if err != nil {
+			t.Fatalf("%s: %v", ip, err)
+		}
+
+		if rec.Source != SourceWHOIS || rec.Server != registry {
+			t.Errorf("%s: source %s server %s, want whois of %s", ip, rec.Source, rec.Server, registry)
+		}
+		if rec.Org != "Example Org" || rec.Name != "EXAMPLE-NET" || rec.AbuseEmail != "abuse@example.test" {
+			t.Errorf("%s: got %+v", ip, rec)
+		}
+		if fmt.Sprint(rec.CIDR) != "[198.51.100.0/24]" || rec.Registered.Year() != 2001 {
+			t.Errorf("%s: cidr %v registered %v", ip, rec.CIDR, rec.Registered)
+		}
+	}
+
+	// second IP is taken from network cache
+	if rootHits.Load() != 1 || registryHits.Load() != 1 {
+		t.Errorf("WHOIS requests: root %d, registry %d; want 1 and 1", rootHits.Load(), registryHits.Load())
+	}
+
+	// no RDAP server in bootstrap: WHOIS is asked too
+	_, err := cl.QueryIP(ctx, net.ParseIP("203.0.113.1"))
+	if !errors.Is(err, ErrNoServer) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNoServer joined with WHOIS ErrNotFound", err)
+	}
+	if registryHits.Load() != 2 {
+		t.Errorf("registry WHOIS asked %d times, want 2", registryHits.Load())
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package rdap
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound - registry has no object for query
+	ErrNotFound = errors.New("object not found")
+	// ErrNoServer - bootstrap registry has no server for query
+	ErrNoServer = errors.New("no RDAP server for query")
+)
+
+// Source - lookup protocol that produced record
+type Source string
+
+const (
+	SourceRDAP  Source = "rdap"
+	SourceWHOIS Source = "whois"
+)
+
+// Record - ownership registration data of IP network or domain
+type Record struct {
+	Source       Source    `json:"source"`
+	Server       string    `json:"server"`
+	Handle       string    `json:"handle,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	Org          string    `json:"org,omitempty"`
+	Country      string    `json:"country,omitempty"`
+	StartAddress string    `json:"start_address,omitempty"`
+	EndAddress   string    `json:"end_address,omitempty"`
+	CIDR         []string  `json:"cidr,omitempty"`
+	AbuseEmail   string    `json:"abuse_email,omitempty"`
+	AbusePhone   string    `json:"abuse_phone,omitempty"`
+	Registrar    string    `json:"registrar,omitempty"`
+	Registered   time.Time `json:"registered,omitzero"`
+	LastChanged  time.Time `json:"last_changed,omitzero"`
+	Expires      time.Time `json:"expires,omitzero"`
+}
+
+// Bootstrap registries: https://www.rfc-editor.org/rfc/rfc9224
+const (
+	registryIPv4   = "ipv4.json"
+	registryIPv6   = "ipv6.json"
+	registryDomain = "dns.json"
+)
+
+// bootstrapFile - IANA bootstrap registry file
+type bootstrapFile struct {
+	Services [][][]string `json:"services"`
+}
+
+// rdapEvent - RFC 9083 event object
+type rdapEvent struct {
+	Action string    `json:"eventAction"`
+	Date   time.Time `json:"eventDate"`
+}
+
+// rdapEntity - RFC 9083 entity object with jCard (RFC 7095) contacts
+type rdapEntity struct {
+	Handle     string       `json:"handle"`
+	Roles      []string     `json:"roles"`
+	VcardArray []any        `json:"vcardArray"`
+	Entities   []rdapEntity `json:"entities"`
+}
+
+// rdapObject - RFC 9083 IP network or domain object
+type rdapObject struct {
+	ObjectClassName string       `json:"objectClassName"`
+	Handle          string       `json:"handle"`
+	LdhName         string       `json:"ldhName"`
+	Name            string       `json:"name"`
+	Country         string       `json:"country"`
+	StartAddress    string       `json:"startAddress"`
+	EndAddress      string       `json:"endAddress"`
+	Events          []rdapEvent  `json:"events"`
+	Entities        []rdapEntity `json:"entities"`
+
+	// RFC 9083 cidr0 extension
+	Cidr0 []struct {
+		V4Prefix string `json:"v4prefix"`
+		V6Prefix string `json:"v6prefix"`
+		Length   int    `json:"length"`
+	} `json:"cidr0_cidrs"`
+
+	ErrorCode   int      `json:"errorCode"`
+	Title       string   `json:"title"`
+	Description []string `json:"description"`
+}
+
+// vcard - jCard property value: ["vcard", [["fn", {}, "text", "Google LLC"], ...]]
+func (e rdapEntity) vcard(property string) string {
+	if len(e.VcardArray) < 2 {
+		return ""
+	}
+
+	props, _ := e.VcardArray[1].([]any)
+	for _, p := range props {
+		prop, _ := p.([]any)
+		if len(prop) < 4 {
+			continue
+		}
+
+		if name, _ := prop[0].(string); name != property {
+			continue
+		}
+
+		switch value := prop[3].(type) {
+		case string:
+			return value
+		case []any:
+			// structured values like "adr"
+			if len(value) > 0 {
+				s, _ := value[0].(string)
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+func (e rdapEntity) hasRole(role string) bool {
+	for _, r := range e.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
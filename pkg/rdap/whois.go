@@ -0,0 +1,243 @@
+// Copyright (c) 2025 EterLine (Andrew)
+// This file is part of micro-utils.
+// Licensed under the MIT License. See the LICENSE file for details.
+
+package rdap
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// whoisMaxReferrals - max followed "refer:" servers after root server
+	whoisMaxReferrals = 2
+	// whoisMaxResponse - max read response size
+	whoisMaxResponse = 1 << 20
+)
+
+// whoisReferKeys - keys that point to next WHOIS server
+var whoisReferKeys = []string{"refer", "whois", "registrar whois server", "referralserver"}
+
+// whoisKeys - record fields by known WHOIS keys of registries (ARIN, RIPE, APNIC, Verisign etc.)
+var whoisKeys = map[string]func(rec *Record, v string){
+	"orgname":                       setOnce(func(r *Record) *string { return &r.Org }),
+	"org-name":                      setOnce(func(r *Record) *string { return &r.Org }),
+	"organisation":                  setOnce(func(r *Record) *string { return &r.Org }),
+	"organization":                  setOnce(func(r *Record) *string { return &r.Org }),
+	"registrant organization":       setOnce(func(r *Record) *string { return &r.Org }),
+	"owner":                         setOnce(func(r *Record) *string { return &r.Org }),
+	"netname":                       setOnce(func(r *Record) *string { return &r.Name }),
+	"domain name":                   setOnce(func(r *Record) *string { return &r.Name }),
+	"nethandle":                     setOnce(func(r *Record) *string { return &r.Handle }),
+	"registry domain id":            setOnce(func(r *Record) *string { return &r.Handle }),
+	"country":                       setOnce(func(r *Record) *string { return &r.Country }),
+	"orgabuseemail":                 setOnce(func(r *Record) *string { return &r.AbuseEmail }),
+	"abuse-mailbox":                 setOnce(func(r *Record) *string { return &r.AbuseEmail }),
+	"registrar abuse contact email": setOnce(func(r *Record) *string { return &r.AbuseEmail }),
+	"orgabusephone":                 setOnce(func(r *Record) *string { return &r.AbusePhone }),
+	"registrar abuse contact phone": setOnce(func(r *Record) *string { return &r.AbusePhone }),
+	"registrar":                     setOnce(func(r *Record) *string { return &r.Registrar }),
+	"regdate":                       setTime(func(r *Record) *time.Time { return &r.Registered }),
+	"created":                       setTime(func(r *Record) *time.Time { return &r.Registered }),
+	"creation date":                 setTime(func(r *Record) *time.Time { return &r.Registered }),
+	"updated":                       setTime(func(r *Record) *time.Time { return &r.LastChanged }),
+	"last-modified":                 setTime(func(r *Record) *time.Time { return &r.LastChanged }),
+	"updated date":                  setTime(func(r *Record) *time.Time { return &r.LastChanged }),
+	"registry expiry date":          setTime(func(r *Record) *time.Time { return &r.Expires }),
+	"netrange":                      setRange,
+	"inetnum":                       setRange,
+	"inet6num":                      setRange,
+	"cidr":                          setCIDR,
+}
+
+// queryWHOIS - query root WHOIS server and follow referrals
+func (c *Client) queryWHOIS(ctx context.Context, query string) (Record, error) {
+	server := c.whoisServer
+
+	for hop := 0; ; hop++ {
+		lines, err := whoisExchange(ctx, server, query)
+		if err != nil {
+			return Record{}, fmt.Errorf("whois %s: %w", server, err)
+		}
+
+		refer := whoisRefer(lines)
+		if refer != "" && refer != server && hop < whoisMaxReferrals {
+			server = refer
+			continue
+		}
+
+		rec := parseWHOIS(lines)
+		rec.Server = server
+
+		if rec.Name == "" && rec.Org == "" && len(rec.CIDR) == 0 {
+			return Record{}, fmt.Errorf("whois %s: %s: %w", server, query, ErrNotFound)
+		}
+
+		return rec, nil
+	}
+}
+
+func whoisExchange(ctx context.Context, server, query string) ([]string, error) {
+	d := net.Dialer{}
+
+	conn, err := d.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := io.WriteString(conn, query+"\r\n"); err != nil {
+		return nil, err
+	}
+
+	var (
+		lines []string
+		sc    = bufio.NewScanner(io.LimitReader(conn, whoisMaxResponse))
+	)
+
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+
+	return lines, sc.Err()
+}
+
+func whoisPair(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '%' || line[0] == '#' {
+		return "", "", false
+	}
+
+	key, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	return strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value), true
+}
+
+func whoisRefer(lines []string) string {
+	for _, line := range lines {
+		key, value, ok := whoisPair(line)
+		if !ok || value == "" {
+			continue
+		}
+
+		for _, rk := range whoisReferKeys {
+			if key != rk {
+				continue
+			}
+
+			// "rwhois://rwhois.example.net:4321" and "whois://whois.arin.net"
+			value = strings.TrimPrefix(value, "whois://")
+			if strings.Contains(value, "://") {
+				continue
+			}
+
+			if _, _, err := net.SplitHostPort(value); err != nil {
+				value = net.JoinHostPort(value, "43")
+			}
+			return strings.ToLower(value)
+		}
+	}
+
+	return ""
+}
+
+func parseWHOIS(lines []string) Record {
+	rec := Record{Source: SourceWHOIS}
+
+	for _, line := range lines {
+		key, value, ok := whoisPair(line)
+		if !ok || value == "" {
+			continue
+		}
+
+		if set, ok := whoisKeys[key]; ok {
+			set(&rec, value)
+		}
+	}
+
+	if len(rec.CIDR) == 0 {
+		rec.CIDR = rangeCIDR(rec.StartAddress, rec.EndAddress)
+	}
+
+	return rec
+}
+
+func setOnce(field func(r *Record) *string) func(r *Record, v string) {
+	return func(r *Record, v string) {
+		if p := field(r); *p == "" {
+			*p = v
+		}
+	}
+}
+
+var whoisTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"20060102",
+}
+
+func setTime(field func(r *Record) *time.Time) func(r *Record, v string) {
+	return func(r *Record, v string) {
+		p := field(r)
+		if !p.IsZero() {
+			return
+		}
+
+		for _, layout := range whoisTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				*p = t
+				return
+			}
+		}
+	}
+}
+
+// setRange - "192.0.2.0 - 192.0.2.255"
+func setRange(r *Record, v string) {
+	if r.StartAddress != "" {
+		return
+	}
+
+	start, end, ok := strings.Cut(v, "-")
+	if !ok {
+		// inet6num is CIDR notation
+		setCIDR(r, v)
+		return
+	}
+
+	r.StartAddress = strings.TrimSpace(start)
+	r.EndAddress = strings.TrimSpace(end)
+}
+
+// setCIDR - "192.0.2.0/24, 198.51.100.0/24"
+func setCIDR(r *Record, v string) {
+	if len(r.CIDR) > 0 {
+		return
+	}
+
+	for _, cidr := range strings.Split(v, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			r.CIDR = append(r.CIDR, cidr)
+		}
+	}
+}